@@ -25,6 +25,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/features/routing"
 	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
 	"github.com/v2fly/v2ray-core/v5/features/stats"
+	"github.com/v2fly/v2ray-core/v5/proxy"
 	"github.com/v2fly/v2ray-core/v5/transport"
 	"github.com/v2fly/v2ray-core/v5/transport/pipe"
 )
@@ -369,6 +370,7 @@ func sniff(ctx context.Context, cReader *cachedReader, network net.Network, snif
 
 func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.Link, destination net.Destination) {
 	var handler outbound.Handler
+	var ruleTag string
 
 	if forcedOutboundTag := session.GetForcedOutboundTagFromContext(ctx); forcedOutboundTag != "" {
 		ctx = session.SetForcedOutboundTagToContext(ctx, "")
@@ -387,6 +389,7 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 			if h := d.ohm.GetHandler(tag); h != nil {
 				newError("taking detour [", tag, "] for [", destination, "]").WriteToLog(session.ExportIDToError(ctx))
 				handler = h
+				ruleTag = route.GetRuleTag()
 			} else {
 				newError("non existing tag: ", tag).AtWarning().WriteToLog(session.ExportIDToError(ctx))
 			}
@@ -407,11 +410,25 @@ func (d *DefaultDispatcher) routedDispatch(ctx context.Context, link *transport.
 	}
 
 	if accessMessage := log.AccessMessageFromContext(ctx); accessMessage != nil {
-		if tag := handler.Tag(); tag != "" {
-			accessMessage.Detour = tag
-		}
+		accessMessage.Detour = detour(handler)
+		accessMessage.RuleTag = ruleTag
 		log.Record(accessMessage)
 	}
 
 	handler.Dispatch(ctx, link)
 }
+
+// detour returns the detour of handler in the access log. Chains are logged
+// with all their hops.
+func detour(handler outbound.Handler) string {
+	tag := handler.Tag()
+	if tag == "" {
+		return ""
+	}
+	if getter, ok := handler.(proxy.GetOutbound); ok {
+		if chain, ok := getter.GetOutbound().(proxy.ChainOutbound); ok {
+			return tag + ": " + strings.Join(chain.Chain(), " -> ")
+		}
+	}
+	return tag
+}
@@ -109,6 +109,7 @@ func (d *DefaultDispatcher) routedDispatchConn(ctx context.Context, conn net.Con
 
 func (d *DefaultDispatcher) routedDispatchConn0(ctx context.Context, conn net.Conn, destination net.Destination) {
 	var handler outbound.Handler
+	var ruleTag string
 
 	if forcedOutboundTag := session.GetForcedOutboundTagFromContext(ctx); forcedOutboundTag != "" {
 		ctx = session.SetForcedOutboundTagToContext(ctx, "")
@@ -126,6 +127,7 @@ func (d *DefaultDispatcher) routedDispatchConn0(ctx context.Context, conn net.Co
 			if h := d.ohm.GetHandler(tag); h != nil {
 				newError("taking detour [", tag, "] for [", destination, "]").WriteToLog(session.ExportIDToError(ctx))
 				handler = h
+				ruleTag = route.GetRuleTag()
 			} else {
 				newError("non existing tag: ", tag).AtWarning().WriteToLog(session.ExportIDToError(ctx))
 			}
@@ -145,9 +147,8 @@ func (d *DefaultDispatcher) routedDispatchConn0(ctx context.Context, conn net.Co
 	}
 
 	if accessMessage := log.AccessMessageFromContext(ctx); accessMessage != nil {
-		if tag := handler.Tag(); tag != "" {
-			accessMessage.Detour = tag
-		}
+		accessMessage.Detour = detour(handler)
+		accessMessage.RuleTag = ruleTag
 		log.Record(accessMessage)
 	}
 
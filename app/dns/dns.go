@@ -38,12 +38,17 @@ type Client struct {
 	defaultQueryStrategy dns.QueryStrategy
 	hosts                *StaticHosts
 	servers              []*Server
+	overrideMatcher      strmatcher.IndexMatcher
+	overrides            []*domainOverride
 
 	disableCache           bool
 	disableFallback        bool
 	disableFallbackIfMatch bool
 	disableExpire          bool
 
+	serverStrategy  ServerStrategy
+	parallelServers int
+
 	requestId int32
 	callbacks sync.Map
 	cache     sync.Map
@@ -57,9 +62,16 @@ type Server struct {
 	domains      []string
 	expectIPs    []*router.GeoIPMatcher
 	concurrency  bool
+	timeout      time.Duration
+	validator    *dnssecValidator
 	access       sync.Mutex
 }
 
+type domainOverride struct {
+	clientIP      net.IP
+	queryStrategy dns.QueryStrategy
+}
+
 type transportContext struct {
 	ctx         context.Context
 	client      *Client
@@ -72,6 +84,7 @@ type transportContext struct {
 
 type queryCallback struct {
 	parseIPs bool
+	merge    bool
 	domain   string
 	strategy dns.QueryStrategy
 
@@ -80,8 +93,75 @@ type queryCallback struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
-	access   sync.Mutex
-	response *serverQueryCallback
+	access    sync.Mutex
+	response  *serverQueryCallback
+	responses []*serverQueryCallback
+}
+
+// finish records the answer of r. The whole query ends unless answers of all
+// servers are merged. The caller must hold q.access.
+func (q *queryCallback) finish(r *serverQueryCallback) {
+	if q.merge {
+		q.responses = append(q.responses, r)
+		return
+	}
+	q.response = r
+	q.cancel()
+}
+
+// mergeMessages returns the response of the first server that answered a raw
+// query, with the answer records of all servers that answered, or false if no
+// server answered.
+func (q *queryCallback) mergeMessages() (*dnsmessage.Message, bool) {
+	q.access.Lock()
+	defer q.access.Unlock()
+
+	var merged *dnsmessage.Message
+	seen := make(map[string]bool)
+	for _, response := range q.responses {
+		if response.message == nil {
+			continue
+		}
+		if merged == nil {
+			message := *response.message
+			message.Answers = nil
+			merged = &message
+		}
+		for _, answer := range response.message.Answers {
+			key := answer.Header.Name.String() + "/" + answer.Header.Type.String() + "/" + answer.Body.GoString()
+			if !seen[key] {
+				seen[key] = true
+				merged.Answers = append(merged.Answers, answer)
+			}
+		}
+	}
+	return merged, merged != nil
+}
+
+// mergeResponses returns the union of addresses answered by all servers, with
+// the smallest TTL among them, or false if no server answered.
+func (q *queryCallback) mergeResponses() ([]net.IP, uint32, bool) {
+	q.access.Lock()
+	defer q.access.Unlock()
+
+	if len(q.responses) == 0 {
+		return nil, 0, false
+	}
+	var ips []net.IP
+	var ttl uint32
+	seen := make(map[string]bool)
+	for _, response := range q.responses {
+		for _, ip := range response.ips {
+			if !seen[string(ip)] {
+				seen[string(ip)] = true
+				ips = append(ips, ip)
+			}
+		}
+		if ttl == 0 || response.ttl < ttl {
+			ttl = response.ttl
+		}
+	}
+	return ips, ttl, true
 }
 
 type serverQueryCallback struct {
@@ -106,6 +186,28 @@ type ipCacheEntire struct {
 	expire4, expire6 time.Time
 }
 
+// newContext returns the context of a query to the server, which is canceled
+// after the timeout of the server.
+func (s *Server) newContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(ctx, s.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// newQuery returns a copy of message to be sent to the server, with EDNS0
+// client subnet of clientIP attached.
+func (s *Server) newQuery(message *dnsmessage.Message, clientIP net.IP) *dnsmessage.Message {
+	query := *message
+	if opt := genEDNS0Options(clientIP); opt != nil {
+		query.Additionals = append(append([]dnsmessage.Resource(nil), message.Additionals...), *opt)
+	}
+	if s.validator != nil {
+		return withDNSSECOK(&query)
+	}
+	return &query
+}
+
 func (c *Client) nextRequestId() uint16 {
 	requestId := atomic.AddInt32(&c.requestId, 1)
 	if requestId > 65535 {
@@ -125,6 +227,22 @@ func (c *Client) Lookup(ctx context.Context, domain string, strategy dns.QuerySt
 		domain = domain[:len(domain)-1]
 	}
 
+	override := c.matchOverride(domain)
+	if override != nil {
+		switch override.queryStrategy {
+		case dns.QueryStrategy_USE_IP4:
+			if strategy == dns.QueryStrategy_USE_IP6 {
+				return nil, 0, dns.ErrEmptyResponse
+			}
+			strategy = dns.QueryStrategy_USE_IP4
+		case dns.QueryStrategy_USE_IP6:
+			if strategy == dns.QueryStrategy_USE_IP4 {
+				return nil, 0, dns.ErrEmptyResponse
+			}
+			strategy = dns.QueryStrategy_USE_IP6
+		}
+	}
+
 	var ips []net.IP
 	var cached4, cached6 bool
 	now := time.Now()
@@ -174,7 +292,7 @@ func (c *Client) Lookup(ctx context.Context, domain string, strategy dns.QuerySt
 	}
 
 	if query {
-		queried, ttl, err := c.lookup(ctx, domain, newStrategy)
+		queried, ttl, err := c.lookup(ctx, domain, newStrategy, override)
 		if err != nil {
 			return nil, ttl, err
 		}
@@ -210,7 +328,44 @@ func (c *Client) Lookup(ctx context.Context, domain string, strategy dns.QuerySt
 	return ips, ttl, nil
 }
 
-func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QueryStrategy) ([]net.IP, uint32, error) {
+// cacheMerged replaces cached addresses of domain, which hold the answer of
+// the last server, with merged ones. The cached entry may be in use, so a copy
+// of it is stored.
+func (c *Client) cacheMerged(domain string, ips []net.IP) {
+	cacheI, loaded := c.cache.Load(domain)
+	if !loaded {
+		return
+	}
+	cache := *cacheI.(*ipCacheEntire)
+	var cache4, cache6 []net.IP
+	for _, ip := range ips {
+		if len(ip) == net.IPv4len {
+			cache4 = append(cache4, ip)
+		} else {
+			cache6 = append(cache6, ip)
+		}
+	}
+	if cache.cached4 {
+		cache.cache4 = cache4
+	}
+	if cache.cached6 {
+		cache.cache6 = cache6
+	}
+	c.cache.Store(domain, &cache)
+}
+
+func (c *Client) matchOverride(domain string) *domainOverride {
+	if c.overrideMatcher == nil {
+		return nil
+	}
+	matches := c.overrideMatcher.Match(domain)
+	if len(matches) == 0 {
+		return nil
+	}
+	return c.overrides[matches[0]]
+}
+
+func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QueryStrategy, override *domainOverride) ([]net.IP, uint32, error) {
 	if c.servers == nil {
 		return nil, 0, os.ErrClosed
 	}
@@ -255,6 +410,7 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 		cancel: cancel,
 
 		parseIPs: true,
+		merge:    c.serverStrategy == ServerStrategy_MERGE,
 		domain:   domain,
 		strategy: strategy,
 	}
@@ -266,7 +422,7 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 
 	for _, server := range servers {
 		server := server
-		ctx, cancel := context.WithCancel(ctx)
+		ctx, cancel := server.newContext(ctx)
 		r := &serverQueryCallback{
 			queryCallback: q,
 			ctx:           ctx,
@@ -277,10 +433,14 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 			r.wg.Done()
 		}()
 		requests = append(requests, r)
+		clientIP := server.clientIP
+		if override != nil && len(override.clientIP) > 0 {
+			clientIP = override.clientIP
+		}
 		switch server.transport.Type() {
 		case dns.TransportTypeDefault:
 			for index := range messages {
-				message := messages[index]
+				message := server.newQuery(messages[index], clientIP)
 				message.ID = c.nextRequestId()
 				reqIds = append(reqIds, message.ID)
 				r.queryType.Store(message.ID, message.Questions[0].Type)
@@ -294,7 +454,7 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 			}
 		case dns.TransportTypeExchange:
 			for index := range messages {
-				message := messages[index]
+				message := server.newQuery(messages[index], clientIP)
 				message.ID = c.nextRequestId()
 				reqIds = append(reqIds, message.ID)
 				r.queryType.Store(message.ID, message.Questions[0].Type)
@@ -311,7 +471,7 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 			}
 		case dns.TransportTypeExchangeRaw:
 			for index := range messages {
-				message := messages[index]
+				message := server.newQuery(messages[index], clientIP)
 				message.ID = c.nextRequestId()
 				packed, err := message.Pack()
 				if err != nil {
@@ -343,19 +503,18 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 					matched, err := server.matchExpectedIPs(r.domain, ips)
 					if err != nil {
 						r.errors = append(r.errors, err)
-						return
+					} else {
+						newError(server.name, " got answer: ", r.domain, " -> ", ips).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+						r.ips = matched
+						q.finish(r)
 					}
-					newError(server.name, " got answer: ", r.domain, " -> ", ips).AtDebug().WriteToLog(session.ExportIDToError(ctx))
-					r.ips = matched
-					q.response = r
-					q.cancel()
 				}
 				cancel()
 			}()
 		}
-		if !server.concurrency {
-			r.wg.Wait()
-			if common.Done(r.ctx) {
+		if !server.concurrency && c.serverStrategy == ServerStrategy_SEQUENTIAL {
+			<-r.ctx.Done()
+			if common.Done(q.ctx) {
 				break
 			}
 		}
@@ -372,6 +531,14 @@ func (c *Client) lookup(ctx context.Context, domain string, strategy dns.QuerySt
 		c.callbacks.Delete(reqId)
 	}
 
+	if ips, ttl, ok := q.mergeResponses(); ok {
+		c.cacheMerged(domain, ips)
+		if len(ips) == 0 {
+			return nil, ttl, dns.ErrEmptyResponse
+		}
+		return ips, ttl, nil
+	}
+
 	response := q.response
 	if response != nil {
 		ips := q.response.ips
@@ -438,6 +605,7 @@ func (c *Client) QueryRaw(ctx context.Context, buffer *buf.Buffer) (*buf.Buffer,
 		wg:     new(sync.WaitGroup),
 		ctx:    ctx,
 		cancel: cancel,
+		merge:  c.serverStrategy == ServerStrategy_MERGE,
 		domain: domain,
 	}
 	q.wg.Add(len(servers))
@@ -445,7 +613,7 @@ func (c *Client) QueryRaw(ctx context.Context, buffer *buf.Buffer) (*buf.Buffer,
 	var requests []*serverQueryCallback
 	for _, server := range servers {
 		server := server
-		ctx, cancel := context.WithCancel(ctx)
+		ctx, cancel := server.newContext(ctx)
 		r := &serverQueryCallback{
 			queryCallback: q,
 			ctx:           ctx,
@@ -455,6 +623,12 @@ func (c *Client) QueryRaw(ctx context.Context, buffer *buf.Buffer) (*buf.Buffer,
 			<-ctx.Done()
 			r.wg.Done()
 		}()
+		// Each server is sent a copy of the query with its own ID.
+		query := *message
+		message := &query
+		if server.validator != nil {
+			message = withDNSSECOK(message)
+		}
 		switch server.transport.Type() {
 		case dns.TransportTypeDefault:
 			message.ID = c.nextRequestId()
@@ -524,20 +698,19 @@ func (c *Client) QueryRaw(ctx context.Context, buffer *buf.Buffer) (*buf.Buffer,
 					matched, err := server.matchExpectedIPs(r.domain, ips)
 					if err != nil {
 						r.errors = append(r.errors, err)
-						return
+					} else {
+						newError(server.name, " got answer: ", r.domain, " -> ", ips).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+						r.ips = matched
+						q.finish(r)
 					}
-					newError(server.name, " got answer: ", r.domain, " -> ", ips).AtDebug().WriteToLog(session.ExportIDToError(ctx))
-					r.ips = matched
-					q.response = r
-					q.cancel()
 				}
 				cancel()
 			}()
 		}
 
-		if !server.concurrency {
-			r.wg.Wait()
-			if common.Done(r.ctx) {
+		if !server.concurrency && c.serverStrategy == ServerStrategy_SEQUENTIAL {
+			<-r.ctx.Done()
+			if common.Done(q.ctx) {
 				break
 			}
 		}
@@ -554,6 +727,11 @@ func (c *Client) QueryRaw(ctx context.Context, buffer *buf.Buffer) (*buf.Buffer,
 		c.callbacks.Delete(reqId)
 	}
 
+	if responseMessage, ok := q.mergeMessages(); ok {
+		responseMessage.ID = messageID
+		return packMessage(responseMessage)
+	}
+
 	response := q.response
 	if response != nil && response.message != nil {
 		responseMessage := response.message
@@ -563,7 +741,7 @@ func (c *Client) QueryRaw(ctx context.Context, buffer *buf.Buffer) (*buf.Buffer,
 
 	for _, request := range requests {
 		if request.message != nil {
-			responseMessage := response.message
+			responseMessage := request.message
 			responseMessage.ID = messageID
 			return packMessage(responseMessage)
 		}
@@ -720,12 +898,51 @@ func (c *Client) writeBack(server *Server, message *dnsmessage.Message) {
 		return
 	}
 
-	d := callbackI.(*serverQueryCallback)
+	var d *serverQueryCallback
+	switch callback := callbackI.(type) {
+	case *exchangeCallback:
+		callback.response <- message
+		return
+	case *serverQueryCallback:
+		d = callback
+	}
 
 	if common.Done(d.ctx) {
 		return
 	}
 
+	if server.validator != nil {
+		fail := func(err error) {
+			if common.Done(d.ctx) {
+				return
+			}
+			d.access.Lock()
+			d.errors = append(d.errors, err)
+			d.access.Unlock()
+			d.cancel()
+			newError("DNSSEC validation failed for domain ", d.domain, " at server ", server.name).Base(err).AtWarning().WriteToLog(session.ExportIDToError(d.ctx))
+		}
+		switch message.RCode {
+		case dnsmessage.RCodeSuccess, dnsmessage.RCodeNameError:
+			go func() {
+				if err := server.validator.validate(d.ctx, message); err != nil {
+					fail(err)
+					return
+				}
+				c.handleResponse(server, d, message)
+			}()
+		default:
+			// Other errors carry no signed records, so they may be forged and
+			// must not fail the lookup as an answer.
+			fail(newError("unauthenticated response").Base(dns.RCodeError(message.RCode)))
+		}
+		return
+	}
+
+	c.handleResponse(server, d, message)
+}
+
+func (c *Client) handleResponse(server *Server, d *serverQueryCallback, message *dnsmessage.Message) {
 	d.access.Lock()
 	defer d.access.Unlock()
 
@@ -748,8 +965,7 @@ func (c *Client) writeBack(server *Server, message *dnsmessage.Message) {
 		newError(server.name, " got answer for raw query ", message.ID).AtDebug().WriteToLog(session.ExportIDToError(d.ctx))
 
 		d.message = message
-		d.queryCallback.response = d
-		d.queryCallback.cancel()
+		d.queryCallback.finish(d)
 		d.cancel()
 		return
 	}
@@ -829,6 +1045,8 @@ func (c *Client) writeBack(server *Server, message *dnsmessage.Message) {
 	}
 	matched, err := server.matchExpectedIPs(d.domain, ips)
 	if err != nil {
+		d.errors = append(d.errors, err)
+		d.cancel()
 		return
 	}
 	d.ips = append(d.ips, matched...)
@@ -854,8 +1072,7 @@ func (c *Client) writeBack(server *Server, message *dnsmessage.Message) {
 
 		newError(server.name, " got answer: ", d.domain, " -> ", queryType, " ", d.ips).AtDebug().WriteToLog(session.ExportIDToError(d.ctx))
 
-		d.queryCallback.response = d
-		d.queryCallback.cancel()
+		d.queryCallback.finish(d)
 		d.cancel()
 	}
 }
@@ -0,0 +1,323 @@
+package dns_test
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	core "github.com/v2fly/v2ray-core/v5"
+	dnsapp "github.com/v2fly/v2ray-core/v5/app/dns"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/serial"
+	feature_dns "github.com/v2fly/v2ray-core/v5/features/dns"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// subnetHandler answers A queries with the EDNS0 client subnet of the query,
+// or 127.0.0.1 if there is none, and AAAA queries with ::1.
+type subnetHandler struct{}
+
+func (*subnetHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	ans := new(dns.Msg)
+	ans.SetReply(r)
+
+	clientIP := net.IP{127, 0, 0, 1}
+	if opt := r.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if subnet, ok := o.(*dns.EDNS0_SUBNET); ok {
+				clientIP = subnet.Address
+			}
+		}
+	}
+
+	q := r.Question[0]
+	switch q.Qtype {
+	case dns.TypeA:
+		ans.Answer = append(ans.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: q.Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   clientIP,
+		})
+	case dns.TypeAAAA:
+		ans.Answer = append(ans.Answer, mustNewRR(q.Name+" 300 IN AAAA ::1"))
+	}
+	w.WriteMsg(ans)
+}
+
+func TestDomainOverride(t *testing.T) {
+	port, closeServer := startDNSServer(&subnetHandler{})
+	defer closeServer()
+
+	time.Sleep(time.Second)
+
+	config := &core.Config{
+		App: []*anypb.Any{
+			serial.ToTypedMessage(&dnsapp.Config{
+				NameServer: []*dnsapp.NameServer{
+					{
+						Address: &net.Endpoint{
+							Network: net.Network_UDP,
+							Address: net.NewIPOrDomain(net.DomainAddress("udp+local://127.0.0.1:" + port.String())),
+						},
+					},
+				},
+				ClientIp: []byte{10, 0, 0, 1},
+				DomainOverride: []*dnsapp.DomainOverride{
+					{
+						Domain: []*dnsapp.NameServer_PriorityDomain{
+							{Type: dnsapp.DomainMatchingType_Subdomain, Domain: "cdn.example"},
+						},
+						ClientIp: []byte{1, 2, 3, 4},
+					},
+					{
+						Domain: []*dnsapp.NameServer_PriorityDomain{
+							{Type: dnsapp.DomainMatchingType_Full, Domain: "plain.example"},
+						},
+						ClientIp: []byte{0, 0, 0, 0},
+					},
+					{
+						Domain: []*dnsapp.NameServer_PriorityDomain{
+							{Type: dnsapp.DomainMatchingType_Full, Domain: "ipv4.example"},
+						},
+						QueryStrategy: dnsapp.QueryStrategy_USE_IP4,
+					},
+				},
+			}),
+		},
+	}
+
+	v, err := core.New(config)
+	common.Must(err)
+	common.Must(v.Start())
+	defer v.Close()
+
+	client := v.GetFeature(feature_dns.ClientType()).(feature_dns.NewClient)
+
+	for domain, expected := range map[string][]net.IP{
+		"www.cdn.example": {{1, 2, 3, 0}},
+		"plain.example":   {{127, 0, 0, 1}},
+		"other.example":   {{10, 0, 0, 0}},
+	} {
+		ips, err := client.LookupIPv4(domain)
+		if err != nil {
+			t.Fatal(domain, ": ", err)
+		}
+		if r := cmp.Diff(ips, expected); r != "" {
+			t.Error(domain, ": ", r)
+		}
+	}
+
+	ips, err := client.LookupIP("ipv4.example")
+	common.Must(err)
+	if r := cmp.Diff(ips, []net.IP{{10, 0, 0, 0}}); r != "" {
+		t.Error(r)
+	}
+
+	if _, err := client.LookupIPv6("ipv4.example"); err != feature_dns.ErrEmptyResponse {
+		t.Error("expected empty response, but got ", err)
+	}
+}
+
+type delayedHandler struct {
+	ip    string
+	delay time.Duration
+}
+
+func (h *delayedHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	time.Sleep(h.delay)
+	ans := new(dns.Msg)
+	ans.SetReply(r)
+	if q := r.Question[0]; q.Qtype == dns.TypeA {
+		ans.Answer = append(ans.Answer, mustNewRR(q.Name+" 300 IN A "+h.ip))
+	}
+	w.WriteMsg(ans)
+}
+
+func TestServerStrategy(t *testing.T) {
+	slowPort, closeSlow := startDNSServer(&delayedHandler{ip: "1.1.1.1", delay: 2 * time.Second})
+	defer closeSlow()
+	fastPort, closeFast := startDNSServer(&delayedHandler{ip: "2.2.2.2"})
+	defer closeFast()
+	otherPort, closeOther := startDNSServer(&delayedHandler{ip: "3.3.3.3", delay: 100 * time.Millisecond})
+	defer closeOther()
+
+	time.Sleep(time.Second)
+
+	nameServer := func(port net.Port, timeoutMs uint32) *dnsapp.NameServer {
+		return &dnsapp.NameServer{
+			Address: &net.Endpoint{
+				Network: net.Network_UDP,
+				Address: net.NewIPOrDomain(net.DomainAddress("udp+local://127.0.0.1:" + port.String())),
+			},
+			TimeoutMs: timeoutMs,
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		config   *dnsapp.Config
+		domain   string
+		expected []net.IP
+	}{
+		{
+			name: "sequential",
+			config: &dnsapp.Config{
+				NameServer: []*dnsapp.NameServer{nameServer(slowPort, 200), nameServer(fastPort, 0)},
+			},
+			domain:   "sequential.example",
+			expected: []net.IP{{2, 2, 2, 2}},
+		},
+		{
+			name: "fastest",
+			config: &dnsapp.Config{
+				NameServer:     []*dnsapp.NameServer{nameServer(slowPort, 0), nameServer(otherPort, 0), nameServer(fastPort, 0)},
+				ServerStrategy: dnsapp.ServerStrategy_FASTEST,
+			},
+			domain:   "fastest.example",
+			expected: []net.IP{{2, 2, 2, 2}},
+		},
+		{
+			name: "merge",
+			config: &dnsapp.Config{
+				NameServer:          []*dnsapp.NameServer{nameServer(fastPort, 0), nameServer(otherPort, 0), nameServer(slowPort, 0)},
+				ServerStrategy:      dnsapp.ServerStrategy_MERGE,
+				ParallelServerCount: 2,
+			},
+			domain:   "merge.example",
+			expected: []net.IP{{2, 2, 2, 2}, {3, 3, 3, 3}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			config := &core.Config{
+				App: []*anypb.Any{serial.ToTypedMessage(testCase.config)},
+			}
+			v, err := core.New(config)
+			common.Must(err)
+			common.Must(v.Start())
+			defer v.Close()
+
+			client := v.GetFeature(feature_dns.ClientType()).(feature_dns.NewClient)
+
+			start := time.Now()
+			ips, err := client.LookupIPv4(testCase.domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Error("lookup took ", elapsed)
+			}
+			sort.Slice(ips, func(i, j int) bool {
+				return bytes.Compare(ips[i], ips[j]) < 0
+			})
+			if r := cmp.Diff(ips, testCase.expected); r != "" {
+				t.Error(r)
+			}
+		})
+	}
+}
+
+func TestQueryRawServerStrategy(t *testing.T) {
+	slowPort, closeSlow := startDNSServer(&delayedHandler{ip: "1.1.1.1", delay: 2 * time.Second})
+	defer closeSlow()
+	fastPort, closeFast := startDNSServer(&delayedHandler{ip: "2.2.2.2"})
+	defer closeFast()
+	otherPort, closeOther := startDNSServer(&delayedHandler{ip: "3.3.3.3", delay: 100 * time.Millisecond})
+	defer closeOther()
+
+	time.Sleep(time.Second)
+
+	nameServer := func(port net.Port, timeoutMs uint32) *dnsapp.NameServer {
+		return &dnsapp.NameServer{
+			Address: &net.Endpoint{
+				Network: net.Network_UDP,
+				Address: net.NewIPOrDomain(net.DomainAddress("udp+local://127.0.0.1:" + port.String())),
+			},
+			TimeoutMs: timeoutMs,
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		config   *dnsapp.Config
+		domain   string
+		expected []string
+	}{
+		{
+			// Each server is queried after the previous one failed.
+			name: "sequential",
+			config: &dnsapp.Config{
+				NameServer: []*dnsapp.NameServer{nameServer(slowPort, 200), nameServer(fastPort, 0)},
+			},
+			domain:   "sequential.example.",
+			expected: []string{"2.2.2.2"},
+		},
+		{
+			name: "fastest",
+			config: &dnsapp.Config{
+				NameServer:     []*dnsapp.NameServer{nameServer(slowPort, 0), nameServer(fastPort, 0)},
+				ServerStrategy: dnsapp.ServerStrategy_FASTEST,
+			},
+			domain:   "fastest.example.",
+			expected: []string{"2.2.2.2"},
+		},
+		{
+			// Answer records of all servers are merged.
+			name: "merge",
+			config: &dnsapp.Config{
+				NameServer:          []*dnsapp.NameServer{nameServer(fastPort, 0), nameServer(otherPort, 0), nameServer(slowPort, 0)},
+				ServerStrategy:      dnsapp.ServerStrategy_MERGE,
+				ParallelServerCount: 2,
+			},
+			domain:   "merge.example.",
+			expected: []string{"2.2.2.2", "3.3.3.3"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			config := &core.Config{
+				App: []*anypb.Any{serial.ToTypedMessage(testCase.config)},
+			}
+			v, err := core.New(config)
+			common.Must(err)
+			common.Must(v.Start())
+			defer v.Close()
+
+			client := v.GetFeature(feature_dns.ClientType()).(feature_dns.NewClient)
+
+			query := new(dns.Msg)
+			query.SetQuestion(testCase.domain, dns.TypeA)
+			packed, err := query.Pack()
+			common.Must(err)
+
+			start := time.Now()
+			response, err := client.QueryRaw(context.Background(), buf.FromBytes(packed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Error("query took ", elapsed)
+			}
+			answer := new(dns.Msg)
+			common.Must(answer.Unpack(response.Bytes()))
+			response.Release()
+			var ips []string
+			for _, rr := range answer.Answer {
+				ips = append(ips, rr.(*dns.A).A.String())
+			}
+			sort.Strings(ips)
+			if r := cmp.Diff(ips, testCase.expected); r != "" {
+				t.Error(r)
+			}
+		})
+	}
+}
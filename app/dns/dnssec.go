@@ -0,0 +1,724 @@
+package dns
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	mdns "github.com/miekg/dns"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/features/dns"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DefaultDNSSECTrustAnchors are the DS records of the IANA root zone key signing keys.
+var DefaultDNSSECTrustAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+const (
+	dnssecUDPSize     = 1232
+	dnssecMaxCacheTTL = time.Hour
+)
+
+func parseTrustAnchors(anchors []string) ([]*mdns.DS, error) {
+	if len(anchors) == 0 {
+		anchors = DefaultDNSSECTrustAnchors
+	}
+	var records []*mdns.DS
+	for _, anchor := range anchors {
+		rr, err := mdns.NewRR(anchor)
+		if err != nil {
+			return nil, newError("failed to parse trust anchor ", anchor).Base(err)
+		}
+		ds, isDS := rr.(*mdns.DS)
+		if !isDS || ds.Hdr.Name != "." {
+			return nil, newError("trust anchor is not a DS record of the root zone: ", anchor)
+		}
+		records = append(records, ds)
+	}
+	return records, nil
+}
+
+// withDNSSECOK returns a copy of message with the DO bit set in its EDNS0 record.
+func withDNSSECOK(message *dnsmessage.Message) *dnsmessage.Message {
+	query := *message
+	query.Additionals = make([]dnsmessage.Resource, 0, len(message.Additionals)+1)
+	var hasOPT bool
+	for _, resource := range message.Additionals {
+		if resource.Header.Type == dnsmessage.TypeOPT {
+			resource.Header.TTL |= 1 << 15
+			hasOPT = true
+		}
+		query.Additionals = append(query.Additionals, resource)
+	}
+	if !hasOPT {
+		opt := dnsmessage.Resource{Body: &dnsmessage.OPTResource{}}
+		common.Must(opt.Header.SetEDNS0(dnssecUDPSize, dnsmessage.RCodeSuccess, true))
+		query.Additionals = append(query.Additionals, opt)
+	}
+	return &query
+}
+
+// dnssecZone is a zone on the delegation chain. A zone without keys is insecure.
+type dnssecZone struct {
+	name string
+	keys []*mdns.DNSKEY
+}
+
+type dnssecCacheEntry struct {
+	// zone is nil if the name is not a zone cut.
+	zone   *dnssecZone
+	expire time.Time
+}
+
+type dnssecRRSet struct {
+	name   string
+	rrtype uint16
+	rrs    []mdns.RR
+	sigs   []*mdns.RRSIG
+}
+
+// dnssecValidator verifies answers of a name server against the chain of trust
+// starting from the configured root trust anchors. Keys and delegations needed
+// for the chain are queried from the same name server.
+type dnssecValidator struct {
+	client  *Client
+	server  *Server
+	anchors []*mdns.DS
+
+	access sync.Mutex
+	cache  map[string]*dnssecCacheEntry
+}
+
+func newDNSSECValidator(client *Client, server *Server, anchors []*mdns.DS) *dnssecValidator {
+	return &dnssecValidator{
+		client:  client,
+		server:  server,
+		anchors: anchors,
+		cache:   make(map[string]*dnssecCacheEntry),
+	}
+}
+
+// validate returns an error if message is bogus. Answers proven to be insecure
+// by a signed delegation without DS records are accepted.
+func (v *dnssecValidator) validate(ctx context.Context, message *dnsmessage.Message) error {
+	msg, err := toDNSSECMessage(message)
+	if err != nil {
+		return err
+	}
+	if len(msg.Question) == 0 {
+		return newError("missing question in response")
+	}
+	qname := mdns.CanonicalName(msg.Question[0].Name)
+	qtype := msg.Question[0].Qtype
+
+	answers := groupRRSets(msg.Answer)
+	target, err := cnameChainTarget(qname, qtype, answers)
+	if err != nil {
+		return err
+	}
+	var answered bool
+	for _, rrset := range answers {
+		if err := v.verifyRRSet(ctx, rrset); err != nil {
+			return err
+		}
+		if rrset.name == target && (rrset.rrtype == qtype || qtype == mdns.TypeANY) {
+			answered = true
+		}
+	}
+	nameError := msg.Rcode == mdns.RcodeNameError
+	if answered && !nameError {
+		return nil
+	}
+
+	// No data or no such name, which must be proven by NSEC or NSEC3 records
+	// in secure zones.
+	var denials []*dnssecRRSet
+	for _, rrset := range groupRRSets(msg.Ns) {
+		if len(rrset.sigs) == 0 {
+			continue
+		}
+		if err := v.verifyRRSet(ctx, rrset); err != nil {
+			return err
+		}
+		denials = append(denials, rrset)
+	}
+	if nameError && deniesName(denials, target) {
+		return nil
+	}
+	if !nameError {
+		for _, rrset := range denials {
+			if deniesType(rrset, target, qtype) {
+				return nil
+			}
+		}
+	}
+	zone, err := v.closestZone(ctx, target)
+	if err != nil {
+		return err
+	}
+	if zone.keys == nil {
+		return nil
+	}
+	if nameError {
+		return newError("missing proof of nonexistence of ", target, " in secure zone ", zone.name)
+	}
+	return newError("missing proof of nonexistence of ", mdns.TypeToString[qtype], " records of ", target, " in secure zone ", zone.name)
+}
+
+// cnameChainTarget follows the CNAME chain from qname in answers, and returns
+// the name at its end. Records of names outside the chain fail the answer, as
+// they may be replayed from any other signed zone.
+func cnameChainTarget(qname string, qtype uint16, answers []*dnssecRRSet) (string, error) {
+	target := qname
+	chain := map[string]bool{qname: true}
+	for qtype != mdns.TypeCNAME && qtype != mdns.TypeANY {
+		var next string
+		for _, rrset := range answers {
+			if rrset.name == target && rrset.rrtype == mdns.TypeCNAME {
+				next = mdns.CanonicalName(rrset.rrs[0].(*mdns.CNAME).Target)
+			}
+		}
+		if next == "" || chain[next] {
+			break
+		}
+		chain[next] = true
+		target = next
+	}
+
+	for _, rrset := range answers {
+		if chain[rrset.name] {
+			continue
+		}
+		if rrset.rrtype == mdns.TypeDNAME && dnameOfChain(rrset.name, chain) {
+			continue
+		}
+		return "", newError("unexpected ", mdns.TypeToString[rrset.rrtype], " records of ", rrset.name, " in answer to ", qname)
+	}
+	return target, nil
+}
+
+// dnameOfChain reports whether a DNAME record of owner redirects a name in chain.
+func dnameOfChain(owner string, chain map[string]bool) bool {
+	for name := range chain {
+		if name != owner && mdns.IsSubDomain(owner, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// deniesType reports whether the NSEC or NSEC3 records in rrset prove that
+// name has no records of qtype. They must be signed by a zone enclosing name.
+func deniesType(rrset *dnssecRRSet, name string, qtype uint16) bool {
+	if !signedAbove(rrset, name) {
+		return false
+	}
+	for _, rr := range rrset.rrs {
+		switch rr := rr.(type) {
+		case *mdns.NSEC:
+			if mdns.CanonicalName(rr.Hdr.Name) == name && !hasType(rr.TypeBitMap, qtype) && !hasType(rr.TypeBitMap, mdns.TypeCNAME) {
+				return true
+			}
+		case *mdns.NSEC3:
+			if rr.Match(name) && !hasType(rr.TypeBitMap, qtype) && !hasType(rr.TypeBitMap, mdns.TypeCNAME) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// deniesName reports whether the NSEC or NSEC3 records in rrsets prove that
+// name does not exist, and that no wildcard expands to it. They must be signed
+// by a zone enclosing name.
+func deniesName(rrsets []*dnssecRRSet, name string) bool {
+	var nsecs []*mdns.NSEC
+	var nsec3s []*mdns.NSEC3
+	for _, rrset := range rrsets {
+		if !signedAbove(rrset, name) {
+			continue
+		}
+		for _, rr := range rrset.rrs {
+			switch rr := rr.(type) {
+			case *mdns.NSEC:
+				nsecs = append(nsecs, rr)
+			case *mdns.NSEC3:
+				nsec3s = append(nsec3s, rr)
+			}
+		}
+	}
+	return nsecDeniesName(nsecs, name) || nsec3DeniesName(nsec3s, name)
+}
+
+// nsecDeniesName checks the proof of RFC 4035 section 5.4: an NSEC record
+// covers name, and another covers the wildcard at its closest encloser.
+func nsecDeniesName(nsecs []*mdns.NSEC, name string) bool {
+	for _, nsec := range nsecs {
+		owner, next := mdns.CanonicalName(nsec.Hdr.Name), mdns.CanonicalName(nsec.NextDomain)
+		if !nsecCovers(owner, next, name) {
+			continue
+		}
+		labels := mdns.CompareDomainName(name, owner)
+		if n := mdns.CompareDomainName(name, next); n > labels {
+			labels = n
+		}
+		wildcard := "*." + ancestor(name, labels)
+		if wildcard == "*.." {
+			wildcard = "*."
+		}
+		for _, w := range nsecs {
+			if nsecCovers(mdns.CanonicalName(w.Hdr.Name), mdns.CanonicalName(w.NextDomain), wildcard) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nsecCovers reports whether name sorts strictly between owner and next, the
+// last NSEC record of a zone pointing back to its apex.
+func nsecCovers(owner, next, name string) bool {
+	afterOwner := canonicalCompare(owner, name) < 0
+	beforeNext := canonicalCompare(name, next) < 0
+	if canonicalCompare(owner, next) < 0 {
+		return afterOwner && beforeNext
+	}
+	return afterOwner || beforeNext
+}
+
+// nsec3DeniesName checks the closest encloser proof of RFC 5155 section 8.4:
+// an NSEC3 record matches the closest encloser, and others cover the next
+// closer name and the wildcard at the closest encloser.
+func nsec3DeniesName(nsec3s []*mdns.NSEC3, name string) bool {
+	labels := mdns.CountLabel(name)
+	for n := labels - 1; n >= 0; n-- {
+		encloser := ancestor(name, n)
+		if !nsec3Any(nsec3s, func(rr *mdns.NSEC3) bool { return rr.Match(encloser) }) {
+			continue
+		}
+		nextCloser := ancestor(name, n+1)
+		wildcard := "*." + encloser
+		if encloser == "." {
+			wildcard = "*."
+		}
+		return nsec3Any(nsec3s, func(rr *mdns.NSEC3) bool { return rr.Cover(nextCloser) }) &&
+			nsec3Any(nsec3s, func(rr *mdns.NSEC3) bool { return rr.Cover(wildcard) })
+	}
+	return false
+}
+
+func nsec3Any(nsec3s []*mdns.NSEC3, f func(*mdns.NSEC3) bool) bool {
+	for _, rr := range nsec3s {
+		if f(rr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ancestor returns the last labels labels of name.
+func ancestor(name string, labels int) string {
+	split := mdns.SplitDomainName(name)
+	return mdns.Fqdn(strings.Join(split[len(split)-labels:], "."))
+}
+
+// canonicalCompare compares names in the canonical order of RFC 4034 section 6.1.
+func canonicalCompare(a, b string) int {
+	la, lb := mdns.SplitDomainName(a), mdns.SplitDomainName(b)
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(strings.ToLower(la[i]), strings.ToLower(lb[j])); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// signedAbove reports whether all signatures of rrset are made by zones enclosing name.
+func signedAbove(rrset *dnssecRRSet, name string) bool {
+	for _, sig := range rrset.sigs {
+		if !mdns.IsSubDomain(mdns.CanonicalName(sig.SignerName), name) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasType(types []uint16, rrtype uint16) bool {
+	for _, t := range types {
+		if t == rrtype {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *dnssecValidator) verifyRRSet(ctx context.Context, rrset *dnssecRRSet) error {
+	if len(rrset.sigs) == 0 {
+		return v.verifyInsecure(ctx, rrset.name)
+	}
+	var lastErr error
+	for _, sig := range rrset.sigs {
+		signer := mdns.CanonicalName(sig.SignerName)
+		if !mdns.IsSubDomain(signer, rrset.name) {
+			lastErr = newError("signer ", signer, " is not a parent of ", rrset.name)
+			continue
+		}
+		if !sig.ValidityPeriod(time.Now()) {
+			lastErr = newError("signature of ", rrset.name, " by ", signer, " is expired")
+			continue
+		}
+		zone, err := v.closestZone(ctx, signer)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if zone.keys == nil {
+			return nil
+		}
+		if zone.name != signer {
+			lastErr = newError("signer ", signer, " is not a zone")
+			continue
+		}
+		if verifySignature(sig, zone.keys, rrset.rrs) {
+			return nil
+		}
+		lastErr = newError("no key of ", signer, " verifies the signature")
+	}
+	return newError("bogus ", mdns.TypeToString[rrset.rrtype], " records of ", rrset.name).Base(lastErr)
+}
+
+func (v *dnssecValidator) verifyInsecure(ctx context.Context, name string) error {
+	zone, err := v.closestZone(ctx, name)
+	if err != nil {
+		return err
+	}
+	if zone.keys != nil {
+		return newError("missing signature for ", name, " in secure zone ", zone.name)
+	}
+	return nil
+}
+
+// closestZone walks the delegation chain from the root towards name, and
+// returns the closest enclosing zone, or the first insecure one on the way.
+func (v *dnssecValidator) closestZone(ctx context.Context, name string) (*dnssecZone, error) {
+	zone, err := v.rootZone(ctx)
+	if err != nil {
+		return nil, err
+	}
+	labels := mdns.SplitDomainName(name)
+	for i := len(labels) - 1; i >= 0 && zone.keys != nil; i-- {
+		child := mdns.Fqdn(strings.Join(labels[i:], "."))
+		next, err := v.delegation(ctx, zone, child)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			zone = next
+		}
+	}
+	return zone, nil
+}
+
+func (v *dnssecValidator) rootZone(ctx context.Context) (*dnssecZone, error) {
+	if entry := v.cached("."); entry != nil {
+		return entry.zone, nil
+	}
+	zone, ttl, err := v.loadKeys(ctx, ".", v.anchors)
+	if err != nil {
+		return nil, err
+	}
+	v.store(".", zone, ttl)
+	return zone, nil
+}
+
+// delegation looks up the DS records of child in the secure parent zone. It
+// returns nil if child is not a zone cut, and a zone without keys if child is
+// an insecure delegation.
+func (v *dnssecValidator) delegation(ctx context.Context, parent *dnssecZone, child string) (*dnssecZone, error) {
+	if entry := v.cached(child); entry != nil {
+		return entry.zone, nil
+	}
+
+	response, err := v.query(ctx, child, mdns.TypeDS)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rrset := range groupRRSets(response.Answer) {
+		if rrset.rrtype != mdns.TypeDS || rrset.name != child {
+			continue
+		}
+		if !verifyRRSetWith(rrset, parent) {
+			return nil, newError("bogus DS records of ", child)
+		}
+		var dsSet []*mdns.DS
+		for _, rr := range rrset.rrs {
+			dsSet = append(dsSet, rr.(*mdns.DS))
+		}
+		zone, ttl, err := v.loadKeys(ctx, child, dsSet)
+		if err != nil {
+			return nil, err
+		}
+		if ttl > rrset.rrs[0].Header().Ttl {
+			ttl = rrset.rrs[0].Header().Ttl
+		}
+		v.store(child, zone, ttl)
+		return zone, nil
+	}
+
+	var proven bool
+	var cut, insecure bool
+	ttl := uint32(dnssecMaxCacheTTL / time.Second)
+	for _, rrset := range groupRRSets(response.Ns) {
+		if rrset.rrtype != mdns.TypeNSEC && rrset.rrtype != mdns.TypeNSEC3 {
+			continue
+		}
+		if !verifyRRSetWith(rrset, parent) {
+			return nil, newError("bogus denial of DS records of ", child)
+		}
+		proven = true
+		for _, rr := range rrset.rrs {
+			if rr.Header().Ttl < ttl {
+				ttl = rr.Header().Ttl
+			}
+			switch rr := rr.(type) {
+			case *mdns.NSEC:
+				if mdns.CanonicalName(rr.Hdr.Name) == child {
+					cut, insecure = delegationFromBitmap(rr.TypeBitMap)
+				}
+			case *mdns.NSEC3:
+				if rr.Match(child) {
+					cut, insecure = delegationFromBitmap(rr.TypeBitMap)
+				} else if rr.Cover(child) && rr.Flags&1 != 0 {
+					// Opt-out span, unsigned delegations may exist here.
+					cut, insecure = true, true
+				}
+			}
+		}
+	}
+	if !proven {
+		return nil, newError("missing proof of absence of DS records of ", child)
+	}
+
+	var zone *dnssecZone
+	if cut && insecure {
+		newError("insecure delegation to ", child).AtDebug().WriteToLog()
+		zone = &dnssecZone{name: child}
+	}
+	v.store(child, zone, ttl)
+	return zone, nil
+}
+
+// loadKeys queries the DNSKEY records of zone and verifies them against its DS records.
+func (v *dnssecValidator) loadKeys(ctx context.Context, name string, dsSet []*mdns.DS) (*dnssecZone, uint32, error) {
+	response, err := v.query(ctx, name, mdns.TypeDNSKEY)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, rrset := range groupRRSets(response.Answer) {
+		if rrset.rrtype != mdns.TypeDNSKEY || rrset.name != name {
+			continue
+		}
+		var keys, trusted []*mdns.DNSKEY
+		for _, rr := range rrset.rrs {
+			key := rr.(*mdns.DNSKEY)
+			keys = append(keys, key)
+			for _, ds := range dsSet {
+				if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+					continue
+				}
+				if digest := key.ToDS(ds.DigestType); digest != nil && strings.EqualFold(digest.Digest, ds.Digest) {
+					trusted = append(trusted, key)
+					break
+				}
+			}
+		}
+		if len(trusted) == 0 {
+			return nil, 0, newError("no DNSKEY of ", name, " matches its DS records")
+		}
+		for _, sig := range rrset.sigs {
+			if sig.ValidityPeriod(time.Now()) && verifySignature(sig, trusted, rrset.rrs) {
+				return &dnssecZone{name: name, keys: keys}, rrset.rrs[0].Header().Ttl, nil
+			}
+		}
+		return nil, 0, newError("bogus DNSKEY records of ", name)
+	}
+	return nil, 0, newError("missing DNSKEY records of ", name)
+}
+
+func (v *dnssecValidator) query(ctx context.Context, name string, qtype uint16) (*mdns.Msg, error) {
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, newError("failed to create domain query").Base(err)
+	}
+	message := withDNSSECOK(&dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               v.client.nextRequestId(),
+			RecursionDesired: true,
+		},
+		Questions: []dnsmessage.Question{{
+			Name:  qname,
+			Type:  dnsmessage.Type(qtype),
+			Class: dnsmessage.ClassINET,
+		}},
+	})
+	response, err := v.client.exchange(ctx, v.server, message)
+	if err != nil {
+		return nil, newError("failed to query ", mdns.TypeToString[qtype], " records of ", name).Base(err)
+	}
+	if response.RCode != dnsmessage.RCodeSuccess {
+		return nil, newError("failed to query ", mdns.TypeToString[qtype], " records of ", name).Base(dns.RCodeError(response.RCode))
+	}
+	return toDNSSECMessage(response)
+}
+
+func (v *dnssecValidator) cached(name string) *dnssecCacheEntry {
+	v.access.Lock()
+	defer v.access.Unlock()
+	entry, found := v.cache[name]
+	if !found || time.Now().After(entry.expire) {
+		return nil
+	}
+	return entry
+}
+
+func (v *dnssecValidator) store(name string, zone *dnssecZone, ttl uint32) {
+	expire := time.Duration(ttl) * time.Second
+	if expire > dnssecMaxCacheTTL {
+		expire = dnssecMaxCacheTTL
+	}
+	v.access.Lock()
+	defer v.access.Unlock()
+	v.cache[name] = &dnssecCacheEntry{
+		zone:   zone,
+		expire: time.Now().Add(expire),
+	}
+}
+
+func verifyRRSetWith(rrset *dnssecRRSet, zone *dnssecZone) bool {
+	for _, sig := range rrset.sigs {
+		if mdns.CanonicalName(sig.SignerName) == zone.name && sig.ValidityPeriod(time.Now()) && verifySignature(sig, zone.keys, rrset.rrs) {
+			return true
+		}
+	}
+	return false
+}
+
+func verifySignature(sig *mdns.RRSIG, keys []*mdns.DNSKEY, rrs []mdns.RR) bool {
+	for _, key := range keys {
+		if key.KeyTag() == sig.KeyTag && key.Algorithm == sig.Algorithm && sig.Verify(key, rrs) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// delegationFromBitmap reports whether the owner of a NSEC record is a zone
+// cut, and whether it's delegated without DS records.
+func delegationFromBitmap(types []uint16) (cut bool, insecure bool) {
+	var hasNS, hasSOA, hasDS bool
+	for _, t := range types {
+		switch t {
+		case mdns.TypeNS:
+			hasNS = true
+		case mdns.TypeSOA:
+			hasSOA = true
+		case mdns.TypeDS:
+			hasDS = true
+		}
+	}
+	cut = hasNS && !hasSOA
+	return cut, cut && !hasDS
+}
+
+func groupRRSets(rrs []mdns.RR) []*dnssecRRSet {
+	var rrsets []*dnssecRRSet
+	find := func(name string, rrtype uint16) *dnssecRRSet {
+		for _, rrset := range rrsets {
+			if rrset.name == name && rrset.rrtype == rrtype {
+				return rrset
+			}
+		}
+		rrset := &dnssecRRSet{name: name, rrtype: rrtype}
+		rrsets = append(rrsets, rrset)
+		return rrset
+	}
+	for _, rr := range rrs {
+		name := mdns.CanonicalName(rr.Header().Name)
+		switch rr := rr.(type) {
+		case *mdns.OPT:
+		case *mdns.RRSIG:
+			rrset := find(name, rr.TypeCovered)
+			rrset.sigs = append(rrset.sigs, rr)
+		default:
+			rrset := find(name, rr.Header().Rrtype)
+			rrset.rrs = append(rrset.rrs, rr)
+		}
+	}
+	filtered := rrsets[:0]
+	for _, rrset := range rrsets {
+		if len(rrset.rrs) > 0 {
+			filtered = append(filtered, rrset)
+		}
+	}
+	return filtered
+}
+
+func toDNSSECMessage(message *dnsmessage.Message) (*mdns.Msg, error) {
+	packed, err := message.Pack()
+	if err != nil {
+		return nil, newError("failed to pack dns message").Base(err)
+	}
+	msg := new(mdns.Msg)
+	if err := msg.Unpack(packed); err != nil {
+		return nil, newError("failed to parse dns message").Base(err)
+	}
+	return msg, nil
+}
+
+type exchangeCallback struct {
+	response chan *dnsmessage.Message
+}
+
+// exchange sends a single query to server and waits for its response.
+func (c *Client) exchange(ctx context.Context, server *Server, message *dnsmessage.Message) (*dnsmessage.Message, error) {
+	switch server.transport.Type() {
+	case dns.TransportTypeDefault:
+		callback := &exchangeCallback{response: make(chan *dnsmessage.Message, 1)}
+		c.callbacks.Store(message.ID, callback)
+		defer c.callbacks.Delete(message.ID)
+		if err := server.transport.Write(ctx, message); err != nil {
+			return nil, err
+		}
+		select {
+		case response := <-callback.response:
+			return response, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	case dns.TransportTypeExchange:
+		return server.transport.Exchange(ctx, message)
+	case dns.TransportTypeExchangeRaw:
+		packed, err := message.Pack()
+		if err != nil {
+			return nil, newError("failed to pack dns query").Base(err)
+		}
+		buffer, err := server.transport.ExchangeRaw(ctx, buf.FromBytes(packed))
+		if err != nil {
+			return nil, err
+		}
+		defer buffer.Release()
+		response := new(dnsmessage.Message)
+		if err := response.Unpack(buffer.Bytes()); err != nil {
+			return nil, newError("failed to parse dns response").Base(err)
+		}
+		return response, nil
+	default:
+		return nil, newError("raw queries are not supported by ", server.name)
+	}
+}
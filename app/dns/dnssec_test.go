@@ -0,0 +1,249 @@
+package dns_test
+
+import (
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	core "github.com/v2fly/v2ray-core/v5"
+	dnsapp "github.com/v2fly/v2ray-core/v5/app/dns"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/serial"
+	feature_dns "github.com/v2fly/v2ray-core/v5/features/dns"
+	"github.com/v2fly/v2ray-core/v5/testing/servers/udp"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+type signedZone struct {
+	name string
+	key  *dns.DNSKEY
+	priv crypto.Signer
+}
+
+func newSignedZone(name string) *signedZone {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	common.Must(err)
+	return &signedZone{
+		name: name,
+		key:  key,
+		priv: priv.(crypto.Signer),
+	}
+}
+
+func (z *signedZone) sign(rrs ...dns.RR) []dns.RR {
+	header := rrs[0].Header()
+	now := uint32(time.Now().Unix())
+	sig := &dns.RRSIG{
+		Hdr:         dns.RR_Header{Name: header.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: header.Ttl},
+		TypeCovered: header.Rrtype,
+		Algorithm:   z.key.Algorithm,
+		SignerName:  z.name,
+		KeyTag:      z.key.KeyTag(),
+		Inception:   now - 3600,
+		Expiration:  now + 3600,
+	}
+	common.Must(sig.Sign(z.priv, rrs))
+	return append(rrs, sig)
+}
+
+type zoneHandler struct {
+	answers   map[string][]dns.RR
+	authority map[string][]dns.RR
+	rcodes    map[string]int
+}
+
+func (h *zoneHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	ans := new(dns.Msg)
+	ans.SetReply(r)
+	q := r.Question[0]
+	key := dns.CanonicalName(q.Name) + "/" + dns.TypeToString[q.Qtype]
+	ans.Answer = h.answers[key]
+	ans.Ns = h.authority[key]
+	ans.Rcode = h.rcodes[key]
+	w.WriteMsg(ans)
+}
+
+func mustNewRR(s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	common.Must(err)
+	return rr
+}
+
+func startDNSServer(handler dns.Handler) (net.Port, func() error) {
+	port := udp.PickPort()
+	server := &dns.Server{
+		Addr:    "127.0.0.1:" + port.String(),
+		Net:     "udp",
+		Handler: handler,
+	}
+	go server.ListenAndServe()
+	return port, server.Shutdown
+}
+
+func TestDNSSECValidation(t *testing.T) {
+	root := newSignedZone(".")
+	example := newSignedZone("example.")
+
+	bogus := example.sign(mustNewRR("bogus.example. 300 IN A 6.6.6.6"))
+	bogus[0].(*dns.A).A = net.IP{6, 6, 6, 7}
+
+	nsec := func(name string, types ...uint16) []dns.RR {
+		return example.sign(&dns.NSEC{
+			Hdr:        dns.RR_Header{Name: name, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: "zzz.example.",
+			TypeBitMap: append(types, dns.TypeRRSIG, dns.TypeNSEC),
+		})
+	}
+
+	cover := func(owner, next string) []dns.RR {
+		return example.sign(&dns.NSEC{
+			Hdr:        dns.RR_Header{Name: owner, Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+			NextDomain: next,
+			TypeBitMap: []uint16{dns.TypeA, dns.TypeRRSIG, dns.TypeNSEC},
+		})
+	}
+	// The only hashed name of the zone is its apex, which covers all others.
+	apexHash := dns.HashName("example.", dns.SHA1, 0, "")
+	nsec3 := example.sign(&dns.NSEC3{
+		Hdr:        dns.RR_Header{Name: apexHash + ".example.", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 300},
+		Hash:       dns.SHA1,
+		HashLength: 20,
+		NextDomain: apexHash,
+		TypeBitMap: []uint16{dns.TypeSOA, dns.TypeRRSIG, dns.TypeDNSKEY},
+	})
+
+	signedPort, closeSigned := startDNSServer(&zoneHandler{
+		answers: map[string][]dns.RR{
+			"./DNSKEY":          root.sign(root.key),
+			"example./DS":       root.sign(example.key.ToDS(dns.SHA256)),
+			"example./DNSKEY":   example.sign(example.key),
+			"www.example./A":    example.sign(mustNewRR("www.example. 300 IN A 1.2.3.4")),
+			"bogus.example./A":  bogus,
+			"plain.insecure./A": {mustNewRR("plain.insecure. 300 IN A 5.5.5.5")},
+			"alias.example./A": append(
+				example.sign(mustNewRR("alias.example. 300 IN CNAME www.example.")),
+				example.sign(mustNewRR("www.example. 300 IN A 1.2.3.4"))...),
+			"replay.example./A": example.sign(mustNewRR("attacker.example. 300 IN A 6.6.6.6")),
+		},
+		authority: map[string][]dns.RR{
+			"nodata.example./A":     nsec("nodata.example.", dns.TypeTXT),
+			"othernsec.example./A":  nsec("other.example.", dns.TypeTXT),
+			"typednsec.example./A":  nsec("typednsec.example.", dns.TypeA),
+			"unproven.example./A":   example.sign(mustNewRR("example. 300 IN SOA ns.example. admin.example. 1 3600 600 86400 300")),
+			"gone.example./A":       append(cover("fo.example.", "gp.example."), cover("example.", "alias.example.")...),
+			"gone3.example./A":      nsec3,
+			"nowildcard.example./A": cover("nov.example.", "nox.example."),
+			"insecure./DS": root.sign(&dns.NSEC{
+				Hdr:        dns.RR_Header{Name: "insecure.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 300},
+				NextDomain: "test.",
+				TypeBitMap: []uint16{dns.TypeNS, dns.TypeRRSIG, dns.TypeNSEC},
+			}),
+		},
+		rcodes: map[string]int{
+			"gone.example./A":       dns.RcodeNameError,
+			"gone3.example./A":      dns.RcodeNameError,
+			"nowildcard.example./A": dns.RcodeNameError,
+			"forged.example./A":     dns.RcodeNameError,
+			"servfail.example./A":   dns.RcodeServerFailure,
+		},
+	})
+	defer closeSigned()
+
+	fallbackPort, closeFallback := startDNSServer(&zoneHandler{
+		answers: map[string][]dns.RR{
+			"bogus.example./A":     {mustNewRR("bogus.example. 300 IN A 9.9.9.9")},
+			"replay.example./A":    {mustNewRR("replay.example. 300 IN A 9.9.9.8")},
+			"othernsec.example./A": {mustNewRR("othernsec.example. 300 IN A 9.9.9.7")},
+			"typednsec.example./A": {mustNewRR("typednsec.example. 300 IN A 9.9.9.6")},
+			"unproven.example./A":  {mustNewRR("unproven.example. 300 IN A 9.9.9.5")},
+			"nodata.example./A":    {mustNewRR("nodata.example. 300 IN A 9.9.9.4")},
+		},
+		rcodes: map[string]int{
+			"gone.example./A":       dns.RcodeServerFailure,
+			"gone3.example./A":      dns.RcodeServerFailure,
+			"forged.example./A":     dns.RcodeServerFailure,
+			"nowildcard.example./A": dns.RcodeServerFailure,
+			"servfail.example./A":   dns.RcodeNameError,
+		},
+	})
+	defer closeFallback()
+
+	time.Sleep(time.Second)
+
+	config := &core.Config{
+		App: []*anypb.Any{
+			serial.ToTypedMessage(&dnsapp.Config{
+				NameServer: []*dnsapp.NameServer{
+					{
+						Address: &net.Endpoint{
+							Network: net.Network_UDP,
+							Address: net.NewIPOrDomain(net.DomainAddress("udp+local://127.0.0.1:" + signedPort.String())),
+						},
+						Dnssec: true,
+					},
+					{
+						Address: &net.Endpoint{
+							Network: net.Network_UDP,
+							Address: net.NewIPOrDomain(net.DomainAddress("udp+local://127.0.0.1:" + fallbackPort.String())),
+						},
+					},
+				},
+				DnssecTrustAnchor: []string{root.key.ToDS(dns.SHA256).String()},
+			}),
+		},
+	}
+
+	v, err := core.New(config)
+	common.Must(err)
+	common.Must(v.Start())
+	defer v.Close()
+
+	client := v.GetFeature(feature_dns.ClientType()).(feature_dns.IPv4Lookup)
+
+	for domain, expected := range map[string][]net.IP{
+		"www.example":    {{1, 2, 3, 4}},
+		"plain.insecure": {{5, 5, 5, 5}},
+		"bogus.example":  {{9, 9, 9, 9}},
+		"alias.example":  {{1, 2, 3, 4}},
+		// Signed records of names outside the CNAME chain are bogus.
+		"replay.example": {{9, 9, 9, 8}},
+		// No data must be proven for the queried name and type.
+		"othernsec.example": {{9, 9, 9, 7}},
+		"typednsec.example": {{9, 9, 9, 6}},
+		"unproven.example":  {{9, 9, 9, 5}},
+	} {
+		ips, err := client.LookupIPv4(domain)
+		if err != nil {
+			t.Fatal(domain, ": ", err)
+		}
+		if r := cmp.Diff(ips, expected); r != "" {
+			t.Error(domain, ": ", r)
+		}
+	}
+
+	if ips, err := client.LookupIPv4("nodata.example"); err == nil {
+		t.Error("expected no data for proven nonexistence, got ", ips)
+	}
+	// No such name must be proven too, and other errors are not trusted, so
+	// the error of the other server is returned instead.
+	for domain, expected := range map[string]feature_dns.RCodeError{
+		"gone.example":       dns.RcodeNameError,
+		"gone3.example":      dns.RcodeNameError,
+		"forged.example":     dns.RcodeServerFailure,
+		"nowildcard.example": dns.RcodeServerFailure,
+		"servfail.example":   dns.RcodeNameError,
+	} {
+		if _, err := client.LookupIPv4(domain); err != expected {
+			t.Error(domain, ": expected ", expected, ", got ", err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/app/router"
@@ -70,6 +71,8 @@ func init() {
 				SkipFallback: v.SkipFallback,
 				Geoip:        v.Geoip,
 				Concurrency:  v.Concurrency,
+				Dnssec:       v.Dnssec,
+				TimeoutMs:    v.TimeoutMs,
 			}
 			for _, prioritizedDomain := range v.PrioritizedDomain {
 				nameserver.PrioritizedDomain = append(nameserver.PrioritizedDomain, &NameServer_PriorityDomain{
@@ -80,14 +83,28 @@ func init() {
 			nameservers = append(nameservers, nameserver)
 		}
 
+		var domainOverrides []*DomainOverride
+
+		for _, v := range simplifiedConfig.DomainOverride {
+			domainOverrides = append(domainOverrides, &DomainOverride{
+				Domain:        v.Domain,
+				ClientIp:      net.ParseIP(v.ClientIp),
+				QueryStrategy: v.QueryStrategy,
+			})
+		}
+
 		fullConfig := &Config{
-			NameServer:      nameservers,
-			ClientIp:        net.ParseIP(simplifiedConfig.ClientIp),
-			StaticHosts:     simplifiedConfig.StaticHosts,
-			Tag:             simplifiedConfig.Tag,
-			DisableCache:    simplifiedConfig.DisableCache,
-			QueryStrategy:   simplifiedConfig.QueryStrategy,
-			DisableFallback: simplifiedConfig.DisableFallback,
+			NameServer:          nameservers,
+			ClientIp:            net.ParseIP(simplifiedConfig.ClientIp),
+			StaticHosts:         simplifiedConfig.StaticHosts,
+			Tag:                 simplifiedConfig.Tag,
+			DisableCache:        simplifiedConfig.DisableCache,
+			QueryStrategy:       simplifiedConfig.QueryStrategy,
+			DisableFallback:     simplifiedConfig.DisableFallback,
+			DnssecTrustAnchor:   simplifiedConfig.DnssecTrustAnchor,
+			DomainOverride:      domainOverrides,
+			ServerStrategy:      simplifiedConfig.ServerStrategy,
+			ParallelServerCount: simplifiedConfig.ParallelServerCount,
 		}
 		return common.CreateObject(ctx, fullConfig)
 	}))
@@ -120,6 +137,11 @@ func New(ctx context.Context, config *Config) (*Client, error) {
 		return nil, newError("failed to create hosts").Base(err)
 	}
 
+	trustAnchors, err := parseTrustAnchors(config.DnssecTrustAnchor)
+	if err != nil {
+		return nil, newError("failed to create DNSSEC trust anchors").Base(err)
+	}
+
 	var servers []*Server
 	domainRuleCount := 0
 	for _, ns := range config.NameServer {
@@ -151,6 +173,9 @@ func New(ctx context.Context, config *Config) (*Client, error) {
 		disableFallback:        config.DisableFallback,
 		disableFallbackIfMatch: config.DisableFallbackIfMatch,
 		disableExpire:          config.DisableExpire,
+
+		serverStrategy:  config.ServerStrategy,
+		parallelServers: int(config.ParallelServerCount),
 	}
 
 	for _, ns := range config.NameServer {
@@ -173,6 +198,12 @@ func New(ctx context.Context, config *Config) (*Client, error) {
 		if err != nil {
 			return nil, newError("failed to create client").Base(err)
 		}
+		if ns.Dnssec {
+			if server.transport.Type() == dns.TransportTypeLookup {
+				return nil, newError("DNSSEC validation is not supported by ", server.name)
+			}
+			server.validator = newDNSSECValidator(client, server, trustAnchors)
+		}
 		servers = append(servers, server)
 	}
 
@@ -187,6 +218,33 @@ func New(ctx context.Context, config *Config) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	overrideMatcher := strmatcher.NewMixedIndexMatcher()
+	overrides := []*domainOverride{nil}
+	for _, o := range config.DomainOverride {
+		override := &domainOverride{
+			queryStrategy: dns.QueryStrategy(o.QueryStrategy),
+		}
+		switch len(o.ClientIp) {
+		case 0, net.IPv4len, net.IPv6len:
+			override.clientIP = net.IP(o.ClientIp)
+		default:
+			return nil, newError("unexpected client IP length ", len(o.ClientIp))
+		}
+		for _, domain := range o.Domain {
+			matcher, err := ToStrMatcher(domain.Type, domain.Domain)
+			if err != nil {
+				return nil, newError("failed to create domain override").Base(err)
+			}
+			overrideMatcher.Add(matcher)
+			overrides = append(overrides, override)
+		}
+	}
+	if err := overrideMatcher.Build(); err != nil {
+		return nil, err
+	}
+	client.overrideMatcher = overrideMatcher
+	client.overrides = overrides
 	client.servers = servers
 	return client, nil
 }
@@ -256,6 +314,7 @@ func newServer(
 		domains:      rules,
 		expectIPs:    matchers,
 		concurrency:  ns.Concurrency,
+		timeout:      time.Duration(ns.TimeoutMs) * time.Millisecond,
 	}
 
 	var name string
@@ -525,6 +584,10 @@ func (c *Client) sortServers(domain string) []*Server {
 		newError("domain ", domain, " will use the first DNS: ", clientNames).AtDebug().WriteToLog()
 	}
 
+	if c.serverStrategy != ServerStrategy_SEQUENTIAL && c.parallelServers > 0 && len(clients) > c.parallelServers {
+		clients = clients[:c.parallelServers]
+	}
+
 	return clients
 }
 
@@ -613,9 +676,12 @@ func Fqdn(domain string) string {
 }
 
 func genEDNS0Options(clientIP net.IP) *dnsmessage.Resource {
-	if len(clientIP) == 0 {
+	if len(clientIP) == 0 || clientIP.IsUnspecified() {
 		return nil
 	}
+	if ip4 := clientIP.To4(); ip4 != nil {
+		clientIP = ip4
+	}
 
 	var netmask int
 	var family uint16
@@ -646,7 +712,7 @@ func genEDNS0Options(clientIP net.IP) *dnsmessage.Resource {
 	const EDNS0SUBNET = 0x08
 
 	opt := new(dnsmessage.Resource)
-	common.Must(opt.Header.SetEDNS0(1350, 0xfe00, true))
+	common.Must(opt.Header.SetEDNS0(1350, dnsmessage.RCodeSuccess, false))
 
 	opt.Body = &dnsmessage.OPTResource{
 		Options: []dnsmessage.Option{
@@ -14,6 +14,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/features"
 	"github.com/v2fly/v2ray-core/v5/features/extension"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
 	"google.golang.org/grpc"
 )
 
@@ -22,6 +23,7 @@ type service struct {
 	v *core.Instance
 
 	observatory extension.Observatory
+	stats       stats.Manager
 }
 
 func (s *service) GetOutboundStatus(ctx context.Context, request *GetOutboundStatusRequest) (*GetOutboundStatusResponse, error) {
@@ -49,6 +51,70 @@ func (s *service) GetOutboundStatus(ctx context.Context, request *GetOutboundSta
 	}, nil
 }
 
+// outboundProber is an observatory that probes outbounds on demand.
+type outboundProber interface {
+	ProbeOutbounds(ctx context.Context, outbounds []string) (*observatory.ObservationResult, error)
+}
+
+func (s *service) ProbeOutbounds(ctx context.Context, request *ProbeOutboundsRequest) (*ProbeOutboundsResponse, error) {
+	if len(request.OutboundTag) == 0 {
+		return nil, newError("no outbound to probe")
+	}
+	target := s.observatory
+	if request.Tag != "" {
+		fet, err := s.observatory.(features.TaggedFeatures).GetFeaturesByTag(request.Tag)
+		if err != nil {
+			return nil, newError("cannot get tagged observatory").Base(err)
+		}
+		target = fet.(extension.Observatory)
+	}
+	prober, ok := target.(outboundProber)
+	if !ok {
+		return nil, newError("observatory does not support probing on demand")
+	}
+	result, err := prober.ProbeOutbounds(ctx, request.OutboundTag)
+	if err != nil {
+		return nil, newError("cannot probe outbounds").Base(err)
+	}
+	return &ProbeOutboundsResponse{Status: result}, nil
+}
+
+func (s *service) SubscribeOutboundStatus(request *SubscribeOutboundStatusRequest, stream ObservatoryService_SubscribeOutboundStatusServer) error {
+	channel := s.stats.GetChannel(observatory.StatusChannelName)
+	if channel == nil {
+		return newError("outbound status events not enabled")
+	}
+	tags := make(map[string]bool, len(request.OutboundTag))
+	for _, tag := range request.OutboundTag {
+		tags[tag] = true
+	}
+	subscriber, err := stats.SubscribeRunnableChannel(channel)
+	if err != nil {
+		return err
+	}
+	defer stats.UnsubscribeClosableChannel(channel, subscriber)
+	for {
+		select {
+		case value, ok := <-subscriber:
+			if !ok {
+				return newError("upstream closed the subscriber channel")
+			}
+			event, ok := value.(*observatory.OutboundStatusEvent)
+			if !ok {
+				return newError("upstream sent malformed event")
+			}
+			if len(tags) > 0 && !tags[event.Status.GetOutboundTag()] {
+				continue
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
+
 func (s *service) Register(server *grpc.Server) {
 	RegisterObservatoryServiceServer(server, s)
 }
@@ -57,8 +123,9 @@ func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, cfg interface{}) (interface{}, error) {
 		s := core.MustFromContext(ctx)
 		sv := &service{v: s}
-		err := s.RequireFeatures(func(Observatory extension.Observatory) {
+		err := s.RequireFeatures(func(Observatory extension.Observatory, sm stats.Manager) {
 			sv.observatory = Observatory
+			sv.stats = sm
 		})
 		if err != nil {
 			return nil, err
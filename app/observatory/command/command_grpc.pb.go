@@ -8,6 +8,7 @@ package command
 
 import (
 	context "context"
+	observatory "github.com/v2fly/v2ray-core/v5/app/observatory"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -23,6 +24,8 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ObservatoryServiceClient interface {
 	GetOutboundStatus(ctx context.Context, in *GetOutboundStatusRequest, opts ...grpc.CallOption) (*GetOutboundStatusResponse, error)
+	SubscribeOutboundStatus(ctx context.Context, in *SubscribeOutboundStatusRequest, opts ...grpc.CallOption) (ObservatoryService_SubscribeOutboundStatusClient, error)
+	ProbeOutbounds(ctx context.Context, in *ProbeOutboundsRequest, opts ...grpc.CallOption) (*ProbeOutboundsResponse, error)
 }
 
 type observatoryServiceClient struct {
@@ -42,11 +45,54 @@ func (c *observatoryServiceClient) GetOutboundStatus(ctx context.Context, in *Ge
 	return out, nil
 }
 
+func (c *observatoryServiceClient) SubscribeOutboundStatus(ctx context.Context, in *SubscribeOutboundStatusRequest, opts ...grpc.CallOption) (ObservatoryService_SubscribeOutboundStatusClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ObservatoryService_ServiceDesc.Streams[0], "/v2ray.core.app.observatory.command.ObservatoryService/SubscribeOutboundStatus", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &observatoryServiceSubscribeOutboundStatusClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ObservatoryService_SubscribeOutboundStatusClient interface {
+	Recv() (*observatory.OutboundStatusEvent, error)
+	grpc.ClientStream
+}
+
+type observatoryServiceSubscribeOutboundStatusClient struct {
+	grpc.ClientStream
+}
+
+func (x *observatoryServiceSubscribeOutboundStatusClient) Recv() (*observatory.OutboundStatusEvent, error) {
+	m := new(observatory.OutboundStatusEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *observatoryServiceClient) ProbeOutbounds(ctx context.Context, in *ProbeOutboundsRequest, opts ...grpc.CallOption) (*ProbeOutboundsResponse, error) {
+	out := new(ProbeOutboundsResponse)
+	err := c.cc.Invoke(ctx, "/v2ray.core.app.observatory.command.ObservatoryService/ProbeOutbounds", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ObservatoryServiceServer is the server API for ObservatoryService service.
 // All implementations must embed UnimplementedObservatoryServiceServer
 // for forward compatibility
 type ObservatoryServiceServer interface {
 	GetOutboundStatus(context.Context, *GetOutboundStatusRequest) (*GetOutboundStatusResponse, error)
+	SubscribeOutboundStatus(*SubscribeOutboundStatusRequest, ObservatoryService_SubscribeOutboundStatusServer) error
+	ProbeOutbounds(context.Context, *ProbeOutboundsRequest) (*ProbeOutboundsResponse, error)
 	mustEmbedUnimplementedObservatoryServiceServer()
 }
 
@@ -57,6 +103,12 @@ type UnimplementedObservatoryServiceServer struct {
 func (UnimplementedObservatoryServiceServer) GetOutboundStatus(context.Context, *GetOutboundStatusRequest) (*GetOutboundStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetOutboundStatus not implemented")
 }
+func (UnimplementedObservatoryServiceServer) SubscribeOutboundStatus(*SubscribeOutboundStatusRequest, ObservatoryService_SubscribeOutboundStatusServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeOutboundStatus not implemented")
+}
+func (UnimplementedObservatoryServiceServer) ProbeOutbounds(context.Context, *ProbeOutboundsRequest) (*ProbeOutboundsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProbeOutbounds not implemented")
+}
 func (UnimplementedObservatoryServiceServer) mustEmbedUnimplementedObservatoryServiceServer() {}
 
 // UnsafeObservatoryServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -88,6 +140,45 @@ func _ObservatoryService_GetOutboundStatus_Handler(srv interface{}, ctx context.
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ObservatoryService_SubscribeOutboundStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeOutboundStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ObservatoryServiceServer).SubscribeOutboundStatus(m, &observatoryServiceSubscribeOutboundStatusServer{stream})
+}
+
+type ObservatoryService_SubscribeOutboundStatusServer interface {
+	Send(*observatory.OutboundStatusEvent) error
+	grpc.ServerStream
+}
+
+type observatoryServiceSubscribeOutboundStatusServer struct {
+	grpc.ServerStream
+}
+
+func (x *observatoryServiceSubscribeOutboundStatusServer) Send(m *observatory.OutboundStatusEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ObservatoryService_ProbeOutbounds_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProbeOutboundsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObservatoryServiceServer).ProbeOutbounds(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/v2ray.core.app.observatory.command.ObservatoryService/ProbeOutbounds",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObservatoryServiceServer).ProbeOutbounds(ctx, req.(*ProbeOutboundsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ObservatoryService_ServiceDesc is the grpc.ServiceDesc for ObservatoryService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -99,7 +190,17 @@ var ObservatoryService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetOutboundStatus",
 			Handler:    _ObservatoryService_GetOutboundStatus_Handler,
 		},
+		{
+			MethodName: "ProbeOutbounds",
+			Handler:    _ObservatoryService_ProbeOutbounds_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeOutboundStatus",
+			Handler:       _ObservatoryService_SubscribeOutboundStatus_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "app/observatory/command/command.proto",
 }
@@ -0,0 +1,101 @@
+//go:build !confonly
+// +build !confonly
+
+package observatory
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/features/stats"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// StatusChannelName is the name of the stats channel that OutboundStatusEvent
+// are published on.
+const StatusChannelName = "observatory>>>status"
+
+const (
+	eventPublishTimeout = 4 * time.Second
+	webhookTimeout      = 5 * time.Second
+)
+
+// statusEvent returns the event for an outbound whose status changed from
+// previous to current, or nil if the change is not worth an event. previous is
+// nil if the outbound was never probed.
+func statusEvent(previous *OutboundStatus, current *OutboundStatus, latencyChangeThreshold time.Duration) *OutboundStatusEvent {
+	switch {
+	case previous == nil:
+		if current.Alive {
+			return nil
+		}
+		return &OutboundStatusEvent{Type: OutboundStatusEvent_Dead, Status: current}
+	case previous.Alive && !current.Alive:
+		return &OutboundStatusEvent{Type: OutboundStatusEvent_Dead, Status: current, PreviousDelay: previous.Delay}
+	case !previous.Alive && current.Alive:
+		return &OutboundStatusEvent{Type: OutboundStatusEvent_Alive, Status: current}
+	case current.Alive && latencyChangeThreshold > 0:
+		change := current.Delay - previous.Delay
+		if change < 0 {
+			change = -change
+		}
+		if change < latencyChangeThreshold.Milliseconds() {
+			return nil
+		}
+		return &OutboundStatusEvent{Type: OutboundStatusEvent_LatencyChanged, Status: current, PreviousDelay: previous.Delay}
+	}
+	return nil
+}
+
+func (o *Observer) publishEvent(event *OutboundStatusEvent) {
+	newError("outbound ", event.Status.OutboundTag, " status changed: ", event.Type).AtInfo().WriteToLog()
+	if o.statusChannel != nil {
+		// The event is dropped if no subscriber takes it before the timeout.
+		ctx, cancel := context.WithTimeout(o.ctx, eventPublishTimeout)
+		o.statusChannel.Publish(ctx, event)
+		time.AfterFunc(eventPublishTimeout, cancel)
+	}
+	if o.config.WebhookUrl != "" {
+		go o.postWebhook(event)
+	}
+}
+
+// postWebhook posts event to the webhook as JSON. The request is sent
+// directly, not through any outbound.
+func (o *Observer) postWebhook(event *OutboundStatusEvent) {
+	body, err := protojson.MarshalOptions{EmitUnpopulated: true}.Marshal(event)
+	if err != nil {
+		newError("failed to encode status event").Base(err).AtWarning().WriteToLog()
+		return
+	}
+	ctx, cancel := context.WithTimeout(o.ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.config.WebhookUrl, bytes.NewReader(body))
+	if err != nil {
+		newError("invalid webhook URL").Base(err).AtWarning().WriteToLog()
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		newError("failed to post status event to webhook").Base(err).AtWarning().WriteToLog()
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		newError("webhook responded ", resp.Status).AtWarning().WriteToLog()
+	}
+}
+
+// registerStatusChannel gets the channel to publish status events on. Events
+// are not published if there is no stats manager.
+func registerStatusChannel(sm stats.Manager) stats.Channel {
+	c, err := stats.GetOrRegisterChannel(sm, StatusChannelName)
+	if err != nil {
+		newError("status events are not published").Base(err).AtDebug().WriteToLog()
+		return nil
+	}
+	return c
+}
@@ -0,0 +1,88 @@
+package observatory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/stats"
+	"github.com/v2fly/v2ray-core/v5/common"
+)
+
+func TestStatusEvents(t *testing.T) {
+	channel := stats.NewChannel(&stats.ChannelConfig{BufferSize: 16})
+	common.Must(channel.Start())
+	defer channel.Close()
+	subscriber, err := channel.Subscribe()
+	common.Must(err)
+
+	o := &Observer{
+		config:        &Config{LatencyChangeThreshold: int64(100 * time.Millisecond)},
+		ctx:           context.Background(),
+		statusChannel: channel,
+	}
+	results := []ProbeResult{
+		{Alive: true, Delay: 100},
+		{Alive: true, Delay: 150},
+		{Alive: true, Delay: 300},
+		{Alive: false, LastErrorReason: "timeout"},
+		{Alive: false, LastErrorReason: "timeout"},
+		{Alive: true, Delay: 120},
+	}
+	for i := range results {
+		o.updateStatusForResult("proxy", &results[i])
+	}
+
+	expected := []struct {
+		eventType     OutboundStatusEvent_Type
+		previousDelay int64
+	}{
+		{OutboundStatusEvent_LatencyChanged, 150},
+		{OutboundStatusEvent_Dead, 300},
+		{OutboundStatusEvent_Alive, 0},
+	}
+	for _, e := range expected {
+		select {
+		case value := <-subscriber:
+			event := value.(*OutboundStatusEvent)
+			if event.Type != e.eventType || event.PreviousDelay != e.previousDelay || event.Status.OutboundTag != "proxy" {
+				t.Error("expected ", e.eventType, " from ", e.previousDelay, ", but got ", event)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected ", e.eventType, " event")
+		}
+	}
+	select {
+	case value := <-subscriber:
+		t.Error("unexpected event ", value)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestStatusEventWebhook(t *testing.T) {
+	events := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		common.Must(json.NewDecoder(r.Body).Decode(&event))
+		events <- event
+	}))
+	defer server.Close()
+
+	o := &Observer{
+		config: &Config{WebhookUrl: server.URL},
+		ctx:    context.Background(),
+	}
+	o.updateStatusForResult("proxy", &ProbeResult{Alive: false, LastErrorReason: "timeout"})
+
+	select {
+	case event := <-events:
+		if event["type"] != "Dead" || event["status"].(map[string]interface{})["outboundTag"] != "proxy" {
+			t.Error("unexpected webhook body ", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not called")
+	}
+}
@@ -5,9 +5,6 @@ package observatory
 
 import (
 	"context"
-	"net"
-	"net/http"
-	"net/url"
 	"sort"
 	"sync"
 	"time"
@@ -15,13 +12,12 @@ import (
 	"github.com/golang/protobuf/proto"
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/common"
-	v2net "github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/common/signal/done"
 	"github.com/v2fly/v2ray-core/v5/common/task"
 	"github.com/v2fly/v2ray-core/v5/features/extension"
 	"github.com/v2fly/v2ray-core/v5/features/outbound"
-	"github.com/v2fly/v2ray-core/v5/transport/internet/tagged"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
 )
 
 type Observer struct {
@@ -31,10 +27,14 @@ type Observer struct {
 	statusLock sync.Mutex
 	status     []*OutboundStatus
 
+	persistLock sync.Mutex
+
 	finished *done.Instance
 
 	ohm outbound.Manager
 
+	statusChannel stats.Channel
+
 	StatusUpdate func(result *OutboundStatus)
 }
 
@@ -48,6 +48,7 @@ func (o *Observer) Type() interface{} {
 
 func (o *Observer) Start() error {
 	if o.config != nil && len(o.config.SubjectSelector) != 0 {
+		o.loadStatus()
 		o.finished = done.New()
 		go o.background()
 	}
@@ -62,6 +63,7 @@ func (o *Observer) Close() error {
 }
 
 func (o *Observer) background() {
+	nextProbe := make(map[string]time.Time)
 	for !o.finished.Done() {
 		hs, ok := o.ohm.(outbound.HandlerSelector)
 		if !ok {
@@ -73,27 +75,33 @@ func (o *Observer) background() {
 
 		o.updateStatus(outbounds)
 
-		sleepTime := time.Second * 10
-		if o.config.ProbeInterval != 0 {
-			sleepTime = time.Duration(o.config.ProbeInterval)
-		}
-
 		if !o.config.EnableConcurrency {
 			sort.Strings(outbounds)
 			for _, v := range outbounds {
 				result := o.probe(v)
 				o.updateStatusForResult(v, &result)
+				o.saveStatus()
 				if o.finished.Done() {
 					return
 				}
-				time.Sleep(sleepTime)
+				time.Sleep(o.probeSettings(v).interval)
 			}
 			continue
 		}
 
-		ch := make(chan struct{}, len(outbounds))
-
+		// Outbounds are probed when their own probe interval has passed.
+		now := time.Now()
+		var due []string
 		for _, v := range outbounds {
+			if !nextProbe[v].After(now) {
+				due = append(due, v)
+				nextProbe[v] = now.Add(o.probeSettings(v).interval)
+			}
+		}
+
+		ch := make(chan struct{}, len(due))
+
+		for _, v := range due {
 			go func(v string) {
 				result := o.probe(v)
 				o.updateStatusForResult(v, &result)
@@ -101,13 +109,26 @@ func (o *Observer) background() {
 			}(v)
 		}
 
-		for range outbounds {
+		for range due {
 			select {
 			case <-ch:
 			case <-o.finished.Wait():
 				return
 			}
 		}
+		if len(due) > 0 {
+			o.saveStatus()
+		}
+
+		sleepTime := defaultProbeInterval
+		if o.config.ProbeInterval > 0 {
+			sleepTime = time.Duration(o.config.ProbeInterval)
+		}
+		for _, v := range outbounds {
+			if d := time.Until(nextProbe[v]); d < sleepTime {
+				sleepTime = d
+			}
+		}
 		time.Sleep(sleepTime)
 	}
 }
@@ -120,80 +141,52 @@ func (o *Observer) updateStatus(outbounds []string) {
 }
 
 func (o *Observer) probe(outbound string) ProbeResult {
+	settings := o.probeSettings(outbound)
+	ctx, cancel := context.WithTimeout(o.ctx, settings.timeout)
+	defer cancel()
+
 	errorCollectorForRequest := newErrorCollector()
+	trackedCtx := session.TrackedConnectionError(ctx, errorCollectorForRequest)
 
-	httpTransport := http.Transport{
-		Proxy: func(*http.Request) (*url.URL, error) {
-			return nil, nil
-		},
-		DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
-			var connection net.Conn
-			taskErr := task.Run(ctx, func() error {
-				// MUST use V2Fly's built in context system
-				dest, err := v2net.ParseDestination(network + ":" + addr)
-				if err != nil {
-					return newError("cannot understand address").Base(err)
-				}
-				trackedCtx := session.TrackedConnectionError(o.ctx, errorCollectorForRequest)
-				conn, err := tagged.Dialer(trackedCtx, dest, outbound)
-				if err != nil {
-					return newError("cannot dial remote address ", dest).Base(err)
-				}
-				connection = conn
-				return nil
-			})
-			if taskErr != nil {
-				return nil, newError("cannot finish connection").Base(taskErr)
-			}
-			return connection, nil
-		},
-		TLSHandshakeTimeout: time.Second * 5,
-	}
-	httpClient := &http.Client{
-		Transport: &httpTransport,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-		Jar:     nil,
-		Timeout: time.Second * 5,
-	}
-	var GETTime time.Duration
-	err := task.Run(o.ctx, func() error {
-		startTime := time.Now()
-		probeURL := "https://api.v2fly.org/checkConnection.svgz"
-		if o.config.ProbeUrl != "" {
-			probeURL = o.config.ProbeUrl
-		}
-		response, err := httpClient.Get(probeURL)
-		if err != nil {
-			return newError("outbound failed to relay connection").Base(err)
+	var delay time.Duration
+	err := task.Run(ctx, func() error {
+		var err error
+		switch o.config.ProbeKind {
+		case ProbeKind_TCP:
+			delay, err = o.probeTCP(trackedCtx, outbound)
+		case ProbeKind_TLS:
+			delay, err = o.probeTLS(trackedCtx, outbound)
+		case ProbeKind_DNS:
+			delay, err = o.probeDNS(trackedCtx, outbound)
+		case ProbeKind_ICMP:
+			delay, err = o.probeICMP(trackedCtx, outbound)
+		default:
+			delay, err = o.probeHTTP(trackedCtx, outbound, settings.url)
 		}
-		if response.Body != nil {
-			response.Body.Close()
-		}
-		endTime := time.Now()
-		GETTime = endTime.Sub(startTime)
-		return nil
+		return err
 	})
 	if err != nil {
 		fullerr := newError("underlying connection failed").Base(errorCollectorForRequest.UnderlyingError())
 		fullerr = newError("with outbound handler report").Base(fullerr)
-		fullerr = newError("GET request failed:", err).Base(fullerr)
+		fullerr = newError(o.config.ProbeKind, " probe failed:", err).Base(fullerr)
 		fullerr = newError("the outbound ", outbound, " is dead:").Base(fullerr)
 		fullerr = fullerr.AtInfo()
 		fullerr.WriteToLog()
 		return ProbeResult{Alive: false, LastErrorReason: fullerr.Error()}
 	}
-	newError("the outbound ", outbound, " is alive:", GETTime.Seconds()).AtInfo().WriteToLog()
-	return ProbeResult{Alive: true, Delay: GETTime.Milliseconds()}
+	newError("the outbound ", outbound, " is alive:", delay.Seconds()).AtInfo().WriteToLog()
+	return ProbeResult{Alive: true, Delay: delay.Milliseconds()}
 }
 
-func (o *Observer) updateStatusForResult(outbound string, result *ProbeResult) {
+// updateStatusForResult updates the status of outbound with result, and
+// returns a copy of the new status.
+func (o *Observer) updateStatusForResult(outbound string, result *ProbeResult) *OutboundStatus {
 	o.statusLock.Lock()
 	defer o.statusLock.Unlock()
-	var status *OutboundStatus
+	var status, previous *OutboundStatus
 	if location := o.findStatusLocationLockHolderOnly(outbound); location != -1 {
 		status = o.status[location]
+		previous = &OutboundStatus{Alive: status.Alive, Delay: status.Delay}
 	} else {
 		status = &OutboundStatus{}
 		o.status = append(o.status, status)
@@ -201,6 +194,7 @@ func (o *Observer) updateStatusForResult(outbound string, result *ProbeResult) {
 
 	status.LastTryTime = time.Now().Unix()
 	status.OutboundTag = outbound
+	status.ProbeKind = o.config.ProbeKind
 	status.Alive = result.Alive
 	if result.Alive {
 		status.Delay = result.Delay
@@ -214,6 +208,34 @@ func (o *Observer) updateStatusForResult(outbound string, result *ProbeResult) {
 	if o.StatusUpdate != nil {
 		o.StatusUpdate(status)
 	}
+	current := proto.Clone(status).(*OutboundStatus)
+	if event := statusEvent(previous, current, time.Duration(o.config.LatencyChangeThreshold)); event != nil {
+		o.publishEvent(event)
+	}
+	return current
+}
+
+// ProbeOutbounds probes outbounds immediately, and returns their new status.
+func (o *Observer) ProbeOutbounds(ctx context.Context, outbounds []string) (*ObservationResult, error) {
+	for _, v := range outbounds {
+		if o.ohm.GetHandler(v) == nil {
+			return nil, newError("outbound ", v, " not found")
+		}
+	}
+
+	results := make([]*OutboundStatus, len(outbounds))
+	var wg sync.WaitGroup
+	for i, v := range outbounds {
+		wg.Add(1)
+		go func(i int, v string) {
+			defer wg.Done()
+			result := o.probe(v)
+			results[i] = o.updateStatusForResult(v, &result)
+		}(i, v)
+	}
+	wg.Wait()
+	o.saveStatus()
+	return &ObservationResult{Status: results}, nil
 }
 
 func (o *Observer) findStatusLocationLockHolderOnly(outbound string) int {
@@ -227,16 +249,19 @@ func (o *Observer) findStatusLocationLockHolderOnly(outbound string) int {
 
 func New(ctx context.Context, config *Config) (*Observer, error) {
 	var outboundManager outbound.Manager
-	err := core.RequireFeatures(ctx, func(om outbound.Manager) {
+	var statsManager stats.Manager
+	err := core.RequireFeatures(ctx, func(om outbound.Manager, sm stats.Manager) {
 		outboundManager = om
+		statsManager = sm
 	})
 	if err != nil {
 		return nil, newError("Cannot get depended features").Base(err)
 	}
 	return &Observer{
-		config: config,
-		ctx:    ctx,
-		ohm:    outboundManager,
+		config:        config,
+		ctx:           ctx,
+		ohm:           outboundManager,
+		statusChannel: registerStatusChannel(statsManager),
 	}, nil
 }
 
@@ -1,5 +1,12 @@
 package observatory
 
+import (
+	"os"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/v2fly/v2ray-core/v5/common/platform/filesystem"
+)
+
 func (o *Observer) UpdateStatus(result *OutboundStatus) {
 	o.statusLock.Lock()
 	defer o.statusLock.Unlock()
@@ -10,3 +17,53 @@ func (o *Observer) UpdateStatus(result *OutboundStatus) {
 		o.status = append(o.status, result)
 	}
 }
+
+// loadStatus restores the status saved by saveStatus, so that balancers have
+// the last known status of outbounds before they are probed again.
+func (o *Observer) loadStatus() {
+	if o.config.PersistPath == "" {
+		return
+	}
+	content, err := filesystem.ReadFile(o.config.PersistPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			newError("failed to read saved outbound status").Base(err).AtWarning().WriteToLog()
+		}
+		return
+	}
+	saved := new(ObservationResult)
+	if err := proto.Unmarshal(content, saved); err != nil {
+		newError("failed to decode saved outbound status").Base(err).AtWarning().WriteToLog()
+		return
+	}
+	for _, status := range saved.Status {
+		o.UpdateStatus(status)
+	}
+	newError("restored status of ", len(saved.Status), " outbounds").AtInfo().WriteToLog()
+}
+
+// saveStatus writes the status of all outbounds to the persist path. The file
+// is replaced at once, so it is never left half written.
+func (o *Observer) saveStatus() {
+	if o.config.PersistPath == "" {
+		return
+	}
+	o.statusLock.Lock()
+	content, err := proto.Marshal(&ObservationResult{Status: o.status})
+	o.statusLock.Unlock()
+	if err != nil {
+		newError("failed to encode outbound status").Base(err).AtWarning().WriteToLog()
+		return
+	}
+
+	o.persistLock.Lock()
+	defer o.persistLock.Unlock()
+	temp := o.config.PersistPath + ".tmp"
+	if err := filesystem.WriteFile(temp, content); err != nil {
+		newError("failed to save outbound status").Base(err).AtWarning().WriteToLog()
+		return
+	}
+	if err := os.Rename(temp, o.config.PersistPath); err != nil {
+		newError("failed to save outbound status").Base(err).AtWarning().WriteToLog()
+	}
+}
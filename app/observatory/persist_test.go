@@ -0,0 +1,32 @@
+package observatory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestPersistStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "observatory")
+	config := &Config{PersistPath: path}
+
+	o := &Observer{config: config}
+	o.loadStatus()
+	if len(o.status) != 0 {
+		t.Fatal("expected no status without saved file, but got ", o.status)
+	}
+	o.UpdateStatus(&OutboundStatus{OutboundTag: "a", Alive: true, Delay: 100, LastTryTime: 1})
+	o.UpdateStatus(&OutboundStatus{OutboundTag: "b", Alive: false, Delay: 99999999, LastTryTime: 1})
+	o.saveStatus()
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Error("expected temporary file removed")
+	}
+
+	restored := &Observer{config: config}
+	restored.loadStatus()
+	if !proto.Equal(&ObservationResult{Status: restored.status}, &ObservationResult{Status: o.status}) {
+		t.Error("expected ", o.status, ", but got ", restored.status)
+	}
+}
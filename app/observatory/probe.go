@@ -0,0 +1,316 @@
+//go:build !confonly
+// +build !confonly
+
+package observatory
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common/dice"
+	v2net "github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/task"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tagged"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	defaultProbeURL      = "https://api.v2fly.org/checkConnection.svgz"
+	defaultProbeInterval = time.Second * 10
+	defaultProbeTimeout  = time.Second * 5
+)
+
+// probeSettings are the settings to probe an outbound with.
+type probeSettings struct {
+	url      string
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// probeSettings returns the settings of the first override whose selector
+// matches outbound, falling back to the global settings for those it does
+// not set.
+func (o *Observer) probeSettings(outbound string) probeSettings {
+	settings := probeSettings{
+		url:      o.config.ProbeUrl,
+		interval: time.Duration(o.config.ProbeInterval),
+		timeout:  time.Duration(o.config.ProbeTimeout),
+	}
+	for _, override := range o.config.ProbeOverride {
+		if !strings.HasPrefix(outbound, override.Selector) {
+			continue
+		}
+		if override.ProbeUrl != "" {
+			settings.url = override.ProbeUrl
+		}
+		if override.ProbeInterval > 0 {
+			settings.interval = time.Duration(override.ProbeInterval)
+		}
+		if override.ProbeTimeout > 0 {
+			settings.timeout = time.Duration(override.ProbeTimeout)
+		}
+		break
+	}
+	if settings.url == "" {
+		settings.url = defaultProbeURL
+	}
+	if settings.interval <= 0 {
+		settings.interval = defaultProbeInterval
+	}
+	if settings.timeout <= 0 {
+		settings.timeout = defaultProbeTimeout
+	}
+	return settings
+}
+
+func (o *Observer) dial(ctx context.Context, outbound string, dest v2net.Destination) (net.Conn, error) {
+	var connection net.Conn
+	taskErr := task.Run(ctx, func() error {
+		// MUST use V2Fly's built in context system
+		conn, err := tagged.Dialer(ctx, dest, outbound)
+		if err != nil {
+			return newError("cannot dial remote address ", dest).Base(err)
+		}
+		connection = conn
+		return nil
+	})
+	if taskErr != nil {
+		return nil, newError("cannot finish connection").Base(taskErr)
+	}
+	return connection, nil
+}
+
+// probeDestination returns the destination of a TCP, TLS or DNS probe.
+func (o *Observer) probeDestination(network v2net.Network, defaultPort v2net.Port) (v2net.Destination, error) {
+	if o.config.ProbeDestination == "" {
+		return v2net.Destination{}, newError("probe destination is not set")
+	}
+	host, port, err := net.SplitHostPort(o.config.ProbeDestination)
+	if err != nil {
+		host, port = o.config.ProbeDestination, defaultPort.String()
+	}
+	if port == "" {
+		return v2net.Destination{}, newError("port of probe destination is not set")
+	}
+	return v2net.ParseDestination(network.SystemString() + ":" + net.JoinHostPort(host, port))
+}
+
+// runWithConn runs f until ctx is done, and closes conn after that, so that f
+// does not block on conn forever.
+func runWithConn(ctx context.Context, conn net.Conn, f func() error) error {
+	defer conn.Close()
+	return task.Run(ctx, f)
+}
+
+func (o *Observer) probeHTTP(ctx context.Context, outbound string, probeURL string) (time.Duration, error) {
+	httpTransport := http.Transport{
+		Proxy: func(*http.Request) (*url.URL, error) {
+			return nil, nil
+		},
+		DialContext: func(_ context.Context, network string, addr string) (net.Conn, error) {
+			dest, err := v2net.ParseDestination(network + ":" + addr)
+			if err != nil {
+				return nil, newError("cannot understand address").Base(err)
+			}
+			return o.dial(ctx, outbound, dest)
+		},
+	}
+	httpClient := &http.Client{
+		Transport: &httpTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Jar: nil,
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		return 0, newError("invalid probe URL").Base(err)
+	}
+	startTime := time.Now()
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, newError("outbound failed to relay connection").Base(err)
+	}
+	if response.Body != nil {
+		response.Body.Close()
+	}
+	return time.Since(startTime), nil
+}
+
+// probeTCP measures the time until the destination sends data. The outbound
+// may accept a connection before it connects to the destination, and close it
+// when that fails, so neither a connection nor its end proves anything.
+func (o *Observer) probeTCP(ctx context.Context, outbound string) (time.Duration, error) {
+	dest, err := o.probeDestination(v2net.Network_TCP, 0)
+	if err != nil {
+		return 0, err
+	}
+	startTime := time.Now()
+	conn, err := o.dial(ctx, outbound, dest)
+	if err != nil {
+		return 0, err
+	}
+	err = runWithConn(ctx, conn, func() error {
+		var b [1]byte
+		if _, err := io.ReadFull(conn, b[:]); err != nil {
+			return newError("outbound failed to relay connection").Base(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(startTime), nil
+}
+
+func (o *Observer) probeTLS(ctx context.Context, outbound string) (time.Duration, error) {
+	dest, err := o.probeDestination(v2net.Network_TCP, 443)
+	if err != nil {
+		return 0, err
+	}
+	serverName := o.config.ProbeServerName
+	if serverName == "" && dest.Address.Family().IsDomain() {
+		serverName = dest.Address.Domain()
+	}
+	startTime := time.Now()
+	conn, err := o.dial(ctx, outbound, dest)
+	if err != nil {
+		return 0, err
+	}
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: serverName == "",
+	})
+	err = runWithConn(ctx, tlsConn, func() error {
+		if err := tlsConn.Handshake(); err != nil {
+			return newError("TLS handshake failed").Base(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(startTime), nil
+}
+
+func (o *Observer) probeDNS(ctx context.Context, outbound string) (time.Duration, error) {
+	dest, err := o.probeDestination(v2net.Network_UDP, 53)
+	if err != nil {
+		return 0, err
+	}
+	domain := o.config.ProbeServerName
+	if domain == "" {
+		domain = "www.google.com"
+	}
+	if !strings.HasSuffix(domain, ".") {
+		domain += "."
+	}
+	name, err := dnsmessage.NewName(domain)
+	if err != nil {
+		return 0, newError("invalid domain to query ", domain).Base(err)
+	}
+	id := dice.RollUint16()
+	query, err := (&dnsmessage.Message{
+		Header: dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{
+			{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+		},
+	}).Pack()
+	if err != nil {
+		return 0, newError("failed to build DNS query").Base(err)
+	}
+
+	startTime := time.Now()
+	conn, err := o.dial(ctx, outbound, dest)
+	if err != nil {
+		return 0, err
+	}
+	err = runWithConn(ctx, conn, func() error {
+		if _, err := conn.Write(query); err != nil {
+			return newError("failed to send DNS query").Base(err)
+		}
+		b := make([]byte, 2048)
+		for {
+			n, err := conn.Read(b)
+			if err != nil {
+				return newError("failed to read DNS response").Base(err)
+			}
+			var parser dnsmessage.Parser
+			header, err := parser.Start(b[:n])
+			if err == nil && header.Response && header.ID == id {
+				return nil
+			}
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(startTime), nil
+}
+
+func (o *Observer) probeICMP(ctx context.Context, outbound string) (time.Duration, error) {
+	ip := v2net.ParseAddress(o.config.ProbeDestination)
+	if !ip.Family().IsIP() {
+		return 0, newError("invalid IP to ping ", o.config.ProbeDestination)
+	}
+	message := icmp.Message{
+		Body: &icmp.Echo{
+			ID:   int(dice.RollUint16()),
+			Seq:  1,
+			Data: []byte("v2ray observatory"),
+		},
+	}
+	var protocol int
+	var replyType icmp.Type
+	if ip.Family().IsIPv4() {
+		message.Type = ipv4.ICMPTypeEcho
+		protocol, replyType = 1, ipv4.ICMPTypeEchoReply
+	} else {
+		message.Type = ipv6.ICMPTypeEchoRequest
+		protocol, replyType = 58, ipv6.ICMPTypeEchoReply
+	}
+	request, err := message.Marshal(nil)
+	if err != nil {
+		return 0, newError("failed to build ICMP echo").Base(err)
+	}
+
+	startTime := time.Now()
+	// Pings are dispatched as UDP packets to port 7.
+	conn, err := o.dial(ctx, outbound, v2net.UDPDestination(ip, 7))
+	if err != nil {
+		return 0, err
+	}
+	err = runWithConn(ctx, conn, func() error {
+		if _, err := conn.Write(request); err != nil {
+			return newError("failed to send ICMP echo").Base(err)
+		}
+		b := make([]byte, 1500)
+		for {
+			n, err := conn.Read(b)
+			if err != nil {
+				return newError("failed to read ICMP reply").Base(err)
+			}
+			reply, err := icmp.ParseMessage(protocol, b[:n])
+			if err != nil {
+				continue
+			}
+			if reply.Type != replyType {
+				return newError("unexpected ICMP reply ", reply.Type)
+			}
+			return nil
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(startTime), nil
+}
@@ -0,0 +1,215 @@
+package observatory
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	v2net "github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/outbound"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tagged"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// dialDirect replaces the tagged dialer, so that probes go to the destination
+// directly instead of through an outbound.
+func dialDirect(t *testing.T, dial func(dest v2net.Destination) (net.Conn, error)) {
+	dialer := tagged.Dialer
+	tagged.Dialer = func(ctx context.Context, dest v2net.Destination, tag string) (net.Conn, error) {
+		return dial(dest)
+	}
+	t.Cleanup(func() {
+		tagged.Dialer = dialer
+	})
+}
+
+func dialNetwork(dest v2net.Destination) (net.Conn, error) {
+	return net.Dial(dest.Network.SystemString(), dest.NetAddr())
+}
+
+func expectAlive(t *testing.T, config *Config) {
+	t.Helper()
+	o := &Observer{config: config, ctx: context.Background()}
+	result := o.probe("test")
+	if !result.Alive {
+		t.Fatal("expected alive, but got ", result.LastErrorReason)
+	}
+	o.updateStatusForResult("test", &result)
+	if kind := o.status[0].ProbeKind; kind != config.ProbeKind {
+		t.Error("expected probe kind ", config.ProbeKind, ", but got ", kind)
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	dialDirect(t, dialNetwork)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("SSH-2.0-test\r\n"))
+			conn.Close()
+		}
+	}()
+
+	expectAlive(t, &Config{ProbeKind: ProbeKind_TCP, ProbeDestination: listener.Addr().String()})
+
+	dialDirect(t, func(dest v2net.Destination) (net.Conn, error) {
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	})
+	o := &Observer{
+		config: &Config{ProbeKind: ProbeKind_TCP, ProbeDestination: listener.Addr().String()},
+		ctx:    context.Background(),
+	}
+	if result := o.probe("test"); result.Alive {
+		t.Error("expected dead for connection closed without data")
+	}
+}
+
+func TestProbeTLS(t *testing.T) {
+	dialDirect(t, dialNetwork)
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	expectAlive(t, &Config{ProbeKind: ProbeKind_TLS, ProbeDestination: server.Listener.Addr().String()})
+
+	o := &Observer{
+		config: &Config{ProbeKind: ProbeKind_TLS, ProbeDestination: server.Listener.Addr().String(), ProbeServerName: "v2fly.org"},
+		ctx:    context.Background(),
+	}
+	if result := o.probe("test"); result.Alive {
+		t.Error("expected dead with a certificate not for the server name")
+	}
+}
+
+func TestProbeDNS(t *testing.T) {
+	dialDirect(t, dialNetwork)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	common.Must(err)
+	defer conn.Close()
+	go func() {
+		b := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			var message dnsmessage.Message
+			if message.Unpack(b[:n]) != nil {
+				continue
+			}
+			message.Header.Response = true
+			response, err := message.Pack()
+			common.Must(err)
+			conn.WriteTo(response, addr)
+		}
+	}()
+
+	expectAlive(t, &Config{ProbeKind: ProbeKind_DNS, ProbeDestination: conn.LocalAddr().String(), ProbeServerName: "v2fly.org"})
+}
+
+func TestProbeICMP(t *testing.T) {
+	dialDirect(t, func(dest v2net.Destination) (net.Conn, error) {
+		if dest.Network != v2net.Network_UDP || dest.Port != 7 || dest.Address.String() != "10.0.0.1" {
+			t.Error("unexpected ping destination ", dest)
+		}
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			b := make([]byte, 1500)
+			n, err := server.Read(b)
+			if err != nil {
+				return
+			}
+			request, err := icmp.ParseMessage(1, b[:n])
+			common.Must(err)
+			reply, err := (&icmp.Message{Type: ipv4.ICMPTypeEchoReply, Body: request.Body}).Marshal(nil)
+			common.Must(err)
+			server.Write(reply)
+		}()
+		return client, nil
+	})
+
+	expectAlive(t, &Config{ProbeKind: ProbeKind_ICMP, ProbeDestination: "10.0.0.1"})
+}
+
+func TestProbeSettings(t *testing.T) {
+	o := &Observer{config: &Config{
+		ProbeUrl:     "https://www.google.com/generate_204",
+		ProbeTimeout: int64(3 * time.Second),
+		ProbeOverride: []*ProbeOverride{
+			{Selector: "asia-cn", ProbeUrl: "https://www.baidu.com", ProbeTimeout: int64(10 * time.Second)},
+			{Selector: "asia-", ProbeInterval: int64(time.Minute)},
+		},
+	}}
+
+	cases := map[string]probeSettings{
+		"eu-1":      {url: "https://www.google.com/generate_204", interval: defaultProbeInterval, timeout: 3 * time.Second},
+		"asia-cn-1": {url: "https://www.baidu.com", interval: defaultProbeInterval, timeout: 10 * time.Second},
+		"asia-jp-1": {url: "https://www.google.com/generate_204", interval: time.Minute, timeout: 3 * time.Second},
+	}
+	for outbound, expected := range cases {
+		if settings := o.probeSettings(outbound); settings != expected {
+			t.Error("expected ", expected, " for ", outbound, ", but got ", settings)
+		}
+	}
+}
+
+type handlerManager struct {
+	outbound.Manager
+	tags []string
+}
+
+func (m *handlerManager) GetHandler(tag string) outbound.Handler {
+	for _, v := range m.tags {
+		if v == tag {
+			return struct{ outbound.Handler }{}
+		}
+	}
+	return nil
+}
+
+func TestProbeOutbounds(t *testing.T) {
+	dialDirect(t, func(dest v2net.Destination) (net.Conn, error) {
+		client, server := net.Pipe()
+		go func() {
+			server.Write([]byte("SSH-2.0-test\r\n"))
+			server.Close()
+		}()
+		return client, nil
+	})
+
+	o := &Observer{
+		config: &Config{ProbeKind: ProbeKind_TCP, ProbeDestination: "127.0.0.1:22"},
+		ctx:    context.Background(),
+		ohm:    &handlerManager{tags: []string{"a", "b"}},
+	}
+	if _, err := o.ProbeOutbounds(context.Background(), []string{"a", "c"}); err == nil {
+		t.Error("expected error for unknown outbound")
+	}
+	result, err := o.ProbeOutbounds(context.Background(), []string{"b", "a"})
+	common.Must(err)
+	if len(result.Status) != 2 || result.Status[0].OutboundTag != "b" || result.Status[1].OutboundTag != "a" {
+		t.Fatal("unexpected result ", result)
+	}
+	for _, status := range result.Status {
+		if !status.Alive {
+			t.Error("expected ", status.OutboundTag, " alive, but got ", status.LastErrorReason)
+		}
+	}
+	if len(o.status) != 2 {
+		t.Error("expected status of 2 outbounds, but got ", o.status)
+	}
+}
@@ -1,6 +1,10 @@
 package proxyman
 
-import "context"
+import (
+	"context"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+)
 
 func (s *AllocationStrategy) GetConcurrencyValue() uint32 {
 	if s == nil || s.Concurrency == nil {
@@ -40,6 +44,28 @@ func (c *ReceiverConfig) GetEffectiveSniffingSettings() *SniffingConfig {
 	return nil
 }
 
+// GetPorts returns the ports in PortRange and PortList, in order and without
+// duplicates.
+func (c *ReceiverConfig) GetPorts() []net.Port {
+	var ports []net.Port
+	seen := make(map[net.Port]bool)
+	add := func(pr *net.PortRange) {
+		for port := pr.From; port <= pr.To && port <= 65535; port++ {
+			if !seen[net.Port(port)] {
+				seen[net.Port(port)] = true
+				ports = append(ports, net.Port(port))
+			}
+		}
+	}
+	if c.PortRange != nil {
+		add(c.PortRange)
+	}
+	for _, pr := range c.PortList.GetRange() {
+		add(pr)
+	}
+	return ports
+}
+
 const useIPKey = "preferUseIP"
 
 func SetPreferUseIP(ctx context.Context, prefer bool) context.Context {
@@ -0,0 +1,103 @@
+package inbound
+
+import (
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/proxyman"
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
+)
+
+// accessControl drops the connections from disallowed sources before they
+// reach the proxy.
+type accessControl struct {
+	allow    []*net.IPNet
+	deny     []*net.IPNet
+	rejected stats.Counter
+}
+
+func toIPNets(cidrs []*routercommon.CIDR) ([]*net.IPNet, error) {
+	ipNets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		ip := net.IP(cidr.Ip)
+		if len(ip) != net.IPv4len && len(ip) != net.IPv6len {
+			return nil, newError("invalid IP length: ", len(ip))
+		}
+		prefix := int(cidr.Prefix)
+		if prefix > len(ip)*8 {
+			return nil, newError("invalid prefix ", prefix, " for ", ip)
+		}
+		ipNets = append(ipNets, &net.IPNet{
+			IP:   ip,
+			Mask: net.CIDRMask(prefix, len(ip)*8),
+		})
+	}
+	return ipNets, nil
+}
+
+func newAccessControl(v *core.Instance, tag string, config *proxyman.AccessControlConfig) (*accessControl, error) {
+	if config == nil || (len(config.Allow) == 0 && len(config.Deny) == 0) {
+		return nil, nil
+	}
+
+	allow, err := toIPNets(config.Allow)
+	if err != nil {
+		return nil, newError("invalid allowed sources").Base(err)
+	}
+	deny, err := toIPNets(config.Deny)
+	if err != nil {
+		return nil, newError("invalid denied sources").Base(err)
+	}
+	ac := &accessControl{
+		allow: allow,
+		deny:  deny,
+	}
+
+	if len(tag) > 0 && v != nil {
+		if statsManager, ok := v.GetFeature(stats.ManagerType()).(stats.Manager); ok {
+			name := "inbound>>>" + tag + ">>>access>>>rejected"
+			c, _ := stats.GetOrRegisterCounter(statsManager, name)
+			if c != nil {
+				ac.rejected = c
+			}
+		}
+	}
+	return ac, nil
+}
+
+func matchIPNets(ipNets []*net.IPNet, ip net.IP) bool {
+	for _, ipNet := range ipNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allows returns whether connections from source are accepted, and counts
+// the rejected ones. Sources without IP, such as unix domain sockets, are
+// always allowed.
+func (ac *accessControl) allows(source net.Address) bool {
+	if ac == nil || source == nil || !source.Family().IsIP() {
+		return true
+	}
+	ip := source.IP()
+	if !matchIPNets(ac.deny, ip) && (len(ac.allow) == 0 || matchIPNets(ac.allow, ip)) {
+		return true
+	}
+	if ac.rejected != nil {
+		ac.rejected.Add(1)
+	}
+	return false
+}
+
+func addressFromAddr(addr net.Addr) net.Address {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return net.IPAddress(addr.IP)
+	case *net.UDPAddr:
+		return net.IPAddress(addr.IP)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package inbound
+
+import (
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/app/proxyman"
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+type testCounter struct {
+	value int64
+}
+
+func (c *testCounter) Value() int64      { return c.value }
+func (c *testCounter) Set(v int64) int64 { c.value = v; return v }
+func (c *testCounter) Add(v int64) int64 { c.value += v; return c.value }
+
+func TestAccessControl(t *testing.T) {
+	ac, err := newAccessControl(nil, "", &proxyman.AccessControlConfig{
+		Allow: []*routercommon.CIDR{
+			{Ip: []byte{10, 0, 0, 0}, Prefix: 8},
+			{Ip: net.LocalHostIPv6.IP(), Prefix: 128},
+		},
+		Deny: []*routercommon.CIDR{
+			{Ip: []byte{10, 0, 0, 1}, Prefix: 32},
+		},
+	})
+	common.Must(err)
+	counter := &testCounter{}
+	ac.rejected = counter
+
+	cases := []struct {
+		source net.Address
+		allows bool
+	}{
+		{net.ParseAddress("10.1.2.3"), true},
+		{net.ParseAddress("::ffff:10.1.2.3"), true},
+		{net.LocalHostIPv6, true},
+		{net.ParseAddress("10.0.0.1"), false},
+		{net.ParseAddress("192.168.1.1"), false},
+		{net.ParseAddress("::2"), false},
+		{net.DomainAddress("/tmp/test.sock"), true},
+	}
+	for _, c := range cases {
+		if ac.allows(c.source) != c.allows {
+			t.Error("expected ", c.allows, " for ", c.source)
+		}
+	}
+	if counter.Value() != 3 {
+		t.Error("expected 3 rejected, but got ", counter.Value())
+	}
+}
+
+func TestAccessControlEmpty(t *testing.T) {
+	ac, err := newAccessControl(nil, "test", &proxyman.AccessControlConfig{})
+	common.Must(err)
+	if ac != nil {
+		t.Fatal("expected no access control")
+	}
+	if !ac.allows(net.ParseAddress("1.1.1.1")) {
+		t.Error("expected everything allowed")
+	}
+}
+
+func TestAccessControlInvalid(t *testing.T) {
+	if _, err := newAccessControl(nil, "", &proxyman.AccessControlConfig{
+		Deny: []*routercommon.CIDR{{Ip: []byte{10, 0, 0}, Prefix: 8}},
+	}); err == nil {
+		t.Error("expected error for invalid IP")
+	}
+}
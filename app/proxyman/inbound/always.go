@@ -17,6 +17,15 @@ import (
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
 )
 
+// isDomainSocket returns whether address is the path of a unix domain socket.
+func isDomainSocket(address net.Address) bool {
+	if !address.Family().IsDomain() {
+		return false
+	}
+	domain := address.Domain()
+	return len(domain) > 0 && (domain[0] == '/' || domain[0] == '@')
+}
+
 func getStatCounter(v *core.Instance, tag string) (stats.Counter, stats.Counter) {
 	var uplinkCounter stats.Counter
 	var downlinkCounter stats.Counter
@@ -70,8 +79,12 @@ func NewAlwaysOnInboundHandlerWithProxy(ctx context.Context, tag string, receive
 
 	uplinkCounter, downlinkCounter := getStatCounter(core.MustFromContext(ctx), tag)
 
+	ac, err := newAccessControl(core.MustFromContext(ctx), tag, receiverConfig.AccessControl)
+	if err != nil {
+		return nil, newError("failed to parse access control").Base(err).AtWarning()
+	}
+
 	nl := p.Network()
-	pr := receiverConfig.PortRange
 	address := receiverConfig.Listen.AsAddress()
 	if address == nil {
 		address = net.AnyIP
@@ -91,32 +104,52 @@ func NewAlwaysOnInboundHandlerWithProxy(ctx context.Context, tag string, receive
 		}
 		mss.SocketSettings.ReceiveOriginalDestAddress = true
 	}
-	if pr == nil {
-		if net.HasNetwork(nl, net.Network_UNIX) {
-			newError("creating unix domain socket worker on ", address).AtDebug().WriteToLog()
-
-			worker := &dsWorker{
-				address:         address,
-				proxy:           p,
-				stream:          mss,
-				tag:             tag,
-				dispatcher:      h.mux,
-				sniffingConfig:  receiverConfig.GetEffectiveSniffingSettings(),
-				uplinkCounter:   uplinkCounter,
-				downlinkCounter: downlinkCounter,
-				ctx:             ctx,
-			}
-			h.workers = append(h.workers, worker)
+	if sp, ok := p.(proxy.StandaloneInbound); ok {
+		newError("creating standalone worker for ", tag).AtDebug().WriteToLog()
+
+		worker := &standaloneWorker{
+			proxy:          sp,
+			tag:            tag,
+			dispatcher:     h.mux,
+			sniffingConfig: receiverConfig.GetEffectiveSniffingSettings(),
+			ctx:            ctx,
 		}
+		h.workers = append(h.workers, worker)
 	}
-	if pr != nil {
-		for port := pr.From; port <= pr.To; port++ {
+	// Unix domain sockets have no port, and neither do the listen addresses
+	// when no port is set.
+	ports := receiverConfig.GetPorts()
+	addresses := []net.Address{address}
+	for _, listen := range receiverConfig.AdditionalListen {
+		addresses = append(addresses, listen.AsAddress())
+	}
+	for _, address := range addresses {
+		if len(ports) == 0 || isDomainSocket(address) {
+			if net.HasNetwork(nl, net.Network_UNIX) {
+				newError("creating unix domain socket worker on ", address).AtDebug().WriteToLog()
+
+				worker := &dsWorker{
+					address:         address,
+					proxy:           p,
+					stream:          mss,
+					tag:             tag,
+					dispatcher:      h.mux,
+					sniffingConfig:  receiverConfig.GetEffectiveSniffingSettings(),
+					uplinkCounter:   uplinkCounter,
+					downlinkCounter: downlinkCounter,
+					ctx:             ctx,
+				}
+				h.workers = append(h.workers, worker)
+			}
+			continue
+		}
+		for _, port := range ports {
 			if net.HasNetwork(nl, net.Network_TCP) {
 				newError("creating stream worker on ", address, ":", port).AtDebug().WriteToLog()
 
 				worker := &tcpWorker{
 					address:         address,
-					port:            net.Port(port),
+					port:            port,
 					proxy:           p,
 					stream:          mss,
 					recvOrigDest:    receiverConfig.ReceiveOriginalDestination,
@@ -125,6 +158,7 @@ func NewAlwaysOnInboundHandlerWithProxy(ctx context.Context, tag string, receive
 					sniffingConfig:  receiverConfig.GetEffectiveSniffingSettings(),
 					uplinkCounter:   uplinkCounter,
 					downlinkCounter: downlinkCounter,
+					accessControl:   ac,
 					ctx:             ctx,
 				}
 				h.workers = append(h.workers, worker)
@@ -136,11 +170,12 @@ func NewAlwaysOnInboundHandlerWithProxy(ctx context.Context, tag string, receive
 					tag:             tag,
 					proxy:           p,
 					address:         address,
-					port:            net.Port(port),
+					port:            port,
 					dispatcher:      h.mux,
 					sniffingConfig:  receiverConfig.GetEffectiveSniffingSettings(),
 					uplinkCounter:   uplinkCounter,
 					downlinkCounter: downlinkCounter,
+					accessControl:   ac,
 					stream:          mss,
 				}
 				h.workers = append(h.workers, worker)
@@ -188,6 +223,17 @@ func (h *AlwaysOnInboundHandler) GetRandomInboundProxy() (interface{}, net.Port,
 	return w.Proxy(), w.Port(), 9999
 }
 
+// HandleConnection implements inbound.ConnectionHandler.
+func (h *AlwaysOnInboundHandler) HandleConnection(conn net.Conn) error {
+	for _, w := range h.workers {
+		if w, ok := w.(connectionWorker); ok {
+			w.handleConnection(conn)
+			return nil
+		}
+	}
+	return newError("inbound ", h.tag, " does not accept stream connections")
+}
+
 func (h *AlwaysOnInboundHandler) Tag() string {
 	return h.tag
 }
@@ -22,6 +22,7 @@ type DynamicInboundHandler struct {
 	proxyConfig    interface{}
 	receiverConfig *proxyman.ReceiverConfig
 	streamSettings *internet.MemoryStreamConfig
+	accessControl  *accessControl
 	portMutex      sync.Mutex
 	portsInUse     map[net.Port]bool
 	workerMutex    sync.RWMutex
@@ -34,6 +35,10 @@ type DynamicInboundHandler struct {
 }
 
 func NewDynamicInboundHandler(ctx context.Context, tag string, receiverConfig *proxyman.ReceiverConfig, proxyConfig interface{}) (*DynamicInboundHandler, error) {
+	if len(receiverConfig.AdditionalListen) > 0 || receiverConfig.PortList != nil {
+		return nil, newError("port allocation requires a single listen address and port range").AtWarning()
+	}
+
 	v := core.MustFromContext(ctx)
 	h := &DynamicInboundHandler{
 		tag:            tag,
@@ -61,6 +66,12 @@ func NewDynamicInboundHandler(ctx context.Context, tag string, receiverConfig *p
 
 	h.streamSettings = mss
 
+	ac, err := newAccessControl(v, tag, receiverConfig.AccessControl)
+	if err != nil {
+		return nil, newError("failed to parse access control").Base(err).AtWarning()
+	}
+	h.accessControl = ac
+
 	h.task = &task.Periodic{
 		Interval: time.Minute * time.Duration(h.receiverConfig.AllocationStrategy.GetRefreshValue()),
 		Execute:  h.refresh,
@@ -138,6 +149,7 @@ func (h *DynamicInboundHandler) refresh() error {
 				sniffingConfig:  h.receiverConfig.GetEffectiveSniffingSettings(),
 				uplinkCounter:   uplinkCounter,
 				downlinkCounter: downlinkCounter,
+				accessControl:   h.accessControl,
 				ctx:             h.ctx,
 			}
 			if err := worker.Start(); err != nil {
@@ -158,6 +170,7 @@ func (h *DynamicInboundHandler) refresh() error {
 				sniffingConfig:  h.receiverConfig.GetEffectiveSniffingSettings(),
 				uplinkCounter:   uplinkCounter,
 				downlinkCounter: downlinkCounter,
+				accessControl:   h.accessControl,
 				stream:          h.streamSettings,
 			}
 			if err := worker.Start(); err != nil {
@@ -30,6 +30,13 @@ type worker interface {
 	Proxy() proxy.Inbound
 }
 
+// connectionWorker is a worker that processes stream connections.
+type connectionWorker interface {
+	// handleConnection processes conn accepted elsewhere, such as a fallback
+	// connection of another inbound, which has already checked its source.
+	handleConnection(conn internet.Connection)
+}
+
 type tcpWorker struct {
 	address         net.Address
 	port            net.Port
@@ -41,6 +48,7 @@ type tcpWorker struct {
 	sniffingConfig  *proxyman.SniffingConfig
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	accessControl   *accessControl
 
 	hub internet.Listener
 
@@ -55,6 +63,12 @@ func getTProxyType(s *internet.MemoryStreamConfig) internet.SocketConfig_TProxyM
 }
 
 func (w *tcpWorker) callback(conn internet.Connection) {
+	if !w.accessControl.allows(addressFromAddr(conn.RemoteAddr())) {
+		newError("connection from ", conn.RemoteAddr(), " rejected by access control").AtDebug().WriteToLog()
+		conn.Close()
+		return
+	}
+
 	ctx, cancel := context.WithCancel(w.ctx)
 	sid := session.NewID()
 	ctx = session.ContextWithID(ctx, sid)
@@ -78,6 +92,20 @@ func (w *tcpWorker) callback(conn internet.Connection) {
 			})
 		}
 	}
+	w.process(ctx, conn)
+	cancel()
+	conn.Close()
+}
+
+func (w *tcpWorker) handleConnection(conn internet.Connection) {
+	ctx, cancel := context.WithCancel(w.ctx)
+	ctx = session.ContextWithID(ctx, session.NewID())
+	w.process(ctx, conn)
+	cancel()
+	conn.Close()
+}
+
+func (w *tcpWorker) process(ctx context.Context, conn internet.Connection) {
 	ctx = session.ContextWithInbound(ctx, &session.Inbound{
 		Source:  net.DestinationFromAddr(conn.RemoteAddr()),
 		Gateway: net.TCPDestination(w.address, w.port),
@@ -102,8 +130,6 @@ func (w *tcpWorker) callback(conn internet.Connection) {
 	if err := w.proxy.Process(ctx, net.Network_TCP, conn, w.dispatcher); err != nil {
 		newError("connection ends").Base(err).WriteToLog(session.ExportIDToError(ctx))
 	}
-	cancel()
-	conn.Close()
 }
 
 func (w *tcpWorker) Proxy() proxy.Inbound {
@@ -239,6 +265,7 @@ type udpWorker struct {
 	sniffingConfig  *proxyman.SniffingConfig
 	uplinkCounter   stats.Counter
 	downlinkCounter stats.Counter
+	accessControl   *accessControl
 
 	checker    *task.Periodic
 	activeConn map[connID]*udpConn
@@ -280,6 +307,12 @@ func (w *udpWorker) getConnection(id connID) (*udpConn, bool) {
 }
 
 func (w *udpWorker) callback(b *buf.Buffer, source net.Destination, originalDest net.Destination) {
+	if !w.accessControl.allows(source.Address) {
+		newError("packet from ", source, " rejected by access control").AtDebug().WriteToLog()
+		b.Release()
+		return
+	}
+
 	id := connID{
 		src: source,
 	}
@@ -476,6 +509,10 @@ func (w *dsWorker) callback(conn internet.Connection) {
 	conn.Close()
 }
 
+func (w *dsWorker) handleConnection(conn internet.Connection) {
+	w.callback(conn)
+}
+
 func (w *dsWorker) Proxy() proxy.Inbound {
 	return w.proxy
 }
@@ -512,3 +549,44 @@ func (w *dsWorker) Close() error {
 
 	return nil
 }
+
+// standaloneWorker runs a StandaloneInbound, which accepts connections by
+// itself.
+type standaloneWorker struct {
+	proxy          proxy.StandaloneInbound
+	tag            string
+	dispatcher     routing.Dispatcher
+	sniffingConfig *proxyman.SniffingConfig
+
+	ctx context.Context
+}
+
+func (w *standaloneWorker) Start() error {
+	ctx := session.ContextWithInbound(w.ctx, &session.Inbound{
+		Tag: w.tag,
+	})
+	content := new(session.Content)
+	if w.sniffingConfig != nil {
+		content.SniffingRequest.Enabled = w.sniffingConfig.Enabled
+		content.SniffingRequest.OverrideDestinationForProtocol = w.sniffingConfig.DestinationOverride
+		content.SniffingRequest.MetadataOnly = w.sniffingConfig.MetadataOnly
+		content.SniffingRequest.RouteOnly = w.sniffingConfig.RouteOnly
+	}
+	ctx = session.ContextWithContent(ctx, content)
+	if err := w.proxy.Serve(ctx, w.dispatcher); err != nil {
+		return newError("failed to start inbound ", w.tag).AtWarning().Base(err)
+	}
+	return nil
+}
+
+func (w *standaloneWorker) Close() error {
+	return w.proxy.Close()
+}
+
+func (w *standaloneWorker) Port() net.Port {
+	return 0
+}
+
+func (w *standaloneWorker) Proxy() proxy.Inbound {
+	return w.proxy
+}
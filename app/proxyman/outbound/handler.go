@@ -23,6 +23,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/proxy"
 	"github.com/v2fly/v2ray-core/v5/transport"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tcp"
 	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
 	"github.com/v2fly/v2ray-core/v5/transport/internet/xtls"
 	"github.com/v2fly/v2ray-core/v5/transport/pipe"
@@ -149,6 +150,17 @@ func (h *Handler) Tag() string {
 
 // Dispatch implements proxy.Outbound.Dispatch.
 func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
+	if chain := session.ChainFromContext(ctx); chain != nil && chain.Hop(h.tag) > 0 {
+		if err := h.checkChainHop(); err != nil {
+			err := newError("outbound ", h.tag, " cannot dial through the previous hop of chain").Base(err)
+			err.WriteToLog(session.ExportIDToError(ctx))
+			session.SubmitOutboundErrorToOriginator(ctx, err)
+			common.Interrupt(link.Writer)
+			common.Interrupt(link.Reader)
+			return
+		}
+	}
+
 	outbound := session.OutboundFromContext(ctx)
 	destination := outbound.Target
 
@@ -206,6 +218,8 @@ func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
 			}
 			session.SubmitOutboundErrorToOriginator(ctx, err)
 			common.Interrupt(link.Writer)
+		} else {
+			h.notifyResult(nil)
 		}
 	} else {
 		err := h.proxy.Process(ctx, link, h)
@@ -221,6 +235,7 @@ func (h *Handler) Dispatch(ctx context.Context, link *transport.Link) {
 			session.SubmitOutboundErrorToOriginator(ctx, err)
 			common.Interrupt(link.Writer)
 		} else {
+			h.notifyResult(nil)
 			common.Close(link.Writer)
 		}
 		common.Interrupt(link.Reader)
@@ -294,6 +309,8 @@ func (h *Handler) DispatchConn(ctx context.Context, conn net.Conn) {
 			}
 			session.SubmitOutboundErrorToOriginator(ctx, err)
 			common.Interrupt(link.Writer)
+		} else {
+			h.notifyResult(nil)
 		}
 		return
 	}
@@ -304,12 +321,26 @@ func (h *Handler) DispatchConn(ctx context.Context, conn net.Conn) {
 		if !(cause == context.Canceled || cause == io.ErrClosedPipe || cause == io.EOF) {
 			err := newError("failed to process outbound traffic").Base(err)
 			err.WriteToLog(session.ExportIDToError(ctx))
+		} else {
+			h.notifyResult(nil)
 		}
 		session.SubmitOutboundErrorToOriginator(ctx, err)
+	} else {
+		h.notifyResult(nil)
 	}
 	common.Close(conn)
 }
 
+// notifyResult passes the result of a connection to the outbound manager, so
+// that balancers can take failing handlers out of service before they are
+// probed again. Only failures to dial are reported, as a connection may also
+// fail later because of the remote side.
+func (h *Handler) notifyResult(err error) {
+	if notifier, ok := h.outboundManager.(outbound.ResultNotifier); ok {
+		notifier.NotifyResult(h.tag, err)
+	}
+}
+
 // Address implements internet.Dialer.
 func (h *Handler) Address() net.Address {
 	if h.senderSettings == nil || h.senderSettings.Via == nil {
@@ -320,6 +351,18 @@ func (h *Handler) Address() net.Address {
 
 // Dial implements internet.Dialer.
 func (h *Handler) Dial(ctx context.Context, dest net.Destination) (internet.Connection, error) {
+	if chain := session.ChainFromContext(ctx); chain != nil {
+		if hop := chain.Hop(h.tag); hop > 0 {
+			tag := chain.Tags[hop-1]
+			handler := h.outboundManager.GetHandler(tag)
+			if handler == nil {
+				return nil, newError("failed to get outbound handler with tag: ", tag)
+			}
+			newError("chaining to ", tag, " for dest ", dest).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+			return h.dialThrough(session.ContextWithChainHop(ctx, chain, hop-1), handler, dest)
+		}
+	}
+
 	if h.senderSettings != nil {
 		if h.senderSettings.ProxySettings.HasTag() && !h.senderSettings.ProxySettings.TransportLayerProxy {
 			ctx = proxyman.SetPreferUseIP(ctx, true)
@@ -327,25 +370,7 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (internet.Conn
 			handler := h.outboundManager.GetHandler(tag)
 			if handler != nil {
 				newError("proxying to ", tag, " for dest ", dest).AtDebug().WriteToLog(session.ExportIDToError(ctx))
-				ctx = session.ContextWithOutbound(ctx, &session.Outbound{
-					Target: dest,
-				})
-
-				opts := pipe.OptionsFromContext(ctx)
-				uplinkReader, uplinkWriter := pipe.New(opts...)
-				downlinkReader, downlinkWriter := pipe.New(opts...)
-
-				go handler.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
-				conn := buf.NewConnection(buf.ConnectionInputMulti(uplinkWriter), buf.ConnectionOutputMulti(downlinkReader))
-
-				if config := tls.ConfigFromStreamSettings(h.streamSettings); config != nil {
-					tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
-					conn = tls.Client(conn, tlsConfig)
-				} else if config := xtls.ConfigFromStreamSettings(h.streamSettings); config != nil {
-					return xtls.Client(conn, config.GetXTLSConfig(xtls.WithDestination(dest))), nil
-				}
-
-				return h.getStatCouterConnection(conn), nil
+				return h.dialThrough(ctx, handler, dest)
 			}
 
 			newError("failed to get outbound handler with tag: ", tag).AtWarning().WriteToLog(session.ExportIDToError(ctx))
@@ -390,9 +415,59 @@ func (h *Handler) Dial(ctx context.Context, dest net.Destination) (internet.Conn
 	}
 
 	conn, err := internet.Dial(ctx, dest, h.streamSettings)
+	if err != nil {
+		h.notifyResult(err)
+	}
 	return h.getStatCouterConnection(conn), err
 }
 
+// checkChainHop returns an error if the handler would not dial through the
+// previous hop of a chain. Mux connections are dialed without the context of
+// the chain, and dialThrough only applies TLS and XTLS of the stream settings.
+func (h *Handler) checkChainHop() error {
+	if h.mux != nil && h.mux.Enabled {
+		return newError("mux is not supported")
+	}
+	settings := h.streamSettings
+	if settings == nil {
+		return nil
+	}
+	if settings.ProtocolName != "tcp" {
+		return newError("transport ", settings.ProtocolName, " is not supported")
+	}
+	if config, ok := settings.ProtocolSettings.(*tcp.Config); ok && config.HeaderSettings != nil {
+		return newError("TCP header is not supported")
+	}
+	if settings.SecuritySettings != nil && tls.ConfigFromStreamSettings(settings) == nil && xtls.ConfigFromStreamSettings(settings) == nil {
+		return newError("security ", settings.SecurityType, " is not supported")
+	}
+	return nil
+}
+
+// dialThrough returns a connection to dest through handler.
+func (h *Handler) dialThrough(ctx context.Context, handler outbound.Handler, dest net.Destination) (internet.Connection, error) {
+	ctx = proxyman.SetPreferUseIP(ctx, true)
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{
+		Target: dest,
+	})
+
+	opts := pipe.OptionsFromContext(ctx)
+	uplinkReader, uplinkWriter := pipe.New(opts...)
+	downlinkReader, downlinkWriter := pipe.New(opts...)
+
+	go handler.Dispatch(ctx, &transport.Link{Reader: uplinkReader, Writer: downlinkWriter})
+	conn := buf.NewConnection(buf.ConnectionInputMulti(uplinkWriter), buf.ConnectionOutputMulti(downlinkReader))
+
+	if config := tls.ConfigFromStreamSettings(h.streamSettings); config != nil {
+		tlsConfig := config.GetTLSConfig(tls.WithDestination(dest))
+		conn = tls.Client(conn, tlsConfig)
+	} else if config := xtls.ConfigFromStreamSettings(h.streamSettings); config != nil {
+		return xtls.Client(conn, config.GetXTLSConfig(xtls.WithDestination(dest))), nil
+	}
+
+	return h.getStatCouterConnection(conn), nil
+}
+
 func (h *Handler) getStatCouterConnection(conn internet.Connection) internet.Connection {
 	if h.uplinkCounter != nil || h.downlinkCounter != nil {
 		return &internet.StatCounterConn{
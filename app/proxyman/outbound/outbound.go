@@ -20,6 +20,7 @@ type Manager struct {
 	defaultHandler   outbound.Handler
 	taggedHandler    map[string]outbound.Handler
 	untaggedHandlers []outbound.Handler
+	resultListeners  []outbound.ResultListener
 	running          bool
 }
 
@@ -165,6 +166,28 @@ func (m *Manager) Select(selectors []string) []string {
 	return tags
 }
 
+// AddResultListener implements outbound.ResultNotifier.
+func (m *Manager) AddResultListener(listener outbound.ResultListener) {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	m.resultListeners = append(m.resultListeners, listener)
+}
+
+// NotifyResult implements outbound.ResultNotifier.
+func (m *Manager) NotifyResult(tag string, err error) {
+	if len(tag) == 0 {
+		return
+	}
+
+	m.access.RLock()
+	defer m.access.RUnlock()
+
+	for _, listener := range m.resultListeners {
+		listener.OnResult(tag, err)
+	}
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*proxyman.OutboundConfig)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		return New(ctx, config.(*proxyman.OutboundConfig))
@@ -6,12 +6,19 @@ package router
 import (
 	"context"
 
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/features"
 	"github.com/v2fly/v2ray-core/v5/features/extension"
 	"github.com/v2fly/v2ray-core/v5/features/outbound"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
 )
 
+// BalancingStrategy picks an outbound from the candidates for a connection.
+// The routing context may be nil if the pick is not for a connection.
 type BalancingStrategy interface {
-	PickOutbound([]string) string
+	PickOutbound(routing.Context, []string) string
 }
 
 type BalancingPrincipleTarget interface {
@@ -27,8 +34,8 @@ type Balancer struct {
 	override override
 }
 
-// PickOutbound picks the tag of an outbound
-func (b *Balancer) PickOutbound() (string, error) {
+// PickOutbound picks the tag of an outbound for the routing context
+func (b *Balancer) PickOutbound(ctx routing.Context) (string, error) {
 	candidates, err := b.SelectOutbounds()
 	if err != nil {
 		if b.fallbackTag != "" {
@@ -41,7 +48,7 @@ func (b *Balancer) PickOutbound() (string, error) {
 	if o := b.override.Get(); o != "" {
 		tag = o
 	} else {
-		tag = b.strategy.PickOutbound(candidates)
+		tag = b.strategy.PickOutbound(ctx, candidates)
 	}
 	if tag == "" {
 		if b.fallbackTag != "" {
@@ -54,6 +61,56 @@ func (b *Balancer) PickOutbound() (string, error) {
 	return tag, nil
 }
 
+// findObservatory returns the observatory with tag, or the default one if tag
+// is empty. It returns nil if there is no observatory in the instance.
+func findObservatory(ctx context.Context, tag string) extension.Observatory {
+	if ctx == nil {
+		return nil
+	}
+	instance := core.FromContext(ctx)
+	if instance == nil {
+		return nil
+	}
+	observatory, ok := instance.GetFeature(extension.ObservatoryType()).(extension.Observatory)
+	if !ok {
+		return nil
+	}
+	if tag != "" {
+		return common.Must2(observatory.(features.TaggedFeatures).GetFeaturesByTag(tag)).(extension.Observatory)
+	}
+	return observatory
+}
+
+// excludeDeadOutbounds filters away the candidates that observatory reports
+// not alive. All candidates are kept if observatory is nil.
+func excludeDeadOutbounds(ctx context.Context, o extension.Observatory, candidates []string) []string {
+	if o == nil {
+		return candidates
+	}
+	observeReport, err := o.GetObservation(ctx)
+	if err != nil {
+		newError("cannot get observe report").Base(err).WriteToLog()
+		return candidates
+	}
+	result, ok := observeReport.(*observatory.ObservationResult)
+	if !ok {
+		return candidates
+	}
+	dead := make(map[string]bool)
+	for _, status := range result.Status {
+		if !status.Alive {
+			dead[status.OutboundTag] = true
+		}
+	}
+	alive := make([]string, 0, len(candidates))
+	for _, tag := range candidates {
+		if !dead[tag] {
+			alive = append(alive, tag)
+		}
+	}
+	return alive
+}
+
 func (b *Balancer) InjectContext(ctx context.Context) {
 	if contextReceiver, ok := b.strategy.(extension.ContextReceiver); ok {
 		contextReceiver.InjectContext(ctx)
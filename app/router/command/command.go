@@ -62,11 +62,21 @@ func NewRoutingServer(router routing.Router, routingStats stats.Channel) Routing
 	}
 }
 
+// routeTester is implemented by routers that pick routes without counting
+// them as taken.
+type routeTester interface {
+	TestRoute(ctx routing.Context) (routing.Route, error)
+}
+
 func (s *routingServer) TestRoute(ctx context.Context, request *TestRouteRequest) (*RoutingContext, error) {
 	if request.RoutingContext == nil {
 		return nil, newError("Invalid routing request.")
 	}
-	route, err := s.router.PickRoute(AsRoutingContext(request.RoutingContext))
+	pickRoute := s.router.PickRoute
+	if tester, ok := s.router.(routeTester); ok {
+		pickRoute = tester.TestRoute
+	}
+	route, err := pickRoute(AsRoutingContext(request.RoutingContext))
 	if err != nil {
 		return nil, err
 	}
@@ -247,6 +247,18 @@ func TestSerivceTestRoute(t *testing.T) {
 				Networks:  []net.Network{net.Network_UDP, net.Network_TCP},
 				TargetTag: &router.RoutingRule_Tag{Tag: "out"},
 			},
+			{
+				Protocol: []string{"dns"},
+				Logical: &router.LogicalRule{
+					Mode: router.LogicalRule_Or,
+					Rule: []*router.RoutingRule{
+						{InboundTag: []string{"dns-in"}},
+						{UserEmail: []string{"dns@v2fly.org"}},
+					},
+				},
+				TargetTag: &router.RoutingRule_Tag{Tag: "dns-out"},
+				RuleTag:   "dns",
+			},
 		},
 	}, mocks.NewDNSClient(mockCtl), mocks.NewOutboundManager(mockCtl), nil))
 
@@ -275,13 +287,14 @@ func TestSerivceTestRoute(t *testing.T) {
 		client := NewRoutingServiceClient(conn)
 
 		testCases := []*RoutingContext{
-			{InboundTag: "in", OutboundTag: "out"},
-			{TargetIPs: [][]byte{{1, 2, 3, 4}}, TargetPort: 8080, OutboundTag: "out"},
-			{TargetDomain: "example.com", TargetPort: 443, OutboundTag: "out"},
-			{SourcePort: 9999, TargetPort: 9999, OutboundTag: "out"},
-			{Network: net.Network_UDP, Protocol: "bittorrent", OutboundTag: "blocked"},
-			{User: "example@v2fly.org", OutboundTag: "out"},
-			{SourceIPs: [][]byte{{127, 0, 0, 1}}, Attributes: map[string]string{"attr": "value"}, OutboundTag: "out"},
+			{InboundTag: "in", OutboundTag: "out", MatchedRule: "rule[0]"},
+			{TargetIPs: [][]byte{{1, 2, 3, 4}}, TargetPort: 8080, OutboundTag: "out", MatchedRule: "rule[2]"},
+			{TargetDomain: "example.com", TargetPort: 443, OutboundTag: "out", MatchedRule: "rule[4]"},
+			{SourcePort: 9999, TargetPort: 9999, OutboundTag: "out", MatchedRule: "rule[3]"},
+			{Network: net.Network_UDP, Protocol: "bittorrent", OutboundTag: "blocked", MatchedRule: "rule[1]"},
+			{User: "example@v2fly.org", OutboundTag: "out", MatchedRule: "rule[6]"},
+			{SourceIPs: [][]byte{{127, 0, 0, 1}}, Attributes: map[string]string{"attr": "value"}, OutboundTag: "out", MatchedRule: "rule[5]"},
+			{User: "dns@v2fly.org", Protocol: "dns", OutboundTag: "dns-out", MatchedRule: "rule[8].logical.rule[1]", RuleTag: "dns"},
 		}
 
 		// Test simple TestRoute
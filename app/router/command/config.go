@@ -56,8 +56,12 @@ var fieldMap = map[string]func(*RoutingContext, routing.Route){
 	"protocol":       func(s *RoutingContext, r routing.Route) { s.Protocol = r.GetProtocol() },
 	"user":           func(s *RoutingContext, r routing.Route) { s.User = r.GetUser() },
 	"attributes":     func(s *RoutingContext, r routing.Route) { s.Attributes = r.GetAttributes() },
+	"process_name":   func(s *RoutingContext, r routing.Route) { s.ProcessName = r.GetProcessName() },
+	"process_path":   func(s *RoutingContext, r routing.Route) { s.ProcessPath = r.GetProcessPath() },
 	"outbound_group": func(s *RoutingContext, r routing.Route) { s.OutboundGroupTags = r.GetOutboundGroupTags() },
 	"outbound":       func(s *RoutingContext, r routing.Route) { s.OutboundTag = r.GetOutboundTag() },
+	"matched_rule":   func(s *RoutingContext, r routing.Route) { s.MatchedRule = r.GetMatchedRule() },
+	"rule_tag":       func(s *RoutingContext, r routing.Route) { s.RuleTag = r.GetRuleTag() },
 }
 
 // AsProtobufMessage takes selectors of fields and returns a function to convert routing.Route to protobuf RoutingContext.
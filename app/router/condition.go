@@ -1,7 +1,9 @@
 package router
 
 import (
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
 	"github.com/v2fly/v2ray-core/v5/common/net"
@@ -15,6 +17,85 @@ type Condition interface {
 	Apply(ctx routing.Context) bool
 }
 
+// conditionExplainer is implemented by conditions with alternatives, to tell
+// which part of them matched.
+type conditionExplainer interface {
+	// explainMatch returns the path to the part of the condition that matches
+	// ctx, given that the condition does.
+	explainMatch(ctx routing.Context) string
+}
+
+func explainMatch(cond Condition, ctx routing.Context) string {
+	if explainer, ok := cond.(conditionExplainer); ok {
+		return explainer.explainMatch(ctx)
+	}
+	return ""
+}
+
+// mismatchExplainer is implemented by conditions made of other conditions, to
+// tell which of them fails.
+type mismatchExplainer interface {
+	// explainMismatch returns why ctx does not match the condition, or an
+	// empty string if it does.
+	explainMismatch(ctx routing.Context) string
+}
+
+func explainMismatch(cond Condition, ctx routing.Context) string {
+	if explainer, ok := cond.(mismatchExplainer); ok {
+		return explainer.explainMismatch(ctx)
+	}
+	if cond.Apply(ctx) {
+		return ""
+	}
+	return conditionName(cond) + " does not match"
+}
+
+// conditionName returns the name of the rule field that cond is built from.
+func conditionName(cond Condition) string {
+	switch cond := cond.(type) {
+	case *DomainMatcher:
+		return "domain"
+	case *MultiGeoIPMatcher:
+		if cond.onSource {
+			return "source ip"
+		}
+		return "ip"
+	case *PortMatcher:
+		if cond.onSource {
+			return "source port"
+		}
+		return "port"
+	case NetworkMatcher:
+		return "network"
+	case *UserMatcher:
+		return "user"
+	case *InboundTagMatcher:
+		return "inbound tag"
+	case *ProtocolMatcher:
+		return "protocol"
+	case *AttributeMatcher:
+		return "attributes"
+	case *UidMatcher:
+		return "uid"
+	case *ProcessNameMatcher:
+		return "process name"
+	case *ProcessPathMatcher:
+		return "process path"
+	case *WifiSSIDMatcher:
+		return "wifi ssid"
+	case *NetworkTypeMatcher:
+		return "network type"
+	case RuleSetCondition:
+		return "rule set"
+	case *LogicalMatcher:
+		return "logical"
+	case *ScheduleMatcher:
+		return "schedule"
+	default:
+		return "condition"
+	}
+}
+
 type ConditionChan []Condition
 
 func NewConditionChan() *ConditionChan {
@@ -37,6 +118,26 @@ func (v *ConditionChan) Apply(ctx routing.Context) bool {
 	return true
 }
 
+// explainMatch implements conditionExplainer.
+func (v *ConditionChan) explainMatch(ctx routing.Context) string {
+	var path string
+	for _, cond := range *v {
+		path += explainMatch(cond, ctx)
+	}
+	return path
+}
+
+// explainMismatch implements mismatchExplainer. Only the first condition
+// that fails is reported.
+func (v *ConditionChan) explainMismatch(ctx routing.Context) string {
+	for _, cond := range *v {
+		if reason := explainMismatch(cond, ctx); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
 func (v *ConditionChan) Len() int {
 	return len(*v)
 }
@@ -339,6 +440,46 @@ func (u UidMatcher) Apply(ctx routing.Context) bool {
 	return u.uidList[ctx.GetUid()]
 }
 
+type ProcessNameMatcher struct {
+	names map[string]bool
+}
+
+func NewProcessNameMatcher(names []string) *ProcessNameMatcher {
+	m := &ProcessNameMatcher{
+		names: map[string]bool{},
+	}
+	for _, name := range names {
+		m.names[name] = true
+	}
+	return m
+}
+
+// Apply implements Condition.
+func (m *ProcessNameMatcher) Apply(ctx routing.Context) bool {
+	name := ctx.GetProcessName()
+	return name != "" && m.names[name]
+}
+
+type ProcessPathMatcher struct {
+	paths map[string]bool
+}
+
+func NewProcessPathMatcher(paths []string) *ProcessPathMatcher {
+	m := &ProcessPathMatcher{
+		paths: map[string]bool{},
+	}
+	for _, path := range paths {
+		m.paths[path] = true
+	}
+	return m
+}
+
+// Apply implements Condition.
+func (m *ProcessPathMatcher) Apply(ctx routing.Context) bool {
+	path := ctx.GetProcessPath()
+	return path != "" && m.paths[path]
+}
+
 type WifiSSIDMatcher struct {
 	ssid map[string]bool
 }
@@ -375,3 +516,167 @@ func NewNetworkTypeMatcher(networkType string) *NetworkTypeMatcher {
 func (m *NetworkTypeMatcher) Apply(ctx routing.Context) bool {
 	return m.networkType == ctx.GetNetworkType()
 }
+
+// LogicalMatcher combines conditions of nested rules.
+type LogicalMatcher struct {
+	mode       LogicalRule_Mode
+	conditions []Condition
+	invert     bool
+}
+
+func NewLogicalMatcher(mode LogicalRule_Mode, conditions []Condition, invert bool) *LogicalMatcher {
+	return &LogicalMatcher{
+		mode:       mode,
+		conditions: conditions,
+		invert:     invert,
+	}
+}
+
+// Apply implements Condition.
+func (m *LogicalMatcher) Apply(ctx routing.Context) bool {
+	return m.match(ctx) != m.invert
+}
+
+func (m *LogicalMatcher) match(ctx routing.Context) bool {
+	switch m.mode {
+	case LogicalRule_Or:
+		return m.firstMatch(ctx) >= 0
+	default:
+		for _, cond := range m.conditions {
+			if !cond.Apply(ctx) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// firstMatch returns the index of the first nested condition that matches
+// ctx, or -1 if there is none.
+func (m *LogicalMatcher) firstMatch(ctx routing.Context) int {
+	for i, cond := range m.conditions {
+		if cond.Apply(ctx) {
+			return i
+		}
+	}
+	return -1
+}
+
+// explainMatch implements conditionExplainer. For an Or rule, it tells the
+// nested rule that matched. Every nested rule counts for an And rule, and none
+// for an inverted rule.
+func (m *LogicalMatcher) explainMatch(ctx routing.Context) string {
+	if m.mode != LogicalRule_Or || m.invert {
+		return ".logical"
+	}
+	i := m.firstMatch(ctx)
+	if i < 0 {
+		return ".logical"
+	}
+	return ".logical.rule[" + strconv.Itoa(i) + "]" + explainMatch(m.conditions[i], ctx)
+}
+
+// explainMismatch implements mismatchExplainer.
+func (m *LogicalMatcher) explainMismatch(ctx routing.Context) string {
+	if m.Apply(ctx) {
+		return ""
+	}
+	if m.invert {
+		return "logical: nested rules match, but the result is inverted"
+	}
+	if m.mode == LogicalRule_Or {
+		return "logical: no nested rule matches"
+	}
+	for i, cond := range m.conditions {
+		if reason := explainMismatch(cond, ctx); reason != "" {
+			return "logical.rule[" + strconv.Itoa(i) + "]: " + reason
+		}
+	}
+	return "logical does not match"
+}
+
+const minutesPerDay = 24 * 60
+
+type scheduleWindow struct {
+	// weekdays is a bitmask of days of the week, with bit 0 being Sunday.
+	weekdays uint8
+	start    int
+	end      int
+}
+
+func (w *scheduleWindow) onWeekday(weekday time.Weekday) bool {
+	return w.weekdays&(1<<uint(weekday)) != 0
+}
+
+func (w *scheduleWindow) contains(weekday time.Weekday, minute int) bool {
+	if w.start < w.end {
+		return w.onWeekday(weekday) && minute >= w.start && minute < w.end
+	}
+	// The window spans midnight, so it may have started on the day before.
+	if minute >= w.start && w.onWeekday(weekday) {
+		return true
+	}
+	return minute < w.end && w.onWeekday((weekday+6)%7)
+}
+
+// ScheduleMatcher matches if the current time is within a schedule.
+type ScheduleMatcher struct {
+	windows  []scheduleWindow
+	location *time.Location
+	now      func() time.Time
+}
+
+// NewScheduleMatcher creates a ScheduleMatcher that gets the current time from
+// now, or time.Now if it is nil.
+func NewScheduleMatcher(schedule *Schedule, now func() time.Time) (*ScheduleMatcher, error) {
+	if len(schedule.Window) == 0 {
+		return nil, newError("empty schedule")
+	}
+	if now == nil {
+		now = time.Now
+	}
+	m := &ScheduleMatcher{
+		location: time.Local,
+		now:      now,
+	}
+	if schedule.Timezone != "" {
+		location, err := time.LoadLocation(schedule.Timezone)
+		if err != nil {
+			return nil, newError("unknown time zone ", schedule.Timezone).Base(err)
+		}
+		m.location = location
+	}
+	for _, window := range schedule.Window {
+		if window.Start >= minutesPerDay || window.End >= minutesPerDay {
+			return nil, newError("invalid schedule window ", window.Start, "-", window.End)
+		}
+		w := scheduleWindow{
+			start: int(window.Start),
+			end:   int(window.End),
+		}
+		if len(window.Weekday) == 0 {
+			w.weekdays = 0x7f
+		}
+		for _, weekday := range window.Weekday {
+			if weekday > 6 {
+				return nil, newError("invalid weekday ", weekday)
+			}
+			w.weekdays |= 1 << weekday
+		}
+		m.windows = append(m.windows, w)
+	}
+	return m, nil
+}
+
+// Apply implements Condition.
+func (m *ScheduleMatcher) Apply(routing.Context) bool {
+	t := m.now().In(m.location)
+	weekday := t.Weekday()
+	minute := t.Hour()*60 + t.Minute()
+	for i := range m.windows {
+		if m.windows[i].contains(weekday, minute) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+//go:build linux
+// +build linux
+
+package router_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/app/router"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+)
+
+func TestProcessMatcher(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	defer conn.Close()
+
+	executable, err := os.Executable()
+	common.Must(err)
+
+	cases := []struct {
+		rule   *router.RoutingRule
+		output bool
+	}{
+		{
+			rule:   &router.RoutingRule{ProcessName: []string{filepath.Base(executable)}},
+			output: true,
+		},
+		{
+			rule:   &router.RoutingRule{ProcessPath: []string{executable}},
+			output: true,
+		},
+		{
+			rule:   &router.RoutingRule{ProcessName: []string{"v2ray-nonexistent"}},
+			output: false,
+		},
+	}
+
+	for _, test := range cases {
+		cond, err := test.rule.BuildCondition()
+		common.Must(err)
+
+		ctx := withInbound(&session.Inbound{Source: net.DestinationFromAddr(conn.LocalAddr())})
+		if actual := cond.Apply(ctx); actual != test.output {
+			t.Error("rule ", test.rule, " expected ", test.output, " but got ", actual)
+		}
+	}
+}
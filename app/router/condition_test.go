@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/v2fly/v2ray-core/v5/app/router"
 	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
@@ -336,6 +337,79 @@ func TestRoutingRule(t *testing.T) {
 				},
 			},
 		},
+		{
+			rule: &router.RoutingRule{
+				Domain: []*routercommon.Domain{{Type: routercommon.Domain_RootDomain, Value: "v2fly.org"}},
+				Logical: &router.LogicalRule{
+					Rule: []*router.RoutingRule{
+						{PortList: &net.PortList{Range: []*net.PortRange{{From: 443, To: 443}}}},
+					},
+					Invert: true,
+				},
+			},
+			test: []ruleTest{
+				{
+					input:  withOutbound(&session.Outbound{Target: net.TCPDestination(net.DomainAddress("www.v2fly.org"), 80)}),
+					output: true,
+				},
+				{
+					input:  withOutbound(&session.Outbound{Target: net.TCPDestination(net.DomainAddress("www.v2fly.org"), 443)}),
+					output: false,
+				},
+				{
+					input:  withOutbound(&session.Outbound{Target: net.TCPDestination(net.DomainAddress("www.example.com"), 80)}),
+					output: false,
+				},
+			},
+		},
+		{
+			rule: &router.RoutingRule{
+				Logical: &router.LogicalRule{
+					Rule: []*router.RoutingRule{
+						{
+							Logical: &router.LogicalRule{
+								Mode: router.LogicalRule_Or,
+								Rule: []*router.RoutingRule{
+									{InboundTag: []string{"a"}},
+									{UserEmail: []string{"b@v2fly.org"}},
+								},
+							},
+						},
+						{Protocol: []string{"bittorrent"}},
+					},
+				},
+			},
+			test: []ruleTest{
+				{
+					input: &routing_session.Context{
+						Inbound: &session.Inbound{Tag: "a"},
+						Content: &session.Content{Protocol: "bittorrent"},
+					},
+					output: true,
+				},
+				{
+					input: &routing_session.Context{
+						Inbound: &session.Inbound{Tag: "c", User: &protocol.MemoryUser{Email: "b@v2fly.org"}},
+						Content: &session.Content{Protocol: "bittorrent"},
+					},
+					output: true,
+				},
+				{
+					input: &routing_session.Context{
+						Inbound: &session.Inbound{Tag: "a"},
+						Content: &session.Content{Protocol: "http"},
+					},
+					output: false,
+				},
+				{
+					input: &routing_session.Context{
+						Inbound: &session.Inbound{Tag: "c"},
+						Content: &session.Content{Protocol: "bittorrent"},
+					},
+					output: false,
+				},
+			},
+		},
 	}
 
 	for _, test := range cases {
@@ -551,3 +625,90 @@ func BenchmarkMultiGeoIPMatcher(b *testing.B) {
 		_ = matcher.Apply(ctx)
 	}
 }
+
+func TestRuleExplainMismatch(t *testing.T) {
+	rule := &router.RoutingRule{
+		Domain:   []*routercommon.Domain{{Type: routercommon.Domain_RootDomain, Value: "v2fly.org"}},
+		PortList: &net.PortList{Range: []*net.PortRange{{From: 443, To: 443}}},
+		Logical: &router.LogicalRule{
+			Rule: []*router.RoutingRule{
+				{Networks: []net.Network{net.Network_TCP}},
+				{InboundTag: []string{"in"}},
+			},
+		},
+	}
+	cond, err := rule.BuildCondition()
+	common.Must(err)
+	r := &router.Rule{Condition: cond, Index: 2, RuleTag: "test"}
+
+	ctxWithTarget := func(domain string, port net.Port, inboundTag string) routing.Context {
+		return &routing_session.Context{
+			Inbound:  &session.Inbound{Tag: inboundTag},
+			Outbound: &session.Outbound{Target: net.TCPDestination(net.DomainAddress(domain), port)},
+		}
+	}
+
+	for _, test := range []struct {
+		input  routing.Context
+		output string
+	}{
+		{ctxWithTarget("www.example.com", 443, "in"), "domain does not match"},
+		{ctxWithTarget("www.v2fly.org", 80, "in"), "port does not match"},
+		{ctxWithTarget("www.v2fly.org", 443, "out"), "logical.rule[1]: inbound tag does not match"},
+		{ctxWithTarget("www.v2fly.org", 443, "in"), ""},
+	} {
+		if reason := r.ExplainMismatch(test.input); reason != test.output {
+			t.Error("expected '", test.output, "', but got '", reason, "'")
+		}
+	}
+	if name := r.String(); name != "rule[2](test)" {
+		t.Error("unexpected rule name ", name)
+	}
+}
+
+func TestScheduleMatcher(t *testing.T) {
+	var now time.Time
+	matcher, err := router.NewScheduleMatcher(&router.Schedule{
+		Window: []*router.Schedule_Window{
+			// Friday and Saturday nights, from 22:00 to 06:00.
+			{Weekday: []uint32{5, 6}, Start: 22 * 60, End: 6 * 60},
+			// Every day, from 12:00 to 13:30.
+			{Start: 12 * 60, End: 13*60 + 30},
+		},
+		Timezone: "UTC",
+	}, func() time.Time { return now })
+	common.Must(err)
+
+	for _, test := range []struct {
+		now    time.Time
+		output bool
+	}{
+		{time.Date(2022, time.March, 4, 21, 59, 0, 0, time.UTC), false}, // Friday
+		{time.Date(2022, time.March, 4, 22, 0, 0, 0, time.UTC), true},
+		{time.Date(2022, time.March, 5, 5, 59, 0, 0, time.UTC), true}, // Saturday
+		{time.Date(2022, time.March, 5, 6, 0, 0, 0, time.UTC), false},
+		{time.Date(2022, time.March, 6, 3, 0, 0, 0, time.UTC), true},   // Sunday
+		{time.Date(2022, time.March, 6, 23, 0, 0, 0, time.UTC), false}, // Sunday
+		{time.Date(2022, time.March, 4, 3, 0, 0, 0, time.UTC), false},  // Friday, after Thursday night
+		{time.Date(2022, time.March, 2, 12, 0, 0, 0, time.UTC), true},
+		{time.Date(2022, time.March, 2, 13, 30, 0, 0, time.UTC), false},
+		// 20:30 in UTC+8 is 12:30 in UTC.
+		{time.Date(2022, time.March, 2, 20, 30, 0, 0, time.FixedZone("", 8*60*60)), true},
+	} {
+		now = test.now
+		if actual := matcher.Apply(&routing_session.Context{}); actual != test.output {
+			t.Error("at ", test.now, ": expected ", test.output, ", but got ", actual)
+		}
+	}
+
+	for _, schedule := range []*router.Schedule{
+		{},
+		{Window: []*router.Schedule_Window{{Start: 24 * 60}}},
+		{Window: []*router.Schedule_Window{{Weekday: []uint32{7}}}},
+		{Window: []*router.Schedule_Window{{}}, Timezone: "Nowhere/Unknown"},
+	} {
+		if _, err := router.NewScheduleMatcher(schedule, nil); err == nil {
+			t.Error("expected error for schedule ", schedule)
+		}
+	}
+}
@@ -6,6 +6,7 @@ package router
 import (
 	"context"
 	"encoding/json"
+	"strconv"
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
@@ -13,6 +14,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/features/outbound"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/v5cfg"
 )
 
@@ -20,11 +22,18 @@ type Rule struct {
 	Tag       string
 	Balancer  *Balancer
 	Condition Condition
+
+	// Index is the position of the rule in the config.
+	Index int
+	// RuleTag is the optional name of the rule.
+	RuleTag string
+
+	hits stats.Counter
 }
 
-func (r *Rule) GetTag() (string, error) {
+func (r *Rule) GetTag(ctx routing.Context) (string, error) {
 	if r.Balancer != nil {
-		return r.Balancer.PickOutbound()
+		return r.Balancer.PickOutbound(ctx)
 	}
 	return r.Tag, nil
 }
@@ -34,7 +43,32 @@ func (r *Rule) Apply(ctx routing.Context) bool {
 	return r.Condition.Apply(ctx)
 }
 
+// String returns the position and tag of the rule, e.g. "rule[3](direct-cn)".
+func (r *Rule) String() string {
+	name := "rule[" + strconv.Itoa(r.Index) + "]"
+	if r.RuleTag != "" {
+		name += "(" + r.RuleTag + ")"
+	}
+	return name
+}
+
+// ExplainMismatch returns why the rule does not match the routing context, or
+// an empty string if it does.
+func (r *Rule) ExplainMismatch(ctx routing.Context) string {
+	return explainMismatch(r.Condition, ctx)
+}
+
+// ExplainMatch describes the rule, and which of its nested rules, matched the
+// routing context, e.g. "rule[3].logical.rule[1]".
+func (r *Rule) ExplainMatch(ctx routing.Context) string {
+	return "rule[" + strconv.Itoa(r.Index) + "]" + explainMatch(r.Condition, ctx)
+}
+
 func (rr *RoutingRule) BuildCondition() (Condition, error) {
+	return rr.buildCondition(nil)
+}
+
+func (rr *RoutingRule) buildCondition(ruleSets map[string]*RuleSetMatcher) (Condition, error) {
 	conds := NewConditionChan()
 
 	if len(rr.Domain) > 0 {
@@ -117,6 +151,42 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 		conds.Add(NewWifiSSIDMatcher(rr.WifiSsidList))
 	}
 
+	if len(rr.ProcessName) > 0 {
+		conds.Add(NewProcessNameMatcher(rr.ProcessName))
+	}
+
+	if len(rr.ProcessPath) > 0 {
+		conds.Add(NewProcessPathMatcher(rr.ProcessPath))
+	}
+
+	if len(rr.RuleSet) > 0 {
+		cond := make(RuleSetCondition, 0, len(rr.RuleSet))
+		for _, tag := range rr.RuleSet {
+			ruleSet, found := ruleSets[tag]
+			if !found {
+				return nil, newError("rule set ", tag, " not found")
+			}
+			cond = append(cond, ruleSet)
+		}
+		conds.Add(cond)
+	}
+
+	if rr.Schedule != nil {
+		cond, err := NewScheduleMatcher(rr.Schedule, nil)
+		if err != nil {
+			return nil, err
+		}
+		conds.Add(cond)
+	}
+
+	if rr.Logical != nil {
+		cond, err := rr.Logical.buildCondition(ruleSets)
+		if err != nil {
+			return nil, err
+		}
+		conds.Add(cond)
+	}
+
 	if conds.Len() == 0 {
 		return nil, newError("this rule has no effective fields").AtWarning()
 	}
@@ -124,6 +194,21 @@ func (rr *RoutingRule) BuildCondition() (Condition, error) {
 	return conds, nil
 }
 
+func (lr *LogicalRule) buildCondition(ruleSets map[string]*RuleSetMatcher) (Condition, error) {
+	if len(lr.Rule) == 0 {
+		return nil, newError("empty logical rule")
+	}
+	conds := make([]Condition, 0, len(lr.Rule))
+	for _, rule := range lr.Rule {
+		cond, err := rule.buildCondition(ruleSets)
+		if err != nil {
+			return nil, newError("failed to build nested rule").Base(err)
+		}
+		conds = append(conds, cond)
+	}
+	return NewLogicalMatcher(lr.Mode, conds, lr.Invert), nil
+}
+
 // Build builds the balancing rule
 func (br *BalancingRule) Build(ohm outbound.Manager, dispatcher routing.Dispatcher) (*Balancer, error) {
 	switch br.Strategy {
@@ -156,6 +241,66 @@ func (br *BalancingRule) Build(ohm outbound.Manager, dispatcher routing.Dispatch
 			ohm:       ohm, fallbackTag: br.FallbackTag,
 			strategy: leastLoadStrategy,
 		}, nil
+	case "consistenthash":
+		i, err := serial.GetInstanceOf(br.StrategySettings)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := i.(*StrategyConsistentHashConfig)
+		if !ok {
+			return nil, newError("not a StrategyConsistentHashConfig").AtError()
+		}
+		return &Balancer{
+			selectors: br.OutboundSelector,
+			ohm:       ohm, fallbackTag: br.FallbackTag,
+			strategy: NewConsistentHashStrategy(s),
+		}, nil
+	case "fallback":
+		i, err := serial.GetInstanceOf(br.StrategySettings)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := i.(*StrategyFallbackConfig)
+		if !ok {
+			return nil, newError("not a StrategyFallbackConfig").AtError()
+		}
+		fallbackStrategy := NewFallbackStrategy(s, br.OutboundSelector)
+		if notifier, ok := ohm.(outbound.ResultNotifier); ok {
+			notifier.AddResultListener(fallbackStrategy)
+		}
+		return &Balancer{
+			selectors: br.OutboundSelector,
+			ohm:       ohm, fallbackTag: br.FallbackTag,
+			strategy: fallbackStrategy,
+		}, nil
+	case "roundrobin":
+		i, err := serial.GetInstanceOf(br.StrategySettings)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := i.(*StrategyRoundRobinConfig)
+		if !ok {
+			return nil, newError("not a StrategyRoundRobinConfig").AtError()
+		}
+		return &Balancer{
+			selectors: br.OutboundSelector,
+			ohm:       ohm, fallbackTag: br.FallbackTag,
+			strategy: NewRoundRobinStrategy(s),
+		}, nil
+	case "weighted":
+		i, err := serial.GetInstanceOf(br.StrategySettings)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := i.(*StrategyWeightedConfig)
+		if !ok {
+			return nil, newError("not a StrategyWeightedConfig").AtError()
+		}
+		return &Balancer{
+			selectors: br.OutboundSelector,
+			ohm:       ohm, fallbackTag: br.FallbackTag,
+			strategy: NewWeightedStrategy(s),
+		}, nil
 	case "random":
 		fallthrough
 	case "":
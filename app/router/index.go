@@ -0,0 +1,241 @@
+package router
+
+import (
+	"math/bits"
+	"sort"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/strmatcher"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+)
+
+// ruleBitmap is a set of rule indices.
+type ruleBitmap []uint64
+
+func newRuleBitmap(size int) ruleBitmap {
+	return make(ruleBitmap, (size+63)/64)
+}
+
+func (b ruleBitmap) set(i int) {
+	b[i/64] |= 1 << uint(i%64)
+}
+
+func (b ruleBitmap) clone() ruleBitmap {
+	return append(ruleBitmap(nil), b...)
+}
+
+// or adds the rules in other to b.
+func (b ruleBitmap) or(other ruleBitmap) {
+	for i := range b {
+		b[i] |= other[i]
+	}
+}
+
+// and keeps only the rules in both b and other.
+func (b ruleBitmap) and(other ruleBitmap) {
+	for i := range b {
+		b[i] &= other[i]
+	}
+}
+
+// next returns the first rule index in b no less than i, or -1 if there is
+// none.
+func (b ruleBitmap) next(i int) int {
+	for w := i / 64; w < len(b); w++ {
+		word := b[w]
+		if w == i/64 {
+			word &= ^uint64(0) << uint(i%64)
+		}
+		if word != 0 {
+			return w*64 + bits.TrailingZeros64(word)
+		}
+	}
+	return -1
+}
+
+// ruleIndex narrows down the rules that may match a routing context, by the
+// fields that can be looked up directly: inbound tag, network, target port and
+// target domain. Candidates are still checked in order with their full
+// conditions, so the first match is always the same as in a linear scan.
+type ruleIndex struct {
+	size int
+
+	anyInboundTag ruleBitmap
+	inboundTag    map[string]ruleBitmap
+
+	network [8]ruleBitmap
+
+	// portStart is sorted. Rules in portRules[i] may match any port from
+	// portStart[i] up to, but not including, portStart[i+1].
+	portStart []net.Port
+	portRules []ruleBitmap
+
+	// Rules whose domains are not indexed, either because they have none or
+	// because they can not be looked up in domain.
+	anyDomain ruleBitmap
+	domain    *strmatcher.MphMatcherGroup
+	hasDomain bool
+}
+
+func newRuleIndex(rules []*RoutingRule) *ruleIndex {
+	size := len(rules)
+	index := &ruleIndex{
+		size:          size,
+		anyInboundTag: newRuleBitmap(size),
+		inboundTag:    make(map[string]ruleBitmap),
+		anyDomain:     newRuleBitmap(size),
+		domain:        strmatcher.NewMphMatcherGroup(),
+	}
+	for n := range index.network {
+		index.network[n] = newRuleBitmap(size)
+	}
+
+	for i, rule := range rules {
+		index.addInboundTag(i, rule)
+		index.addNetwork(i, rule)
+		index.addDomain(i, rule)
+	}
+	index.buildPorts(rules)
+	if index.hasDomain {
+		index.domain.Build()
+	}
+	return index
+}
+
+func (index *ruleIndex) addInboundTag(i int, rule *RoutingRule) {
+	if len(rule.InboundTag) == 0 {
+		index.anyInboundTag.set(i)
+		return
+	}
+	for _, tag := range rule.InboundTag {
+		if len(tag) == 0 {
+			continue
+		}
+		rules, found := index.inboundTag[tag]
+		if !found {
+			rules = newRuleBitmap(index.size)
+			index.inboundTag[tag] = rules
+		}
+		rules.set(i)
+	}
+}
+
+func (index *ruleIndex) addNetwork(i int, rule *RoutingRule) {
+	var networks []net.Network
+	switch {
+	case len(rule.Networks) > 0:
+		networks = rule.Networks
+	case rule.NetworkList != nil:
+		networks = rule.NetworkList.Network
+	default:
+		for n := range index.network {
+			index.network[n].set(i)
+		}
+		return
+	}
+	for _, n := range networks {
+		if int(n) < len(index.network) {
+			index.network[n].set(i)
+		}
+	}
+}
+
+// addDomain indexes the domains of rule if all of them are full or domain
+// matches, which are looked up the same way as in DomainMatcher.
+func (index *ruleIndex) addDomain(i int, rule *RoutingRule) {
+	if len(rule.Domain) == 0 || rule.DomainMatcher == "linear" {
+		index.anyDomain.set(i)
+		return
+	}
+	matchers := make([]strmatcher.Matcher, 0, len(rule.Domain))
+	for _, domain := range rule.Domain {
+		matcher, err := domainToMatcher(domain)
+		if err != nil {
+			index.anyDomain.set(i)
+			return
+		}
+		switch matcher.(type) {
+		case strmatcher.FullMatcher, strmatcher.DomainMatcher:
+			matchers = append(matchers, matcher)
+		default:
+			index.anyDomain.set(i)
+			return
+		}
+	}
+	for _, matcher := range matchers {
+		switch matcher := matcher.(type) {
+		case strmatcher.FullMatcher:
+			index.domain.AddFullMatcher(matcher, uint32(i))
+		case strmatcher.DomainMatcher:
+			index.domain.AddDomainMatcher(matcher, uint32(i))
+		}
+	}
+	index.hasDomain = true
+}
+
+func (index *ruleIndex) buildPorts(rules []*RoutingRule) {
+	portLists := make([]net.MemoryPortList, len(rules))
+	starts := map[net.Port]bool{0: true}
+	for i, rule := range rules {
+		switch {
+		case rule.PortList != nil:
+			portLists[i] = net.PortListFromProto(rule.PortList)
+		case rule.PortRange != nil:
+			portLists[i] = net.PortListFromProto(&net.PortList{Range: []*net.PortRange{rule.PortRange}})
+		default:
+			continue
+		}
+		for _, r := range portLists[i] {
+			starts[r.From] = true
+			if r.To < 65535 {
+				starts[r.To+1] = true
+			}
+		}
+	}
+
+	index.portStart = make([]net.Port, 0, len(starts))
+	for port := range starts {
+		index.portStart = append(index.portStart, port)
+	}
+	sort.Slice(index.portStart, func(i, j int) bool {
+		return index.portStart[i] < index.portStart[j]
+	})
+
+	index.portRules = make([]ruleBitmap, len(index.portStart))
+	for s, port := range index.portStart {
+		rules := newRuleBitmap(index.size)
+		for i, portList := range portLists {
+			if portList == nil || portList.Contains(port) {
+				rules.set(i)
+			}
+		}
+		index.portRules[s] = rules
+	}
+}
+
+// candidates returns the rules that may match ctx.
+func (index *ruleIndex) candidates(ctx routing.Context) ruleBitmap {
+	result := index.anyInboundTag.clone()
+	if rules, found := index.inboundTag[ctx.GetInboundTag()]; found {
+		result.or(rules)
+	}
+
+	if n := int(ctx.GetNetwork()); n < len(index.network) {
+		result.and(index.network[n])
+	}
+
+	port := ctx.GetTargetPort()
+	s := sort.Search(len(index.portStart), func(i int) bool {
+		return index.portStart[i] > port
+	}) - 1
+	result.and(index.portRules[s])
+
+	domains := index.anyDomain.clone()
+	if domain := ctx.GetTargetDomain(); len(domain) > 0 && index.hasDomain {
+		for _, i := range index.domain.Match(domain) {
+			domains.set(int(i))
+		}
+	}
+	result.and(domains)
+	return result
+}
@@ -0,0 +1,139 @@
+package router_test
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	. "github.com/v2fly/v2ray-core/v5/app/router"
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
+)
+
+// generateRules returns count rules mixing the fields that are indexed with
+// those that are not.
+func generateRules(rnd *rand.Rand, count int) []*RoutingRule {
+	domainTypes := []routercommon.Domain_Type{
+		routercommon.Domain_RootDomain,
+		routercommon.Domain_RootDomain,
+		routercommon.Domain_Full,
+		routercommon.Domain_Plain,
+		routercommon.Domain_Regex,
+	}
+	rules := make([]*RoutingRule, 0, count)
+	for i := 0; i < count; i++ {
+		rule := &RoutingRule{
+			TargetTag: &RoutingRule_Tag{Tag: "rule" + strconv.Itoa(i)},
+		}
+		if rnd.Intn(3) == 0 {
+			rule.InboundTag = []string{"in" + strconv.Itoa(rnd.Intn(4))}
+		}
+		if rnd.Intn(3) == 0 {
+			rule.Networks = []net.Network{net.Network(2 + rnd.Intn(2))}
+		}
+		if rnd.Intn(3) == 0 {
+			from := uint32(rnd.Intn(1000))
+			rule.PortList = &net.PortList{Range: []*net.PortRange{{From: from, To: from + uint32(rnd.Intn(100))}}}
+		}
+		switch rnd.Intn(3) {
+		case 0:
+			domainType := domainTypes[rnd.Intn(len(domainTypes))]
+			for j := 0; j < 20; j++ {
+				value := "d" + strconv.Itoa(rnd.Intn(200)) + ".example"
+				if domainType == routercommon.Domain_Regex {
+					value = "^d" + strconv.Itoa(rnd.Intn(200)) + `\.`
+				}
+				rule.Domain = append(rule.Domain, &routercommon.Domain{Type: domainType, Value: value})
+			}
+		case 1:
+			rule.Cidr = []*routercommon.CIDR{{Ip: []byte{10, byte(rnd.Intn(256)), 0, 0}, Prefix: 16}}
+		}
+		if rule.InboundTag == nil && rule.Networks == nil && rule.PortList == nil && rule.Domain == nil && rule.Cidr == nil {
+			rule.Protocol = []string{"tls"}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func generateContext(rnd *rand.Rand) routing.Context {
+	var address net.Address
+	if rnd.Intn(2) == 0 {
+		address = net.DomainAddress("www.d" + strconv.Itoa(rnd.Intn(250)) + ".example")
+	} else {
+		address = net.IPAddress([]byte{10, byte(rnd.Intn(256)), 1, 1})
+	}
+	dest := net.Destination{
+		Network: net.Network(2 + rnd.Intn(2)),
+		Address: address,
+		Port:    net.Port(rnd.Intn(1200)),
+	}
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Tag: "in" + strconv.Itoa(rnd.Intn(5))})
+	ctx = session.ContextWithOutbound(ctx, &session.Outbound{Target: dest})
+	return routing_session.AsRoutingContext(ctx)
+}
+
+func pickTag(r *Router, ctx routing.Context) string {
+	route, err := r.PickRoute(ctx)
+	if err != nil {
+		return ""
+	}
+	return route.GetOutboundTag()
+}
+
+func TestRuleIndex(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	rules := generateRules(rnd, 300)
+
+	indexed := new(Router)
+	common.Must(indexed.Init(context.TODO(), &Config{Rule: rules}, nil, nil, nil))
+	linear := new(Router)
+	common.Must(linear.Init(context.TODO(), &Config{Rule: rules, DisableRuleIndex: true}, nil, nil, nil))
+
+	var matched int
+	for i := 0; i < 10000; i++ {
+		ctx := generateContext(rnd)
+		expected := pickTag(linear, ctx)
+		if tag := pickTag(indexed, ctx); tag != expected {
+			t.Fatal("expected '", expected, "', but got '", tag, "' for ", ctx.GetInboundTag(), " ", ctx.GetNetwork(), " ", ctx.GetTargetDomain(), ctx.GetTargetIPs(), ":", ctx.GetTargetPort())
+		}
+		if expected != "" {
+			matched++
+		}
+	}
+	if matched == 0 {
+		t.Error("no context matched any rule")
+	}
+}
+
+func benchmarkPickRoute(b *testing.B, disableRuleIndex bool) {
+	rnd := rand.New(rand.NewSource(1))
+	r := new(Router)
+	common.Must(r.Init(context.TODO(), &Config{
+		Rule:             generateRules(rnd, 300),
+		DisableRuleIndex: disableRuleIndex,
+	}, nil, nil, nil))
+
+	contexts := make([]routing.Context, 1024)
+	for i := range contexts {
+		contexts[i] = generateContext(rnd)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = r.PickRoute(contexts[i%len(contexts)])
+	}
+}
+
+func BenchmarkPickRouteLinear(b *testing.B) {
+	benchmarkPickRoute(b, true)
+}
+
+func BenchmarkPickRouteIndexed(b *testing.B) {
+	benchmarkPickRoute(b, false)
+}
@@ -4,15 +4,18 @@ package router
 
 import (
 	"context"
+	"math/rand"
 
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/platform"
 	"github.com/v2fly/v2ray-core/v5/features/dns"
 	"github.com/v2fly/v2ray-core/v5/features/outbound"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
 	routing_dns "github.com/v2fly/v2ray-core/v5/features/routing/dns"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/geodata"
 )
@@ -21,8 +24,12 @@ import (
 type Router struct {
 	domainStrategy DomainStrategy
 	rules          []*Rule
+	index          *ruleIndex
 	balancers      map[string]*Balancer
+	ruleSets       map[string]*RuleSetMatcher
 	dns            dns.Client
+
+	traceSampleRate float32
 }
 
 // Route is an implementation of routing.Route.
@@ -30,12 +37,14 @@ type Route struct {
 	routing.Context
 	outboundGroupTags []string
 	outboundTag       string
+	rule              *Rule
 }
 
 // Init initializes the Router.
 func (r *Router) Init(ctx context.Context, config *Config, d dns.Client, ohm outbound.Manager, dispatcher routing.Dispatcher) error {
 	r.domainStrategy = config.DomainStrategy
 	r.dns = d
+	r.traceSampleRate = config.TraceSampleRate
 
 	r.balancers = make(map[string]*Balancer, len(config.BalancingRule))
 	for _, rule := range config.BalancingRule {
@@ -47,15 +56,29 @@ func (r *Router) Init(ctx context.Context, config *Config, d dns.Client, ohm out
 		r.balancers[rule.Tag] = balancer
 	}
 
+	r.ruleSets = make(map[string]*RuleSetMatcher, len(config.RuleSet))
+	for _, ruleSet := range config.RuleSet {
+		if _, found := r.ruleSets[ruleSet.Tag]; found {
+			return newError("duplicate rule set tag ", ruleSet.Tag)
+		}
+		matcher, err := NewRuleSetMatcher(ctx, ruleSet)
+		if err != nil {
+			return err
+		}
+		r.ruleSets[ruleSet.Tag] = matcher
+	}
+
 	r.rules = make([]*Rule, 0, len(config.Rule))
-	for _, rule := range config.Rule {
-		cond, err := rule.BuildCondition()
+	for i, rule := range config.Rule {
+		cond, err := rule.buildCondition(r.ruleSets)
 		if err != nil {
 			return err
 		}
 		rr := &Rule{
 			Condition: cond,
 			Tag:       rule.GetTag(),
+			Index:     i,
+			RuleTag:   rule.RuleTag,
 		}
 		btag := rule.GetBalancingTag()
 		if len(btag) > 0 {
@@ -68,20 +91,50 @@ func (r *Router) Init(ctx context.Context, config *Config, d dns.Client, ohm out
 		r.rules = append(r.rules, rr)
 	}
 
+	if !config.DisableRuleIndex {
+		r.index = newRuleIndex(config.Rule)
+	}
+
 	return nil
 }
 
+// initRuleCounters registers hit counters of tagged rules.
+func (r *Router) initRuleCounters(sm stats.Manager) {
+	for _, rule := range r.rules {
+		if rule.RuleTag == "" {
+			continue
+		}
+		name := "router>>>rule>>>" + rule.RuleTag + ">>>hits"
+		if c, _ := stats.GetOrRegisterCounter(sm, name); c != nil {
+			rule.hits = c
+		}
+	}
+}
+
 // PickRoute implements routing.Router.
 func (r *Router) PickRoute(ctx routing.Context) (routing.Route, error) {
+	return r.pickRoute(ctx, true)
+}
+
+// TestRoute returns the same route as PickRoute, but does not count the hit
+// of its rule, as the route is not taken.
+func (r *Router) TestRoute(ctx routing.Context) (routing.Route, error) {
+	return r.pickRoute(ctx, false)
+}
+
+func (r *Router) pickRoute(ctx routing.Context, countHit bool) (routing.Route, error) {
 	rule, ctx, err := r.pickRouteInternal(ctx)
 	if err != nil {
 		return nil, err
 	}
-	tag, err := rule.GetTag()
+	if countHit && rule.hits != nil {
+		rule.hits.Add(1)
+	}
+	tag, err := rule.GetTag(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &Route{Context: ctx, outboundTag: tag}, nil
+	return &Route{Context: ctx, outboundTag: tag, rule: rule}, nil
 }
 
 func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context, error) {
@@ -94,10 +147,17 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 		ctx = routing_dns.ContextWithDNSClient(ctx, r.dns)
 	}
 
-	for _, rule := range r.rules {
-		if rule.Apply(ctx) {
-			return rule, ctx, nil
-		}
+	trace := r.traceSampleRate > 0 && rand.Float32() < r.traceSampleRate
+
+	// Resolving IPs does not change the fields that the index is built on, so
+	// the candidates are valid for both passes.
+	var candidates ruleBitmap
+	if r.index != nil && !trace {
+		candidates = r.index.candidates(ctx)
+	}
+
+	if rule := r.applyRules(ctx, candidates, trace); rule != nil {
+		return rule, ctx, nil
 	}
 
 	if r.domainStrategy != DomainStrategy_IpIfNonMatch || len(ctx.GetTargetDomain()) == 0 || skipDNSResolve {
@@ -107,26 +167,83 @@ func (r *Router) pickRouteInternal(ctx routing.Context) (*Rule, routing.Context,
 	ctx = routing_dns.ContextWithDNSClient(ctx, r.dns)
 
 	// Try applying rules again if we have IPs.
-	for _, rule := range r.rules {
-		if rule.Apply(ctx) {
-			return rule, ctx, nil
-		}
+	if rule := r.applyRules(ctx, candidates, trace); rule != nil {
+		return rule, ctx, nil
 	}
 
 	return nil, ctx, common.ErrNoClue
 }
 
+// applyRules returns the first rule that matches ctx. Only rules in
+// candidates are checked, unless it is nil. If trace is set, every rule
+// checked is logged.
+func (r *Router) applyRules(ctx routing.Context, candidates ruleBitmap, trace bool) *Rule {
+	if trace {
+		return r.traceRules(ctx)
+	}
+	if candidates == nil {
+		for _, rule := range r.rules {
+			if rule.Apply(ctx) {
+				return rule
+			}
+		}
+		return nil
+	}
+	for i := candidates.next(0); i >= 0; i = candidates.next(i + 1) {
+		if rule := r.rules[i]; rule.Apply(ctx) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (r *Router) traceRules(ctx routing.Context) *Rule {
+	target := ctx.GetTargetDomain()
+	if target == "" {
+		if ips := ctx.GetTargetIPs(); len(ips) > 0 {
+			target = ips[0].String()
+		}
+	}
+	target = "[" + ctx.GetInboundTag() + "] " + target + ":" + ctx.GetTargetPort().String()
+
+	for _, rule := range r.rules {
+		if reason := rule.ExplainMismatch(ctx); reason != "" {
+			newError("trace ", target, ": ", rule, " skipped, ", reason).AtInfo().WriteToLog()
+			continue
+		}
+		newError("trace ", target, ": ", rule, " matched at ", rule.ExplainMatch(ctx)).AtInfo().WriteToLog()
+		return rule
+	}
+	newError("trace ", target, ": no rule matched").AtInfo().WriteToLog()
+	return nil
+}
+
 // Start implements common.Runnable.
 func (r *Router) Start() error {
+	for _, ruleSet := range r.ruleSets {
+		if err := ruleSet.Start(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // Close implements common.Closable.
 func (r *Router) Close() error {
+	var errs []error
+	for _, ruleSet := range r.ruleSets {
+		errs = append(errs, ruleSet.Close())
+	}
+	if err := errors.Combine(errs...); err != nil {
+		return newError("failed to close rule sets").Base(err)
+	}
+
 	// TODO: fix router leak
 	r.balancers = nil
+	r.ruleSets = nil
 	r.dns = nil
 	r.rules = nil
+	r.index = nil
 	return nil
 }
 
@@ -145,11 +262,31 @@ func (r *Route) GetOutboundTag() string {
 	return r.outboundTag
 }
 
+// GetRuleTag implements routing.Route.
+func (r *Route) GetRuleTag() string {
+	if r.rule == nil {
+		return ""
+	}
+	return r.rule.RuleTag
+}
+
+// GetMatchedRule implements routing.Route.
+func (r *Route) GetMatchedRule() string {
+	if r.rule == nil {
+		return ""
+	}
+	return r.rule.ExplainMatch(r.Context)
+}
+
 func init() {
 	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
 		r := new(Router)
-		if err := core.RequireFeatures(ctx, func(d dns.Client, ohm outbound.Manager, dispatcher routing.Dispatcher) error {
-			return r.Init(ctx, config.(*Config), d, ohm, dispatcher)
+		if err := core.RequireFeatures(ctx, func(d dns.Client, ohm outbound.Manager, dispatcher routing.Dispatcher, sm stats.Manager) error {
+			if err := r.Init(ctx, config.(*Config), d, ohm, dispatcher); err != nil {
+				return err
+			}
+			r.initRuleCounters(sm)
+			return nil
 		}); err != nil {
 			return nil, err
 		}
@@ -252,6 +389,8 @@ func init() {
 			rule.UserEmail = v.UserEmail
 			rule.InboundTag = v.InboundTag
 			rule.DomainMatcher = v.DomainMatcher
+			rule.RuleSet = v.RuleSet
+			rule.RuleTag = v.RuleTag
 			switch s := v.TargetTag.(type) {
 			case *SimplifiedRoutingRule_Tag:
 				rule.TargetTag = &RoutingRule_Tag{s.Tag}
@@ -265,6 +404,10 @@ func init() {
 			DomainStrategy: simplifiedConfig.DomainStrategy,
 			Rule:           routingRules,
 			BalancingRule:  simplifiedConfig.BalancingRule,
+			RuleSet:        simplifiedConfig.RuleSet,
+
+			DisableRuleIndex: simplifiedConfig.DisableRuleIndex,
+			TraceSampleRate:  simplifiedConfig.TraceSampleRate,
 		}
 		return common.CreateObject(ctx, fullConfig)
 	}))
@@ -5,14 +5,23 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/dispatcher"
+	"github.com/v2fly/v2ray-core/v5/app/proxyman"
+	_ "github.com/v2fly/v2ray-core/v5/app/proxyman/outbound"
 	. "github.com/v2fly/v2ray-core/v5/app/router"
 	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/app/stats"
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/features/outbound"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
 	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
+	feature_stats "github.com/v2fly/v2ray-core/v5/features/stats"
 	"github.com/v2fly/v2ray-core/v5/testing/mocks"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 type mockOutboundManager struct {
@@ -246,3 +255,53 @@ func TestIPIfNonMatchIP(t *testing.T) {
 		t.Error("expect tag 'test', bug actually ", tag)
 	}
 }
+
+func TestRuleHitCounter(t *testing.T) {
+	config := &core.Config{
+		App: []*anypb.Any{
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+			serial.ToTypedMessage(&stats.Config{}),
+			serial.ToTypedMessage(&Config{
+				Rule: []*RoutingRule{
+					{
+						TargetTag: &RoutingRule_Tag{Tag: "direct"},
+						RuleTag:   "lan",
+						Cidr:      []*routercommon.CIDR{{Ip: []byte{192, 168, 0, 0}, Prefix: 16}},
+					},
+					{
+						TargetTag: &RoutingRule_Tag{Tag: "proxy"},
+						Networks:  []net.Network{net.Network_TCP},
+					},
+				},
+			}),
+		},
+	}
+
+	v, err := core.New(config)
+	common.Must(err)
+	r := v.GetFeature(routing.RouterType()).(routing.Router)
+
+	for _, dest := range []net.Destination{
+		net.TCPDestination(net.ParseAddress("192.168.1.1"), 80),
+		net.TCPDestination(net.ParseAddress("192.168.1.2"), 80),
+		net.TCPDestination(net.ParseAddress("1.1.1.1"), 80),
+	} {
+		ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{Target: dest})
+		_, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+		common.Must(err)
+	}
+
+	// Routes only tested through the API are not counted.
+	ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{Target: net.TCPDestination(net.ParseAddress("192.168.1.3"), 80)})
+	_, err = r.(*Router).TestRoute(routing_session.AsRoutingContext(ctx))
+	common.Must(err)
+
+	counter := v.GetFeature(feature_stats.ManagerType()).(feature_stats.Manager).GetCounter("router>>>rule>>>lan>>>hits")
+	if counter == nil {
+		t.Fatal("counter not registered")
+	}
+	if hits := counter.Value(); hits != 2 {
+		t.Error("expected 2 hits, but got ", hits)
+	}
+}
@@ -0,0 +1,390 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/task"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tagged"
+	"google.golang.org/protobuf/proto"
+)
+
+const ruleSetDownloadTimeout = time.Minute
+
+// ruleSetMatchers is the content of a rule set. It is replaced as a whole on
+// reload, so that a routing decision never sees a half updated rule set.
+type ruleSetMatchers struct {
+	domain *DomainMatcher
+	ip     *MultiGeoIPMatcher
+}
+
+// RuleSetMatcher matches the target of a connection against a rule set.
+type RuleSetMatcher struct {
+	config     *RuleSet
+	httpClient *http.Client
+	refresh    *task.Periodic
+
+	matchers atomic.Value
+
+	access       sync.Mutex
+	modTime      time.Time
+	etag         string
+	lastModified string
+}
+
+// NewRuleSetMatcher creates a RuleSetMatcher. Rule sets from files are loaded
+// immediately, while rule sets from URLs are downloaded after Start.
+func NewRuleSetMatcher(ctx context.Context, config *RuleSet) (*RuleSetMatcher, error) {
+	if config.Tag == "" {
+		return nil, newError("empty rule set tag")
+	}
+	if (config.Path == "") == (config.Url == "") {
+		return nil, newError("exactly one of path and url must be set for rule set ", config.Tag)
+	}
+
+	m := &RuleSetMatcher{
+		config: config,
+	}
+	m.matchers.Store(&ruleSetMatchers{})
+
+	if config.Url != "" {
+		m.httpClient = newRuleSetHTTPClient(ctx, config.DownloadOutboundTag)
+	} else if err := m.loadFile(); err != nil {
+		return nil, err
+	}
+
+	if config.RefreshInterval > 0 {
+		m.refresh = &task.Periodic{
+			Interval: time.Duration(config.RefreshInterval) * time.Second,
+			Execute: func() error {
+				if err := m.Reload(); err != nil {
+					newError("failed to reload rule set ", config.Tag).Base(err).AtWarning().WriteToLog()
+				}
+				return nil
+			},
+		}
+	}
+	return m, nil
+}
+
+func newRuleSetHTTPClient(ctx context.Context, tag string) *http.Client {
+	if tag == "" {
+		return &http.Client{Timeout: ruleSetDownloadTimeout}
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			DialContext: func(_ context.Context, network, addr string) (net.Conn, error) {
+				dest, err := net.ParseDestination(network + ":" + addr)
+				if err != nil {
+					return nil, err
+				}
+				return tagged.Dialer(ctx, dest, tag)
+			},
+		},
+		Timeout: ruleSetDownloadTimeout,
+	}
+}
+
+// Start implements common.Runnable.
+func (m *RuleSetMatcher) Start() error {
+	if m.refresh != nil {
+		if m.config.Url != "" {
+			// The first download may go through an outbound that is not ready
+			// yet, so it must not block starting the instance.
+			go m.refresh.Start()
+			return nil
+		}
+		return m.refresh.Start()
+	}
+	if m.config.Url != "" {
+		go func() {
+			if err := m.Reload(); err != nil {
+				newError("failed to load rule set ", m.config.Tag).Base(err).AtWarning().WriteToLog()
+			}
+		}()
+	}
+	return nil
+}
+
+// Close implements common.Closable.
+func (m *RuleSetMatcher) Close() error {
+	if m.refresh != nil {
+		return m.refresh.Close()
+	}
+	return nil
+}
+
+// Reload loads the rule set again if its content has changed, and swaps it
+// in. The current content is kept if loading fails.
+func (m *RuleSetMatcher) Reload() error {
+	if m.config.Url != "" {
+		return m.loadURL()
+	}
+	return m.loadFile()
+}
+
+func (m *RuleSetMatcher) loadFile() error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	info, err := os.Stat(m.config.Path)
+	if err != nil {
+		return newError("failed to read rule set ", m.config.Tag).Base(err)
+	}
+	if info.ModTime().Equal(m.modTime) {
+		return nil
+	}
+	data, err := os.ReadFile(m.config.Path)
+	if err != nil {
+		return newError("failed to read rule set ", m.config.Tag).Base(err)
+	}
+	if err := m.update(data); err != nil {
+		return err
+	}
+	m.modTime = info.ModTime()
+	return nil
+}
+
+func (m *RuleSetMatcher) loadURL() error {
+	m.access.Lock()
+	defer m.access.Unlock()
+
+	req, err := http.NewRequest(http.MethodGet, m.config.Url, nil)
+	if err != nil {
+		return newError("invalid url of rule set ", m.config.Tag).Base(err)
+	}
+	if m.etag != "" {
+		req.Header.Set("If-None-Match", m.etag)
+	}
+	if m.lastModified != "" {
+		req.Header.Set("If-Modified-Since", m.lastModified)
+	}
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return newError("failed to download rule set ", m.config.Tag).Base(err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return nil
+	default:
+		return newError("failed to download rule set ", m.config.Tag, ": unexpected status ", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return newError("failed to download rule set ", m.config.Tag).Base(err)
+	}
+	if err := m.update(data); err != nil {
+		return err
+	}
+	m.etag = resp.Header.Get("ETag")
+	m.lastModified = resp.Header.Get("Last-Modified")
+	return nil
+}
+
+func (m *RuleSetMatcher) update(data []byte) error {
+	var domains []*routercommon.Domain
+	var cidrs []*routercommon.CIDR
+	var err error
+	switch m.config.Format {
+	case RuleSet_Plain:
+		domains, cidrs, err = parsePlainRuleSet(data)
+	case RuleSet_GeoSite:
+		domains, err = parseGeoSiteRuleSet(data, m.config.Code)
+	case RuleSet_GeoIP:
+		cidrs, err = parseGeoIPRuleSet(data, m.config.Code)
+	default:
+		err = newError("unknown format ", m.config.Format)
+	}
+	if err != nil {
+		return newError("failed to parse rule set ", m.config.Tag).Base(err)
+	}
+
+	matchers := new(ruleSetMatchers)
+	if len(domains) > 0 {
+		matchers.domain, err = NewDomainMatcher("mph", domains)
+		if err != nil {
+			return newError("failed to build rule set ", m.config.Tag).Base(err)
+		}
+	}
+	if len(cidrs) > 0 {
+		// Leave the country code empty, so that the matcher is not shared
+		// with, or cached for, other GeoIPs.
+		matchers.ip, err = NewMultiGeoIPMatcher([]*routercommon.GeoIP{{Cidr: cidrs}}, false)
+		if err != nil {
+			return newError("failed to build rule set ", m.config.Tag).Base(err)
+		}
+	}
+	m.matchers.Store(matchers)
+	newError("rule set ", m.config.Tag, " loaded with ", len(domains), " domains and ", len(cidrs), " IP ranges").AtInfo().WriteToLog()
+	return nil
+}
+
+// Apply implements Condition.
+func (m *RuleSetMatcher) Apply(ctx routing.Context) bool {
+	matchers := m.matchers.Load().(*ruleSetMatchers)
+	if matchers.domain != nil && matchers.domain.Apply(ctx) {
+		return true
+	}
+	if matchers.ip != nil && matchers.ip.Apply(ctx) {
+		return true
+	}
+	return false
+}
+
+// RuleSetCondition matches if any of the rule sets matches.
+type RuleSetCondition []*RuleSetMatcher
+
+// Apply implements Condition.
+func (c RuleSetCondition) Apply(ctx routing.Context) bool {
+	for _, m := range c {
+		if m.Apply(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePlainRuleSet(data []byte) ([]*routercommon.Domain, []*routercommon.CIDR, error) {
+	var domains []*routercommon.Domain
+	var cidrs []*routercommon.CIDR
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domain, err := parsePlainDomain(line)
+		if err != nil {
+			return nil, nil, err
+		}
+		if domain != nil {
+			domains = append(domains, domain)
+			continue
+		}
+		cidr, err := parsePlainCIDR(line)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cidr != nil {
+			cidrs = append(cidrs, cidr)
+			continue
+		}
+		if strings.Contains(line, ":") {
+			// geosite:, geoip: and ext: refer to other files, which rule sets
+			// cannot load.
+			return nil, nil, newError("unsupported rule: ", line)
+		}
+		// As in routing rules, other domains are keywords.
+		domains = append(domains, &routercommon.Domain{
+			Type:  routercommon.Domain_Plain,
+			Value: strings.ToLower(line),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return domains, cidrs, nil
+}
+
+func parsePlainDomain(line string) (*routercommon.Domain, error) {
+	if strings.HasPrefix(line, "dotless:") {
+		switch substr := strings.ToLower(line[len("dotless:"):]); {
+		case substr == "":
+			return &routercommon.Domain{Type: routercommon.Domain_Regex, Value: "^[^.]*$"}, nil
+		case !strings.Contains(substr, "."):
+			return &routercommon.Domain{Type: routercommon.Domain_Regex, Value: "^[^.]*" + substr + "[^.]*$"}, nil
+		default:
+			return nil, newError("substr in dotless rule should not contain a dot: ", substr)
+		}
+	}
+	for prefix, domainType := range map[string]routercommon.Domain_Type{
+		"domain:":  routercommon.Domain_RootDomain,
+		"full:":    routercommon.Domain_Full,
+		"keyword:": routercommon.Domain_Plain,
+		"regexp:":  routercommon.Domain_Regex,
+	} {
+		if strings.HasPrefix(line, prefix) {
+			value := line[len(prefix):]
+			if value == "" {
+				return nil, newError("empty value in rule: ", line)
+			}
+			if domainType != routercommon.Domain_Regex {
+				value = strings.ToLower(value)
+			}
+			return &routercommon.Domain{
+				Type:  domainType,
+				Value: value,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// parsePlainCIDR returns nil if line is neither an IP nor a CIDR.
+func parsePlainCIDR(line string) (*routercommon.CIDR, error) {
+	addr, mask, hasMask := strings.Cut(line, "/")
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		if hasMask {
+			return nil, newError("invalid CIDR: ", line)
+		}
+		return nil, nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	bits := uint64(len(ip) * 8)
+	if hasMask {
+		prefix, err := strconv.ParseUint(mask, 10, 32)
+		if err != nil || prefix > bits {
+			return nil, newError("invalid CIDR: ", line)
+		}
+		bits = prefix
+	}
+	return &routercommon.CIDR{
+		Ip:     ip,
+		Prefix: uint32(bits),
+	}, nil
+}
+
+func parseGeoSiteRuleSet(data []byte, code string) ([]*routercommon.Domain, error) {
+	var list routercommon.GeoSiteList
+	if err := proto.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	for _, site := range list.Entry {
+		if strings.EqualFold(site.CountryCode, code) {
+			return site.Domain, nil
+		}
+	}
+	return nil, newError("code not found: ", code)
+}
+
+func parseGeoIPRuleSet(data []byte, code string) ([]*routercommon.CIDR, error) {
+	var list routercommon.GeoIPList
+	if err := proto.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	for _, geoip := range list.Entry {
+		if strings.EqualFold(geoip.CountryCode, code) {
+			return geoip.Cidr, nil
+		}
+	}
+	return nil, newError("code not found: ", code)
+}
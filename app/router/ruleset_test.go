@@ -0,0 +1,188 @@
+package router_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	. "github.com/v2fly/v2ray-core/v5/app/router"
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
+	"github.com/v2fly/v2ray-core/v5/testing/mocks"
+	"google.golang.org/protobuf/proto"
+)
+
+func routeTo(r *Router, dest net.Destination) string {
+	ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{Target: dest})
+	route, err := r.PickRoute(routing_session.AsRoutingContext(ctx))
+	if err != nil {
+		return ""
+	}
+	return route.GetOutboundTag()
+}
+
+func TestRuleSetFileRefresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.txt")
+	common.Must(os.WriteFile(path, []byte("# comment\nv2fly.org\nfull:www.example.com\n10.0.0.0/8\n"), 0o644))
+
+	config := &Config{
+		Rule: []*RoutingRule{
+			{
+				TargetTag: &RoutingRule_Tag{Tag: "matched"},
+				RuleSet:   []string{"file"},
+			},
+		},
+		RuleSet: []*RuleSet{
+			{
+				Tag:             "file",
+				Path:            path,
+				RefreshInterval: 1,
+			},
+		},
+	}
+
+	mockCtl := gomock.NewController(t)
+	defer mockCtl.Finish()
+
+	r := new(Router)
+	common.Must(r.Init(context.TODO(), config, mocks.NewDNSClient(mockCtl), nil, nil))
+	common.Must(r.Start())
+	defer r.Close()
+
+	for dest, expected := range map[net.Destination]string{
+		net.TCPDestination(net.DomainAddress("www.v2fly.org"), 443):   "matched",
+		net.TCPDestination(net.DomainAddress("www.example.com"), 443): "matched",
+		net.TCPDestination(net.DomainAddress("example.com"), 443):     "",
+		net.TCPDestination(net.ParseAddress("10.1.2.3"), 443):         "matched",
+		net.TCPDestination(net.ParseAddress("192.168.1.1"), 443):      "",
+	} {
+		if tag := routeTo(r, dest); tag != expected {
+			t.Error(dest, ": expected '", expected, "', but got '", tag, "'")
+		}
+	}
+
+	common.Must(os.WriteFile(path, []byte("keyword:example\n"), 0o644))
+	modTime := time.Now().Add(time.Minute)
+	common.Must(os.Chtimes(path, modTime, modTime))
+	time.Sleep(2 * time.Second)
+
+	for dest, expected := range map[net.Destination]string{
+		net.TCPDestination(net.DomainAddress("www.v2fly.org"), 443): "",
+		net.TCPDestination(net.DomainAddress("example.com"), 443):   "matched",
+		net.TCPDestination(net.ParseAddress("10.1.2.3"), 443):       "",
+	} {
+		if tag := routeTo(r, dest); tag != expected {
+			t.Error(dest, ": expected '", expected, "', but got '", tag, "'")
+		}
+	}
+}
+
+func TestRuleSetPlainSyntax(t *testing.T) {
+	dir := t.TempDir()
+	newRouter := func(content string) (*Router, error) {
+		path := filepath.Join(dir, "rules.txt")
+		common.Must(os.WriteFile(path, []byte(content), 0o644))
+		config := &Config{
+			Rule: []*RoutingRule{
+				{
+					TargetTag: &RoutingRule_Tag{Tag: "matched"},
+					RuleSet:   []string{"file"},
+				},
+			},
+			RuleSet: []*RuleSet{
+				{
+					Tag:  "file",
+					Path: path,
+				},
+			},
+		}
+		mockCtl := gomock.NewController(t)
+		t.Cleanup(mockCtl.Finish)
+		r := new(Router)
+		return r, r.Init(context.TODO(), config, mocks.NewDNSClient(mockCtl), nil, nil)
+	}
+
+	r, err := newRouter("v2fly\ndotless:local\n")
+	common.Must(err)
+	for dest, expected := range map[net.Destination]string{
+		// Bare domains are keywords, as in routing rules.
+		net.TCPDestination(net.DomainAddress("www.v2fly.org"), 443): "matched",
+		net.TCPDestination(net.DomainAddress("localhost"), 443):     "matched",
+		net.TCPDestination(net.DomainAddress("local.example"), 443): "",
+	} {
+		if tag := routeTo(r, dest); tag != expected {
+			t.Error(dest, ": expected '", expected, "', but got '", tag, "'")
+		}
+	}
+
+	for _, content := range []string{"geosite:cn\n", "geoip:cn\n", "ext:geosite.dat:cn\n", "dotless:a.b\n"} {
+		if _, err := newRouter(content); err == nil {
+			t.Error("expected error for rule set ", content)
+		}
+	}
+}
+
+func TestRuleSetURL(t *testing.T) {
+	siteList, err := proto.Marshal(&routercommon.GeoSiteList{
+		Entry: []*routercommon.GeoSite{
+			{
+				CountryCode: "ADS",
+				Domain: []*routercommon.Domain{
+					{Type: routercommon.Domain_RootDomain, Value: "ads.example"},
+				},
+			},
+		},
+	})
+	common.Must(err)
+
+	var requests, downloads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		etag := fmt.Sprint(`"`, len(siteList), `"`)
+		if req.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", etag)
+		w.Write(siteList)
+	}))
+	defer server.Close()
+
+	matcher, err := NewRuleSetMatcher(context.Background(), &RuleSet{
+		Tag:    "ads",
+		Url:    server.URL,
+		Format: RuleSet_GeoSite,
+		Code:   "ads",
+	})
+	common.Must(err)
+
+	ctx := routing_session.AsRoutingContext(session.ContextWithOutbound(context.Background(), &session.Outbound{
+		Target: net.TCPDestination(net.DomainAddress("www.ads.example"), 443),
+	}))
+	if matcher.Apply(ctx) {
+		t.Error("expected no match before the rule set is downloaded")
+	}
+
+	common.Must(matcher.Reload())
+	if !matcher.Apply(ctx) {
+		t.Error("expected match after the rule set is downloaded")
+	}
+
+	common.Must(matcher.Reload())
+	if requests != 2 || downloads != 1 {
+		t.Error("expected 2 requests and 1 download, but got ", requests, " and ", downloads)
+	}
+	if !matcher.Apply(ctx) {
+		t.Error("expected match after the rule set is not modified")
+	}
+}
@@ -0,0 +1,110 @@
+//go:build !confonly
+// +build !confonly
+
+package router
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/features/extension"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+)
+
+// ConsistentHashStrategy keeps connections with the same keys on the same
+// outbound. It uses rendezvous hashing, so when an outbound goes down only the
+// connections on it are moved, and they move back when it comes up again.
+type ConsistentHashStrategy struct {
+	ctx             context.Context
+	observatory     extension.Observatory
+	observatoryOnce sync.Once
+
+	config *StrategyConsistentHashConfig
+}
+
+// NewConsistentHashStrategy creates a new ConsistentHashStrategy with settings
+func NewConsistentHashStrategy(config *StrategyConsistentHashConfig) *ConsistentHashStrategy {
+	return &ConsistentHashStrategy{
+		config: config,
+	}
+}
+
+func (s *ConsistentHashStrategy) InjectContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+func (s *ConsistentHashStrategy) GetPrincipleTarget(strings []string) []string {
+	return s.aliveOutbounds(strings)
+}
+
+func (s *ConsistentHashStrategy) PickOutbound(ctx routing.Context, candidates []string) string {
+	key := s.hashKey(ctx)
+	var selected string
+	var maxWeight uint64
+	for _, tag := range s.aliveOutbounds(candidates) {
+		if weight := rendezvousWeight(key, tag); selected == "" || weight > maxWeight {
+			selected = tag
+			maxWeight = weight
+		}
+	}
+	return selected
+}
+
+func (s *ConsistentHashStrategy) hashKey(ctx routing.Context) []byte {
+	if ctx == nil {
+		return nil
+	}
+	keys := s.config.Key
+	if len(keys) == 0 {
+		keys = []StrategyConsistentHashConfig_Key{StrategyConsistentHashConfig_SourceIP}
+	}
+	var b []byte
+	for _, key := range keys {
+		switch key {
+		case StrategyConsistentHashConfig_SourceIP:
+			if ips := ctx.GetSourceIPs(); len(ips) > 0 {
+				b = append(b, ips[0]...)
+			}
+		case StrategyConsistentHashConfig_Destination:
+			if domain := ctx.GetTargetDomain(); domain != "" {
+				b = append(b, domain...)
+			} else if ips := ctx.GetTargetIPs(); len(ips) > 0 {
+				b = append(b, ips[0]...)
+			}
+		case StrategyConsistentHashConfig_User:
+			b = append(b, ctx.GetUser()...)
+		}
+		b = append(b, 0)
+	}
+	return b
+}
+
+// rendezvousWeight returns the weight of an outbound for a key. The outbound
+// with the highest weight is picked.
+func rendezvousWeight(key []byte, tag string) uint64 {
+	h := fnv.New64a()
+	h.Write(key)
+	h.Write([]byte(tag))
+	// FNV does not mix the last bytes well, so finalize it as in SplitMix64.
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// aliveOutbounds filters away the candidates that the observatory reports not
+// alive. All candidates are kept if there is no observatory.
+func (s *ConsistentHashStrategy) aliveOutbounds(candidates []string) []string {
+	s.observatoryOnce.Do(func() {
+		if s.observatory == nil {
+			s.observatory = findObservatory(s.ctx, s.config.ObserverTag)
+		}
+	})
+	return excludeDeadOutbounds(s.ctx, s.observatory, candidates)
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*StrategyConsistentHashConfig)(nil), nil))
+}
@@ -0,0 +1,109 @@
+package router
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/extension"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	routing_session "github.com/v2fly/v2ray-core/v5/features/routing/session"
+)
+
+type staticObservatory struct {
+	result *observatory.ObservationResult
+}
+
+func (o *staticObservatory) Type() interface{} {
+	return extension.ObservatoryType()
+}
+
+func (o *staticObservatory) Start() error {
+	return nil
+}
+
+func (o *staticObservatory) Close() error {
+	return nil
+}
+
+func (o *staticObservatory) GetObservation(context.Context) (proto.Message, error) {
+	return o.result, nil
+}
+
+func hashContext(source string, domain string, user string) routing.Context {
+	return &routing_session.Context{
+		Inbound: &session.Inbound{
+			Source: net.TCPDestination(net.ParseAddress(source), 1234),
+			User:   &protocol.MemoryUser{Email: user},
+		},
+		Outbound: &session.Outbound{
+			Target: net.TCPDestination(net.DomainAddress(domain), 443),
+		},
+	}
+}
+
+func TestConsistentHashStrategy(t *testing.T) {
+	candidates := []string{"a", "b", "c", "d", "e"}
+	strategy := NewConsistentHashStrategy(&StrategyConsistentHashConfig{})
+
+	picks := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		source := "10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256)
+		tag := strategy.PickOutbound(hashContext(source, "example.com", ""), candidates)
+		if again := strategy.PickOutbound(hashContext(source, "v2fly.org", "user"), candidates); again != tag {
+			t.Fatal("expected ", source, " to stay on ", tag, ", but got ", again)
+		}
+		picks[source] = tag
+		counts[tag]++
+	}
+	for _, tag := range candidates {
+		if counts[tag] < 100 {
+			t.Error("only ", counts[tag], " of 1000 sources are on ", tag)
+		}
+	}
+
+	// Only sources on the outbound that goes down may move.
+	strategy.observatory = &staticObservatory{
+		result: &observatory.ObservationResult{
+			Status: []*observatory.OutboundStatus{
+				{OutboundTag: "a", Alive: true},
+				{OutboundTag: "c", Alive: false},
+			},
+		},
+	}
+	for source, tag := range picks {
+		moved := strategy.PickOutbound(hashContext(source, "example.com", ""), candidates)
+		if moved == "c" || (tag != "c" && moved != tag) {
+			t.Error("expected ", source, " on ", tag, " not to move to ", moved)
+		}
+	}
+}
+
+func TestConsistentHashStrategyKeys(t *testing.T) {
+	candidates := []string{"a", "b", "c", "d", "e"}
+	strategy := NewConsistentHashStrategy(&StrategyConsistentHashConfig{
+		Key: []StrategyConsistentHashConfig_Key{
+			StrategyConsistentHashConfig_Destination,
+			StrategyConsistentHashConfig_User,
+		},
+	})
+
+	tags := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		domain := "d" + strconv.Itoa(i) + ".example"
+		tag := strategy.PickOutbound(hashContext("10.0.0.1", domain, "user"), candidates)
+		if again := strategy.PickOutbound(hashContext("10.0.0.2", domain, "user"), candidates); again != tag {
+			t.Fatal("expected ", domain, " to stay on ", tag, ", but got ", again)
+		}
+		tags[tag] = true
+	}
+	if len(tags) != len(candidates) {
+		t.Error("expected destinations on all outbounds, but got ", tags)
+	}
+}
@@ -0,0 +1,217 @@
+//go:build !confonly
+// +build !confonly
+
+package router
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/features/extension"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+)
+
+const (
+	defaultFallbackMaxFailures   = 3
+	defaultFallbackFailureWindow = time.Minute
+	defaultFallbackDownDuration  = time.Minute
+)
+
+// passiveHealth is the health of an outbound learned from real traffic.
+type passiveHealth struct {
+	failures []time.Time
+	// downSince is zero if the outbound is not taken out of service.
+	downSince time.Time
+}
+
+// FallbackStrategy picks the first alive outbound in the order of the
+// selectors. An outbound is not alive if the observatory says so, or if its
+// connections failed too many times recently.
+type FallbackStrategy struct {
+	ctx             context.Context
+	observatory     extension.Observatory
+	observatoryOnce sync.Once
+
+	config        *StrategyFallbackConfig
+	selectors     []string
+	maxFailures   int
+	failureWindow time.Duration
+	downDuration  time.Duration
+
+	access sync.Mutex
+	health map[string]*passiveHealth
+}
+
+// NewFallbackStrategy creates a new FallbackStrategy with settings
+func NewFallbackStrategy(config *StrategyFallbackConfig, selectors []string) *FallbackStrategy {
+	s := &FallbackStrategy{
+		config:        config,
+		selectors:     selectors,
+		maxFailures:   int(config.MaxFailures),
+		failureWindow: time.Duration(config.FailureWindow),
+		downDuration:  time.Duration(config.DownDuration),
+		health:        make(map[string]*passiveHealth),
+	}
+	if s.maxFailures <= 0 {
+		s.maxFailures = defaultFallbackMaxFailures
+	}
+	if s.failureWindow <= 0 {
+		s.failureWindow = defaultFallbackFailureWindow
+	}
+	if s.downDuration <= 0 {
+		s.downDuration = defaultFallbackDownDuration
+	}
+	return s
+}
+
+func (s *FallbackStrategy) InjectContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+func (s *FallbackStrategy) GetPrincipleTarget(strings []string) []string {
+	if tag := s.PickOutbound(nil, strings); tag != "" {
+		return []string{tag}
+	}
+	return nil
+}
+
+func (s *FallbackStrategy) PickOutbound(_ routing.Context, candidates []string) string {
+	status := s.observedStatus()
+	for _, tag := range s.sortCandidates(candidates) {
+		if s.isAlive(tag, status[tag]) {
+			return tag
+		}
+	}
+	return ""
+}
+
+// OnResult implements outbound.ResultListener.
+func (s *FallbackStrategy) OnResult(tag string, err error) {
+	if s.selectorIndex(tag) < 0 {
+		return
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	now := time.Now()
+	h := s.health[tag]
+	if err == nil {
+		// A connection may have been opened before the failures, so success
+		// only drops expired failures, and does not bring the outbound back.
+		if h != nil {
+			h.failures = s.recentFailures(h.failures, now)
+			if len(h.failures) == 0 && h.downSince.IsZero() {
+				delete(s.health, tag)
+			}
+		}
+		return
+	}
+	if h == nil {
+		h = new(passiveHealth)
+		s.health[tag] = h
+	}
+	h.failures = append(s.recentFailures(h.failures, now), now)
+	if len(h.failures) >= s.maxFailures && h.downSince.IsZero() {
+		h.downSince = now
+		newError("outbound ", tag, " is out of service after ", len(h.failures), " failures").Base(err).AtWarning().WriteToLog()
+	}
+}
+
+// recentFailures returns the failures within the failure window before now.
+func (s *FallbackStrategy) recentFailures(failures []time.Time, now time.Time) []time.Time {
+	recent := failures[:0]
+	for _, t := range failures {
+		if now.Sub(t) < s.failureWindow {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+func (s *FallbackStrategy) isAlive(tag string, status *observatory.OutboundStatus) bool {
+	if status != nil && !status.Alive {
+		return false
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	h := s.health[tag]
+	if h == nil || h.downSince.IsZero() {
+		return true
+	}
+	// The outbound is back in service when it is probed alive, or when it has
+	// been out of service for long enough to be tried again.
+	if (status != nil && status.LastTryTime > h.downSince.Unix()) || time.Since(h.downSince) >= s.downDuration {
+		delete(s.health, tag)
+		return true
+	}
+	return false
+}
+
+// selectorIndex returns the index of the first selector that matches tag, or
+// -1 if there is none.
+func (s *FallbackStrategy) selectorIndex(tag string) int {
+	for i, selector := range s.selectors {
+		if strings.HasPrefix(tag, selector) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortCandidates orders candidates by the selectors they match, and then by
+// tag. Candidates matching no selector are put last.
+func (s *FallbackStrategy) sortCandidates(candidates []string) []string {
+	order := func(tag string) int {
+		if i := s.selectorIndex(tag); i >= 0 {
+			return i
+		}
+		return len(s.selectors)
+	}
+	sorted := append([]string(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := order(sorted[i]), order(sorted[j])
+		if a != b {
+			return a < b
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+func (s *FallbackStrategy) observedStatus() map[string]*observatory.OutboundStatus {
+	s.observatoryOnce.Do(func() {
+		if s.observatory == nil {
+			s.observatory = findObservatory(s.ctx, s.config.ObserverTag)
+		}
+	})
+	if s.observatory == nil {
+		return nil
+	}
+
+	observeReport, err := s.observatory.GetObservation(s.ctx)
+	if err != nil {
+		newError("cannot get observe report").Base(err).WriteToLog()
+		return nil
+	}
+	result, ok := observeReport.(*observatory.ObservationResult)
+	if !ok {
+		return nil
+	}
+	status := make(map[string]*observatory.OutboundStatus, len(result.Status))
+	for _, v := range result.Status {
+		status[v.OutboundTag] = v
+	}
+	return status
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*StrategyFallbackConfig)(nil), nil))
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
+)
+
+func TestFallbackStrategy(t *testing.T) {
+	candidates := []string{"backup-b", "other", "primary", "backup-a"}
+	strategy := NewFallbackStrategy(&StrategyFallbackConfig{
+		MaxFailures:  2,
+		DownDuration: int64(time.Hour),
+	}, []string{"primary", "backup"})
+
+	expectPick := func(expected string) {
+		t.Helper()
+		if tag := strategy.PickOutbound(nil, candidates); tag != expected {
+			t.Error("expected ", expected, ", but got ", tag)
+		}
+	}
+
+	expectPick("primary")
+
+	failure := errors.New("connection refused")
+	strategy.OnResult("primary", failure)
+	expectPick("primary")
+	strategy.OnResult("primary", failure)
+	expectPick("backup-a")
+
+	// Results of outbounds not under the balancer are ignored.
+	strategy.OnResult("other", failure)
+	strategy.OnResult("other", failure)
+
+	// A connection opened before the failures may end cleanly, which does not
+	// bring the outbound back.
+	strategy.OnResult("primary", nil)
+	expectPick("backup-a")
+
+	downSince := time.Now()
+	strategy.OnResult("primary", failure)
+	strategy.OnResult("primary", failure)
+	strategy.observatory = &staticObservatory{
+		result: &observatory.ObservationResult{
+			Status: []*observatory.OutboundStatus{
+				{OutboundTag: "primary", Alive: true, LastTryTime: downSince.Unix() - 1},
+				{OutboundTag: "backup-a", Alive: false},
+			},
+		},
+	}
+	expectPick("backup-b")
+
+	// A probe after the failures brings the outbound back.
+	strategy.observatory.(*staticObservatory).result.Status[0].LastTryTime = downSince.Unix() + 1
+	expectPick("primary")
+
+	strategy.observatory.(*staticObservatory).result.Status[0].Alive = false
+	candidates = []string{"primary", "backup-a"}
+	expectPick("")
+}
+
+func TestFallbackStrategyFailureWindow(t *testing.T) {
+	strategy := NewFallbackStrategy(&StrategyFallbackConfig{
+		MaxFailures:   2,
+		FailureWindow: int64(50 * time.Millisecond),
+		DownDuration:  int64(time.Hour),
+	}, []string{"primary", "backup"})
+	candidates := []string{"primary", "backup"}
+	failure := errors.New("connection refused")
+
+	strategy.OnResult("primary", failure)
+	time.Sleep(100 * time.Millisecond)
+	strategy.OnResult("primary", nil)
+	strategy.OnResult("primary", failure)
+	if tag := strategy.PickOutbound(nil, candidates); tag != "primary" {
+		t.Error("expired failures must not count, but got ", tag)
+	}
+
+	// Successes do not reset failures within the window.
+	strategy.OnResult("primary", nil)
+	strategy.OnResult("primary", failure)
+	if tag := strategy.PickOutbound(nil, candidates); tag != "backup" {
+		t.Error("expected backup, but got ", tag)
+	}
+}
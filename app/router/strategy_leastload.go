@@ -13,6 +13,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/dice"
 	"github.com/v2fly/v2ray-core/v5/features"
 	"github.com/v2fly/v2ray-core/v5/features/extension"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
 )
 
 // LeastLoadStrategy represents a least load balancing strategy
@@ -63,7 +64,7 @@ func (l *LeastLoadStrategy) InjectContext(ctx context.Context) {
 	l.ctx = ctx
 }
 
-func (l *LeastLoadStrategy) PickOutbound(candidates []string) string {
+func (l *LeastLoadStrategy) PickOutbound(_ routing.Context, candidates []string) string {
 	selects := l.pickOutbounds(candidates)
 	count := len(selects)
 	if count == 0 {
@@ -3,6 +3,7 @@ package router
 import (
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/dice"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
 )
 
 // RandomStrategy represents a random balancing strategy
@@ -12,7 +13,7 @@ func (s *RandomStrategy) GetPrincipleTarget(strings []string) []string {
 	return strings
 }
 
-func (s *RandomStrategy) PickOutbound(candidates []string) string {
+func (s *RandomStrategy) PickOutbound(_ routing.Context, candidates []string) string {
 	count := len(candidates)
 	if count == 0 {
 		// goes to fallbackTag
@@ -0,0 +1,68 @@
+//go:build !confonly
+// +build !confonly
+
+package router
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/features/extension"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+)
+
+// RoundRobinStrategy picks the candidates in turn.
+type RoundRobinStrategy struct {
+	ctx             context.Context
+	observatory     extension.Observatory
+	observatoryOnce sync.Once
+
+	config *StrategyRoundRobinConfig
+	next   uint32
+}
+
+// NewRoundRobinStrategy creates a new RoundRobinStrategy with settings
+func NewRoundRobinStrategy(config *StrategyRoundRobinConfig) *RoundRobinStrategy {
+	return &RoundRobinStrategy{
+		config: config,
+	}
+}
+
+func (s *RoundRobinStrategy) InjectContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+func (s *RoundRobinStrategy) GetPrincipleTarget(strings []string) []string {
+	return s.aliveOutbounds(strings)
+}
+
+func (s *RoundRobinStrategy) PickOutbound(_ routing.Context, candidates []string) string {
+	alive := s.aliveOutbounds(candidates)
+	if len(alive) == 0 {
+		// goes to fallbackTag
+		return ""
+	}
+	// Candidates come in no particular order.
+	sort.Strings(alive)
+	n := atomic.AddUint32(&s.next, 1) - 1
+	return alive[n%uint32(len(alive))]
+}
+
+func (s *RoundRobinStrategy) aliveOutbounds(candidates []string) []string {
+	if s.config.ObserverTag == "" {
+		return append([]string(nil), candidates...)
+	}
+	s.observatoryOnce.Do(func() {
+		if s.observatory == nil {
+			s.observatory = findObservatory(s.ctx, s.config.ObserverTag)
+		}
+	})
+	return excludeDeadOutbounds(s.ctx, s.observatory, candidates)
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*StrategyRoundRobinConfig)(nil), nil))
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoundRobinStrategy(t *testing.T) {
+	candidates := []string{"c", "a", "b"}
+	strategy := NewRoundRobinStrategy(&StrategyRoundRobinConfig{})
+
+	var picks []string
+	for i := 0; i < 6; i++ {
+		picks = append(picks, strategy.PickOutbound(nil, candidates))
+	}
+	if s := strings.Join(picks, ""); s != "abcabc" {
+		t.Error("unexpected picks ", s)
+	}
+}
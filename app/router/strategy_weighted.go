@@ -0,0 +1,90 @@
+//go:build !confonly
+// +build !confonly
+
+package router
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/features/extension"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+)
+
+// WeightedStrategy spreads connections over the candidates in proportion to
+// their weights. It uses smooth weighted round-robin, so the proportions hold
+// over any short run of connections, not only on average.
+type WeightedStrategy struct {
+	ctx             context.Context
+	observatory     extension.Observatory
+	observatoryOnce sync.Once
+
+	config *StrategyWeightedConfig
+
+	access  sync.Mutex
+	weights *WeightManager
+	current map[string]float64
+}
+
+// NewWeightedStrategy creates a new WeightedStrategy with settings
+func NewWeightedStrategy(config *StrategyWeightedConfig) *WeightedStrategy {
+	return &WeightedStrategy{
+		config: config,
+		weights: NewWeightManager(config.Weights, 1, func(value, weight float64) float64 {
+			return value * weight
+		}),
+		current: make(map[string]float64),
+	}
+}
+
+func (s *WeightedStrategy) InjectContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+func (s *WeightedStrategy) GetPrincipleTarget(strings []string) []string {
+	return s.aliveOutbounds(strings)
+}
+
+func (s *WeightedStrategy) PickOutbound(_ routing.Context, candidates []string) string {
+	alive := s.aliveOutbounds(candidates)
+	sort.Strings(alive)
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	var selected string
+	var total float64
+	for _, tag := range alive {
+		weight := s.weights.Get(tag)
+		if weight <= 0 {
+			continue
+		}
+		total += weight
+		s.current[tag] += weight
+		if selected == "" || s.current[tag] > s.current[selected] {
+			selected = tag
+		}
+	}
+	if selected != "" {
+		s.current[selected] -= total
+	}
+	return selected
+}
+
+func (s *WeightedStrategy) aliveOutbounds(candidates []string) []string {
+	if s.config.ObserverTag == "" {
+		return append([]string(nil), candidates...)
+	}
+	s.observatoryOnce.Do(func() {
+		if s.observatory == nil {
+			s.observatory = findObservatory(s.ctx, s.config.ObserverTag)
+		}
+	})
+	return excludeDeadOutbounds(s.ctx, s.observatory, candidates)
+}
+
+func init() {
+	common.Must(common.RegisterConfig((*StrategyWeightedConfig)(nil), nil))
+}
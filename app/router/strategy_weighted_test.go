@@ -0,0 +1,46 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
+)
+
+func TestWeightedStrategy(t *testing.T) {
+	candidates := []string{"link-c-10M", "link-b", "link-a"}
+	strategy := NewWeightedStrategy(&StrategyWeightedConfig{
+		Weights: []*StrategyWeight{
+			{Match: "link-a", Value: 5},
+			{Regexp: true, Match: `\d+M$`},
+		},
+	})
+
+	counts := make(map[string]int)
+	for i := 0; i < 16; i++ {
+		counts[strategy.PickOutbound(nil, candidates)]++
+	}
+	if counts["link-a"] != 5 || counts["link-b"] != 1 || counts["link-c-10M"] != 10 {
+		t.Error("unexpected counts ", counts)
+	}
+}
+
+func TestWeightedStrategySkipsDead(t *testing.T) {
+	candidates := []string{"a", "b"}
+	strategy := NewWeightedStrategy(&StrategyWeightedConfig{
+		Weights:     []*StrategyWeight{{Match: "a", Value: 10}},
+		ObserverTag: "probe",
+	})
+	strategy.observatory = &staticObservatory{
+		result: &observatory.ObservationResult{
+			Status: []*observatory.OutboundStatus{
+				{OutboundTag: "a", Alive: false},
+				{OutboundTag: "b", Alive: true},
+			},
+		},
+	}
+	for i := 0; i < 5; i++ {
+		if tag := strategy.PickOutbound(nil, candidates); tag != "b" {
+			t.Fatal("expected b, but got ", tag)
+		}
+	}
+}
@@ -27,6 +27,8 @@ type AccessMessage struct {
 	Reason interface{}
 	Email  string
 	Detour string
+	// RuleTag is the tag of the routing rule that decided Detour.
+	RuleTag string
 }
 
 func (m *AccessMessage) String() string {
@@ -53,6 +55,11 @@ func (m *AccessMessage) String() string {
 		builder.WriteString(m.Email)
 	}
 
+	if len(m.RuleTag) > 0 {
+		builder.WriteString(" rule: ")
+		builder.WriteString(m.RuleTag)
+	}
+
 	return builder.String()
 }
 
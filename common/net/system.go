@@ -26,6 +26,7 @@ var (
 	FileListener    = net.FileListener
 	FilePacketConn  = net.FilePacketConn
 	InterfaceByName = net.InterfaceByName
+	InterfaceAddrs  = net.InterfaceAddrs
 )
 
 type (
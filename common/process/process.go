@@ -0,0 +1,26 @@
+// Package process finds the local process owning a connection.
+package process
+
+import (
+	"github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
+// Info describes a local process.
+type Info struct {
+	PID  uint32
+	UID  uint32
+	Name string
+	Path string
+}
+
+// FindByLocalAddress returns the local process owning the socket bound to the
+// given source address, as seen from the other end of a connection on the same
+// host.
+func FindByLocalAddress(source net.Destination) (*Info, error) {
+	if !source.IsValid() || !source.Address.Family().IsIP() {
+		return nil, newError("invalid source address ", source)
+	}
+	return findByLocalAddress(source)
+}
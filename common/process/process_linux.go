@@ -0,0 +1,194 @@
+//go:build linux
+// +build linux
+
+package process
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+// tcpListen is the state of listening sockets in /proc/net/tcp.
+const tcpListen = "0A"
+
+// Addresses in /proc/net are printed as 32-bit words in host byte order.
+var nativeEndian binary.ByteOrder = binary.LittleEndian
+
+func init() {
+	switch runtime.GOARCH {
+	case "mips", "mips64", "ppc64", "s390x":
+		nativeEndian = binary.BigEndian
+	}
+}
+
+func findByLocalAddress(source net.Destination) (*Info, error) {
+	var tables []string
+	switch source.Network {
+	case net.Network_TCP:
+		tables = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+	case net.Network_UDP:
+		tables = []string{"/proc/net/udp", "/proc/net/udp6"}
+	default:
+		return nil, newError("unsupported network ", source.Network)
+	}
+	if !isLocalAddress(source.Address.IP()) {
+		return nil, newError("source ", source, " is not a local address")
+	}
+
+	var inode, uid uint32
+	var found bool
+	for _, table := range tables {
+		var err error
+		inode, uid, found, err = findSocket(table, source)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			break
+		}
+	}
+	if !found {
+		return nil, newError("no socket bound to ", source)
+	}
+
+	pid, err := findProcessBySocket(inode)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &Info{
+		PID: pid,
+		UID: uid,
+	}
+	procPath := filepath.Join("/proc", strconv.FormatUint(uint64(pid), 10))
+	if path, err := os.Readlink(filepath.Join(procPath, "exe")); err == nil {
+		info.Path = path
+		info.Name = filepath.Base(path)
+	} else if comm, err := os.ReadFile(filepath.Join(procPath, "comm")); err == nil {
+		info.Name = strings.TrimSpace(string(comm))
+	}
+	return info, nil
+}
+
+// isLocalAddress returns whether ip is an address of this host. Sockets of
+// remote clients must not be matched with local ones bound to the same port.
+func isLocalAddress(ip net.IP) bool {
+	if ip.IsLoopback() {
+		return true
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// findSocket looks up the socket bound to source in a /proc/net table. A
+// socket bound to the unspecified address on the same port is taken if no
+// exact match is found, as unconnected UDP sockets are. Listening TCP sockets
+// are skipped, as they never originate a connection.
+func findSocket(table string, source net.Destination) (inode uint32, uid uint32, found bool, err error) {
+	file, err := os.Open(table)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, false, nil
+		}
+		return 0, 0, false, newError("failed to open ", table).Base(err)
+	}
+	defer file.Close()
+
+	ip := source.Address.IP()
+	var fallbackInode, fallbackUID uint32
+	var hasFallback bool
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || (source.Network == net.Network_TCP && fields[3] == tcpListen) {
+			continue
+		}
+		localIP, localPort, err := parseAddress(fields[1])
+		if err != nil || localPort != source.Port {
+			continue
+		}
+		socketUID, err := strconv.ParseUint(fields[7], 10, 32)
+		if err != nil {
+			continue
+		}
+		socketInode, err := strconv.ParseUint(fields[9], 10, 32)
+		if err != nil || socketInode == 0 {
+			continue
+		}
+		if localIP.Equal(ip) {
+			return uint32(socketInode), uint32(socketUID), true, nil
+		}
+		if localIP.IsUnspecified() && !hasFallback {
+			fallbackInode, fallbackUID, hasFallback = uint32(socketInode), uint32(socketUID), true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, 0, false, newError("failed to read ", table).Base(err)
+	}
+	return fallbackInode, fallbackUID, hasFallback, nil
+}
+
+// parseAddress parses an address like "0100007F:0035" in /proc/net tables.
+func parseAddress(s string) (net.IP, net.Port, error) {
+	index := strings.IndexByte(s, ':')
+	if index < 0 {
+		return nil, 0, newError("invalid address ", s)
+	}
+	words, err := hex.DecodeString(s[:index])
+	if err != nil || (len(words) != net.IPv4len && len(words) != net.IPv6len) {
+		return nil, 0, newError("invalid address ", s)
+	}
+	ip := make(net.IP, len(words))
+	for i := 0; i < len(words); i += 4 {
+		nativeEndian.PutUint32(ip[i:], binary.BigEndian.Uint32(words[i:]))
+	}
+	port, err := strconv.ParseUint(s[index+1:], 16, 16)
+	if err != nil {
+		return nil, 0, newError("invalid address ", s).Base(err)
+	}
+	return ip, net.Port(port), nil
+}
+
+func findProcessBySocket(inode uint32) (uint32, error) {
+	target := "socket:[" + strconv.FormatUint(uint64(inode), 10) + "]"
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, newError("failed to list processes").Base(err)
+	}
+	for _, entry := range entries {
+		pid, err := strconv.ParseUint(entry.Name(), 10, 32)
+		if err != nil {
+			continue
+		}
+		fdPath := filepath.Join("/proc", entry.Name(), "fd")
+		fds, err := os.ReadDir(fdPath)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdPath, fd.Name()))
+			if err == nil && link == target {
+				return uint32(pid), nil
+			}
+		}
+	}
+	return 0, newError("no process owns socket ", inode)
+}
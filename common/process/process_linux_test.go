@@ -0,0 +1,65 @@
+//go:build linux
+// +build linux
+
+package process_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/process"
+)
+
+func TestFindByLocalAddress(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	common.Must(err)
+	defer conn.Close()
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IP{127, 0, 0, 1}})
+	common.Must(err)
+	defer udpConn.Close()
+
+	executable, err := os.Executable()
+	common.Must(err)
+
+	for _, addr := range []net.Addr{conn.LocalAddr(), udpConn.LocalAddr()} {
+		info, err := process.FindByLocalAddress(net.DestinationFromAddr(addr))
+		if err != nil {
+			t.Fatal(addr, ": ", err)
+		}
+		if info.PID != uint32(os.Getpid()) {
+			t.Error("unexpected pid ", info.PID)
+		}
+		if info.Path != executable {
+			t.Error("unexpected path ", info.Path)
+		}
+	}
+
+	idle, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer idle.Close()
+	if _, err := process.FindByLocalAddress(net.DestinationFromAddr(idle.Addr())); err == nil {
+		t.Error("expected error for listening socket")
+	}
+
+	wildcard, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IP{0, 0, 0, 0}})
+	common.Must(err)
+	defer wildcard.Close()
+	remote := net.UDPDestination(net.ParseAddress("203.0.113.1"), net.DestinationFromAddr(wildcard.LocalAddr()).Port)
+	if _, err := process.FindByLocalAddress(remote); err == nil {
+		t.Error("expected error for remote address")
+	}
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	common.Must(closed.Close())
+	if _, err := process.FindByLocalAddress(net.DestinationFromAddr(closed.Addr())); err == nil {
+		t.Error("expected error for unbound address")
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package process
+
+import (
+	"github.com/v2fly/v2ray-core/v5/common/net"
+)
+
+func findByLocalAddress(source net.Destination) (*Info, error) {
+	return nil, newError("process lookup is not supported on this platform")
+}
@@ -0,0 +1,75 @@
+// Package authprovider resolves inbound users from an external source, so
+// that large user bases do not have to be kept in config or pushed through
+// the API.
+package authprovider
+
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/v2fly/v2ray-core/v5/common/uuid"
+)
+
+// User is a user as returned by a Provider. ID is used by VMess and VLESS,
+// Password by Trojan.
+type User struct {
+	Email    string `json:"email"`
+	Level    uint32 `json:"level"`
+	ID       string `json:"id"`
+	Password string `json:"password"`
+	Flow     string `json:"flow"`
+}
+
+// Provider resolves a user from the key a client presents. The key is the
+// UUID string for VLESS and the hex SHA-224 of the password for Trojan.
+type Provider interface {
+	// Lookup returns the user for key, or nil if there is none.
+	Lookup(ctx context.Context, key string) (*User, error)
+}
+
+// Lister is implemented by providers that can enumerate all users. It is
+// required for VMess, whose AEAD auth ID can only be matched against users
+// already known in memory.
+type Lister interface {
+	List(ctx context.Context) ([]*User, error)
+}
+
+// New creates the Provider described by config.
+func New(config *Config) (Provider, error) {
+	switch {
+	case config.Url != "" && config.File != "":
+		return nil, newError("only one of url and file may be set")
+	case config.Url != "":
+		return newHTTPProvider(config.Url)
+	case config.File != "":
+		return newFileProvider(config.File)
+	default:
+		return nil, newError("either url or file must be set")
+	}
+}
+
+// Keys returns the keys under which u can be looked up.
+func (u *User) Keys() []string {
+	var keys []string
+	if u.ID != "" {
+		if id, err := uuid.ParseString(u.ID); err == nil {
+			keys = append(keys, id.String())
+		} else {
+			keys = append(keys, strings.ToLower(u.ID))
+		}
+	}
+	if u.Password != "" {
+		keys = append(keys, PasswordKey(u.Password))
+	}
+	return keys
+}
+
+// PasswordKey returns the lookup key of a Trojan password.
+func PasswordKey(password string) string {
+	hash := sha256.Sum224([]byte(password))
+	return hex.EncodeToString(hash[:])
+}
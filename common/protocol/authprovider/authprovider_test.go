@@ -0,0 +1,218 @@
+package authprovider_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	. "github.com/v2fly/v2ray-core/v5/common/protocol/authprovider"
+)
+
+func toMemoryUser(user *User, key string) (*protocol.MemoryUser, error) {
+	return &protocol.MemoryUser{Email: user.Email, Level: user.Level}, nil
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	common.Must(os.WriteFile(path, []byte(`[
+		{"email": "a@example.com", "id": "B831381D-6324-4D53-AD4F-8CDA48B30811"},
+		{"email": "b@example.com", "level": 1, "password": "secret"}
+	]`), 0o600))
+
+	provider, err := New(&Config{File: path})
+	common.Must(err)
+	defer common.Close(provider)
+
+	for key, email := range map[string]string{
+		"b831381d-6324-4d53-ad4f-8cda48b30811": "a@example.com",
+		PasswordKey("secret"):                  "b@example.com",
+	} {
+		user, err := provider.Lookup(context.Background(), key)
+		common.Must(err)
+		if user == nil || user.Email != email {
+			t.Error("unexpected user for ", key, ": ", user)
+		}
+	}
+	if user, _ := provider.Lookup(context.Background(), "secret"); user != nil {
+		t.Error("plain password must not be a key")
+	}
+
+	users, err := provider.(Lister).List(context.Background())
+	common.Must(err)
+	if len(users) != 2 {
+		t.Error("expected 2 users, got ", len(users))
+	}
+}
+
+func TestHTTPProvider(t *testing.T) {
+	users := []*User{
+		{Email: "a@example.com", ID: "b831381d-6324-4d53-ad4f-8cda48b30811"},
+		{Email: "b@example.com", Password: "secret"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		if key == "" {
+			json.NewEncoder(w).Encode(users)
+			return
+		}
+		for _, user := range users {
+			for _, k := range user.Keys() {
+				if k == key {
+					json.NewEncoder(w).Encode(user)
+					return
+				}
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	provider, err := New(&Config{Url: server.URL + "/users"})
+	common.Must(err)
+
+	user, err := provider.Lookup(context.Background(), PasswordKey("secret"))
+	common.Must(err)
+	if r := cmp.Diff(user, users[1]); r != "" {
+		t.Error(r)
+	}
+	user, err = provider.Lookup(context.Background(), PasswordKey("wrong"))
+	common.Must(err)
+	if user != nil {
+		t.Error("unexpected user ", user)
+	}
+
+	list, err := provider.(Lister).List(context.Background())
+	common.Must(err)
+	if r := cmp.Diff(list, users); r != "" {
+		t.Error(r)
+	}
+}
+
+func TestResolverCache(t *testing.T) {
+	var queries int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&queries, 1)
+		time.Sleep(50 * time.Millisecond)
+		if r.URL.Query().Get("key") != "known" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(&User{Email: "known@example.com"})
+	}))
+	defer server.Close()
+
+	resolver, err := NewResolver(&Config{Url: server.URL, NegativeTtl: 1}, toMemoryUser)
+	common.Must(err)
+	defer resolver.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if user := resolver.Resolve("known"); user == nil || user.Email != "known@example.com" {
+				t.Error("unexpected user ", user)
+			}
+		}()
+	}
+	wg.Wait()
+	if q := atomic.LoadInt32(&queries); q != 1 {
+		t.Error("expected 1 query for concurrent lookups, got ", q)
+	}
+
+	if user := resolver.Resolve("unknown"); user != nil {
+		t.Error("unexpected user ", user)
+	}
+	resolver.Resolve("unknown")
+	if q := atomic.LoadInt32(&queries); q != 2 {
+		t.Error("expected unknown key to be cached, got ", q, " queries")
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	resolver.Resolve("unknown")
+	resolver.Resolve("known")
+	if q := atomic.LoadInt32(&queries); q != 3 {
+		t.Error("expected only the unknown key to expire, got ", q, " queries")
+	}
+}
+
+func TestResolverPendingLookups(t *testing.T) {
+	var queries int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&queries, 1)
+		time.Sleep(500 * time.Millisecond)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	resolver, err := NewResolver(&Config{Url: server.URL}, toMemoryUser)
+	common.Must(err)
+	defer resolver.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if user := resolver.Resolve(strconv.Itoa(i)); user != nil {
+				t.Error("unexpected user ", user)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if q := atomic.LoadInt32(&queries); q > 16 {
+		t.Error("expected at most 16 pending queries, got ", q)
+	}
+}
+
+func TestSyncer(t *testing.T) {
+	var access sync.Mutex
+	users := []*User{{Email: "a"}, {Email: "b"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		access.Lock()
+		defer access.Unlock()
+		json.NewEncoder(w).Encode(users)
+	}))
+	defer server.Close()
+
+	current := make(map[string]uint32)
+	add := func(u *protocol.MemoryUser) error {
+		if _, found := current[u.Email]; found {
+			t.Error("user ", u.Email, " added twice")
+		}
+		current[u.Email] = u.Level
+		return nil
+	}
+	remove := func(u *protocol.MemoryUser) {
+		delete(current, u.Email)
+	}
+
+	syncer, err := NewSyncer(&Config{Url: server.URL}, toMemoryUser, add, remove)
+	common.Must(err)
+	defer syncer.Close()
+
+	if r := cmp.Diff(current, map[string]uint32{"a": 0, "b": 0}); r != "" {
+		t.Error(r)
+	}
+
+	access.Lock()
+	users = []*User{{Email: "b", Level: 1}, {Email: "c"}}
+	access.Unlock()
+	common.Must(syncer.Sync())
+
+	if r := cmp.Diff(current, map[string]uint32{"b": 1, "c": 0}); r != "" {
+		t.Error(r)
+	}
+}
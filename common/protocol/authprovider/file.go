@@ -0,0 +1,101 @@
+package authprovider
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/task"
+)
+
+const fileCheckInterval = 5 * time.Second
+
+type fileProvider struct {
+	sync.RWMutex
+	path    string
+	modTime time.Time
+	size    int64
+	users   []*User
+	byKey   map[string]*User
+	watcher *task.Periodic
+}
+
+func newFileProvider(path string) (*fileProvider, error) {
+	p := &fileProvider{path: path}
+	if _, err := p.reload(); err != nil {
+		return nil, err
+	}
+	p.watcher = &task.Periodic{
+		Interval: fileCheckInterval,
+		Execute: func() error {
+			if changed, err := p.reload(); err != nil {
+				newError("failed to reload ", path).Base(err).AtWarning().WriteToLog()
+			} else if changed {
+				newError("reloaded users from ", path).AtInfo().WriteToLog()
+			}
+			return nil
+		},
+	}
+	common.Must(p.watcher.Start())
+	return p, nil
+}
+
+// reload reads the file again if its size or modification time has changed.
+// The previous users are kept if the new content is invalid.
+func (p *fileProvider) reload() (bool, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return false, newError("failed to stat ", p.path).Base(err)
+	}
+	p.RLock()
+	unchanged := info.ModTime().Equal(p.modTime) && info.Size() == p.size
+	p.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		return false, newError("failed to read ", p.path).Base(err)
+	}
+	var users []*User
+	if err := json.Unmarshal(content, &users); err != nil {
+		return false, newError("failed to parse ", p.path).Base(err)
+	}
+	byKey := make(map[string]*User, len(users))
+	for _, user := range users {
+		for _, key := range user.Keys() {
+			byKey[key] = user
+		}
+	}
+
+	p.Lock()
+	p.modTime = info.ModTime()
+	p.size = info.Size()
+	p.users = users
+	p.byKey = byKey
+	p.Unlock()
+	return true, nil
+}
+
+// Lookup implements Provider.
+func (p *fileProvider) Lookup(_ context.Context, key string) (*User, error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.byKey[key], nil
+}
+
+// List implements Lister.
+func (p *fileProvider) List(context.Context) ([]*User, error) {
+	p.RLock()
+	defer p.RUnlock()
+	return p.users, nil
+}
+
+// Close implements common.Closable.
+func (p *fileProvider) Close() error {
+	return p.watcher.Close()
+}
@@ -0,0 +1,81 @@
+package authprovider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const httpLookupTimeout = 5 * time.Second
+
+type httpProvider struct {
+	url    *url.URL
+	client *http.Client
+}
+
+func newHTTPProvider(rawURL string) (*httpProvider, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, newError("invalid url ", rawURL).Base(err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, newError("unsupported url scheme ", u.Scheme)
+	}
+	return &httpProvider{
+		url:    u,
+		client: &http.Client{Timeout: httpLookupTimeout},
+	}, nil
+}
+
+func (p *httpProvider) get(ctx context.Context, key string, v interface{}) (bool, error) {
+	u := *p.url
+	if key != "" {
+		query := u.Query()
+		query.Set("key", key)
+		u.RawQuery = query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false, newError("failed to query ", p.url.Host).Base(err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	default:
+		return false, newError("unexpected status ", resp.Status, " from ", p.url.Host)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return false, newError("invalid response from ", p.url.Host).Base(err)
+	}
+	return true, nil
+}
+
+// Lookup implements Provider.
+func (p *httpProvider) Lookup(ctx context.Context, key string) (*User, error) {
+	user := new(User)
+	found, err := p.get(ctx, key, user)
+	if !found {
+		return nil, err
+	}
+	return user, nil
+}
+
+// List implements Lister.
+func (p *httpProvider) List(ctx context.Context) ([]*User, error) {
+	var users []*User
+	if _, err := p.get(ctx, "", &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -0,0 +1,183 @@
+package authprovider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/signal/semaphore"
+	"github.com/v2fly/v2ray-core/v5/common/task"
+)
+
+const (
+	defaultTTL         = 300 * time.Second
+	defaultNegativeTTL = 30 * time.Second
+	cleanupInterval    = time.Minute
+
+	// Bounds of the work that unknown keys can cause, since anyone can send
+	// them. Lookups beyond maxPendingLookups fail, and misses are not cached
+	// once the cache has maxCacheEntries.
+	maxPendingLookups = 16
+	maxCacheEntries   = 1 << 16
+)
+
+func (c *Config) ttl() time.Duration {
+	if c.Ttl == 0 {
+		return defaultTTL
+	}
+	return time.Duration(c.Ttl) * time.Second
+}
+
+func (c *Config) negativeTTL() time.Duration {
+	if c.NegativeTtl == 0 {
+		return defaultNegativeTTL
+	}
+	return time.Duration(c.NegativeTtl) * time.Second
+}
+
+// ConvertFunc turns a User returned by a Provider into a protocol user. It
+// must fail if the user does not match the key it was looked up with.
+type ConvertFunc func(user *User, key string) (*protocol.MemoryUser, error)
+
+type cacheEntry struct {
+	user   *protocol.MemoryUser
+	expire time.Time
+	done   chan struct{}
+}
+
+// Resolver looks up users on demand and caches the results, including
+// misses, for the configured TTLs. Concurrent lookups of the same key share
+// a single query to the provider, and the number of pending queries is
+// limited.
+type Resolver struct {
+	provider    Provider
+	convert     ConvertFunc
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	access  sync.Mutex
+	cache   map[string]*cacheEntry
+	pending *semaphore.Instance
+	cleanup *task.Periodic
+}
+
+// NewResolver creates a Resolver for the provider described by config.
+func NewResolver(config *Config, convert ConvertFunc) (*Resolver, error) {
+	provider, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	r := &Resolver{
+		provider:    provider,
+		convert:     convert,
+		ttl:         config.ttl(),
+		negativeTTL: config.negativeTTL(),
+		cache:       make(map[string]*cacheEntry),
+		pending:     semaphore.New(maxPendingLookups),
+	}
+	r.cleanup = &task.Periodic{
+		Interval: cleanupInterval,
+		Execute: func() error {
+			r.removeExpired()
+			return nil
+		},
+	}
+	common.Must(r.cleanup.Start())
+	return r, nil
+}
+
+// Resolve returns the user for key, or nil if the provider does not know it.
+func (r *Resolver) Resolve(key string) *protocol.MemoryUser {
+	r.access.Lock()
+	if entry, found := r.cache[key]; found {
+		select {
+		case <-entry.done:
+			if time.Now().Before(entry.expire) {
+				r.access.Unlock()
+				return entry.user
+			}
+		default:
+			r.access.Unlock()
+			<-entry.done
+			return entry.user
+		}
+	}
+	select {
+	case <-r.pending.Wait():
+	default:
+		r.access.Unlock()
+		newError("too many pending user lookups").AtWarning().WriteToLog()
+		return nil
+	}
+	entry := &cacheEntry{done: make(chan struct{})}
+	r.cache[key] = entry
+	r.access.Unlock()
+
+	user, err := r.lookup(key)
+	r.pending.Signal()
+
+	r.access.Lock()
+	switch {
+	case err != nil:
+		// Errors are not cached, so the next attempt queries again.
+		newError("failed to resolve user").Base(err).AtWarning().WriteToLog()
+		r.remove(key, entry)
+	case user != nil:
+		entry.user = user
+		entry.expire = time.Now().Add(r.ttl)
+	case len(r.cache) > maxCacheEntries:
+		r.remove(key, entry)
+	default:
+		entry.expire = time.Now().Add(r.negativeTTL)
+	}
+	r.access.Unlock()
+	close(entry.done)
+	return user
+}
+
+// remove deletes the cache entry of key if it's still entry. It must be called
+// with access held.
+func (r *Resolver) remove(key string, entry *cacheEntry) {
+	if r.cache[key] == entry {
+		delete(r.cache, key)
+	}
+}
+
+func (r *Resolver) lookup(key string) (*protocol.MemoryUser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), httpLookupTimeout)
+	defer cancel()
+
+	user, err := r.provider.Lookup(ctx, key)
+	if err != nil || user == nil {
+		return nil, err
+	}
+	mUser, err := r.convert(user, key)
+	if err != nil {
+		return nil, newError("invalid user ", user.Email).Base(err)
+	}
+	return mUser, nil
+}
+
+func (r *Resolver) removeExpired() {
+	now := time.Now()
+	r.access.Lock()
+	defer r.access.Unlock()
+
+	for key, entry := range r.cache {
+		select {
+		case <-entry.done:
+			if now.After(entry.expire) {
+				delete(r.cache, key)
+			}
+		default:
+		}
+	}
+}
+
+// Close implements common.Closable.
+func (r *Resolver) Close() error {
+	r.cleanup.Close()
+	return common.Close(r.provider)
+}
@@ -0,0 +1,105 @@
+package authprovider
+
+import (
+	"context"
+	"sync"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/task"
+)
+
+// Syncer keeps an inbound's users in step with a Lister, for protocols that
+// cannot resolve users on demand. The list is fetched once on creation and
+// then every TTL.
+type Syncer struct {
+	lister  Lister
+	convert ConvertFunc
+	add     func(*protocol.MemoryUser) error
+	remove  func(*protocol.MemoryUser)
+
+	access sync.Mutex
+	users  map[User]*protocol.MemoryUser
+	task   *task.Periodic
+}
+
+// NewSyncer creates a Syncer for the provider described by config. Users
+// that appear in the list are passed to add, users that disappear from it
+// to remove.
+func NewSyncer(config *Config, convert ConvertFunc, add func(*protocol.MemoryUser) error, remove func(*protocol.MemoryUser)) (*Syncer, error) {
+	provider, err := New(config)
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := provider.(Lister)
+	if !ok {
+		common.Close(provider)
+		return nil, newError("auth provider cannot list users")
+	}
+	s := &Syncer{
+		lister:  lister,
+		convert: convert,
+		add:     add,
+		remove:  remove,
+		users:   make(map[User]*protocol.MemoryUser),
+	}
+	s.task = &task.Periodic{
+		Interval: config.ttl(),
+		Execute: func() error {
+			if err := s.Sync(); err != nil {
+				newError("failed to sync users").Base(err).AtWarning().WriteToLog()
+			}
+			return nil
+		},
+	}
+	common.Must(s.task.Start())
+	return s, nil
+}
+
+// Sync fetches the list of users and applies the difference.
+func (s *Syncer) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), httpLookupTimeout)
+	defer cancel()
+
+	list, err := s.lister.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	current := make(map[User]bool, len(list))
+	for _, user := range list {
+		current[*user] = true
+	}
+	// Removals go first, so a changed user is not briefly present twice.
+	for user, mUser := range s.users {
+		if !current[user] {
+			s.remove(mUser)
+			delete(s.users, user)
+		}
+	}
+	for _, user := range list {
+		if _, found := s.users[*user]; found {
+			continue
+		}
+		mUser, err := s.convert(user, "")
+		if err != nil {
+			newError("invalid user ", user.Email).Base(err).AtWarning().WriteToLog()
+			continue
+		}
+		if err := s.add(mUser); err != nil {
+			newError("failed to add user ", user.Email).Base(err).AtWarning().WriteToLog()
+			continue
+		}
+		s.users[*user] = mUser
+	}
+	return nil
+}
+
+// Close implements common.Closable.
+func (s *Syncer) Close() error {
+	s.task.Close()
+	return common.Close(s.lister)
+}
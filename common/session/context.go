@@ -15,6 +15,7 @@ const (
 	sockoptSessionKey
 	trackedConnectionErrorKey
 	handlerSessionKey // nolint: varcheck
+	chainSessionKey
 )
 
 // ContextWithID returns a new context with the given ID.
@@ -76,6 +77,25 @@ func SockoptFromContext(ctx context.Context) *Sockopt {
 	return nil
 }
 
+// ContextWithChain returns a new context with the outbound chain.
+func ContextWithChain(ctx context.Context, chain *Chain) context.Context {
+	return context.WithValue(ctx, chainSessionKey, chain)
+}
+
+// ChainFromContext returns the outbound chain in ctx, or nil if there is none.
+func ChainFromContext(ctx context.Context) *Chain {
+	if chain, ok := ctx.Value(chainSessionKey).(*Chain); ok {
+		return chain
+	}
+	return nil
+}
+
+// ContextWithChainHop returns a new context, in which errors submitted to the
+// originator are recorded as the errors of hop in chain.
+func ContextWithChainHop(ctx context.Context, chain *Chain, hop int) context.Context {
+	return TrackedConnectionError(ctx, chainHop{chain: chain, hop: hop})
+}
+
 func GetTransportLayerProxyTagFromContext(ctx context.Context) string {
 	if ContentFromContext(ctx) == nil {
 		return ""
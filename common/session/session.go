@@ -4,9 +4,11 @@ package session
 import (
 	"context"
 	"math/rand"
+	"sync"
 
 	"github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/process"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 )
 
@@ -46,11 +48,18 @@ type Inbound struct {
 
 	// Conn is actually internet.Connection. May be nil.
 	Conn net.Conn
+	// CanSpliceCopy is set by inbounds that relay the payload of Conn as is,
+	// so that outbounds may splice raw data into Conn.
+	CanSpliceCopy bool
 
 	// SagerNet private
 	Uid         uint32
 	NetworkType string
 	WifiSSID    string
+
+	// Process is the local process that the connection comes from. It is
+	// resolved on demand by routing, and left empty if not found.
+	Process *process.Info
 }
 
 // Outbound is the metadata of an outbound connection.
@@ -82,6 +91,64 @@ type Content struct {
 	SkipDNSResolve bool
 }
 
+// Chain is the outbound handlers that a connection goes through in order. Each
+// handler in it dials through the one before it, and the first dials directly.
+type Chain struct {
+	// Tags of the handlers, from the first hop to the last.
+	Tags []string
+
+	access sync.Mutex
+	errors []error
+}
+
+// Hop returns the index of tag in the chain, or -1 if tag is not in it.
+func (c *Chain) Hop(tag string) int {
+	for i, t := range c.Tags {
+		if t == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+// SubmitHopError records err as the error of the handler at hop. Only the
+// first error of each hop is kept.
+func (c *Chain) SubmitHopError(hop int, err error) {
+	if err == nil || hop < 0 || hop >= len(c.Tags) {
+		return
+	}
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.errors == nil {
+		c.errors = make([]error, len(c.Tags))
+	}
+	if c.errors[hop] == nil {
+		c.errors[hop] = err
+	}
+}
+
+// FirstError returns the error of the first hop that failed, which is the
+// cause of the failures of the hops after it. hop is -1 if no hop failed.
+func (c *Chain) FirstError() (hop int, err error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	for i, err := range c.errors {
+		if err != nil {
+			return i, err
+		}
+	}
+	return -1, nil
+}
+
+type chainHop struct {
+	chain *Chain
+	hop   int
+}
+
+func (h chainHop) SubmitError(err error) {
+	h.chain.SubmitHopError(h.hop, err)
+}
+
 // Sockopt is the settings for socket connection.
 type Sockopt struct {
 	// Mark of the socket connection.
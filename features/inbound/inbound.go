@@ -20,6 +20,14 @@ type Handler interface {
 	GetRandomInboundProxy() (interface{}, net.Port, int)
 }
 
+// ConnectionHandler is a Handler that processes connections accepted
+// elsewhere, such as the fallback connections of another inbound.
+type ConnectionHandler interface {
+	// HandleConnection processes conn as if it were accepted by this handler,
+	// and returns after conn is closed.
+	HandleConnection(conn net.Conn) error
+}
+
 type Initializer interface {
 	Initialize(self Handler)
 }
@@ -27,6 +27,21 @@ type HandlerSelector interface {
 	Select([]string) []string
 }
 
+// ResultListener is notified of the results of connections made by outbound
+// handlers.
+type ResultListener interface {
+	// OnResult is called with the error that failed a connection through the
+	// handler with tag, or nil if the connection succeeded.
+	OnResult(tag string, err error)
+}
+
+// ResultNotifier is a Manager that notifies listeners of the results of
+// connections made by its handlers.
+type ResultNotifier interface {
+	AddResultListener(listener ResultListener)
+	NotifyResult(tag string, err error)
+}
+
 // Manager is a feature that manages outbound.Handlers.
 //
 // v2ray:api:stable
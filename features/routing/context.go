@@ -43,4 +43,10 @@ type Context interface {
 	GetUid() uint32
 	GetWifiSsid() string
 	GetNetworkType() string
+
+	// GetProcessName returns the executable name of the local process that the connection comes from, if found.
+	GetProcessName() string
+
+	// GetProcessPath returns the executable path of the local process that the connection comes from, if found.
+	GetProcessPath() string
 }
@@ -27,6 +27,12 @@ type Route interface {
 
 	// GetOutboundTag returns the tag of the outbound the connection was dispatched to.
 	GetOutboundTag() string
+
+	// GetMatchedRule returns a description of the routing rule that the route was decided by.
+	GetMatchedRule() string
+
+	// GetRuleTag returns the tag of the routing rule that the route was decided by.
+	GetRuleTag() string
 }
 
 // RouterType return the type of Router interface. Can be used to implement common.HasType.
@@ -1,9 +1,12 @@
 package session
 
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
 import (
 	"context"
 
 	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/process"
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
 )
@@ -144,6 +147,38 @@ func (ctx *Context) GetNetworkType() string {
 	return ctx.Inbound.NetworkType
 }
 
+// GetProcessName implements routing.Context.
+func (ctx *Context) GetProcessName() string {
+	if info := ctx.processInfo(); info != nil {
+		return info.Name
+	}
+	return ""
+}
+
+// GetProcessPath implements routing.Context.
+func (ctx *Context) GetProcessPath() string {
+	if info := ctx.processInfo(); info != nil {
+		return info.Path
+	}
+	return ""
+}
+
+// processInfo resolves the process of the inbound connection once, and caches it in the inbound.
+func (ctx *Context) processInfo() *process.Info {
+	if ctx.Inbound == nil {
+		return nil
+	}
+	if ctx.Inbound.Process == nil {
+		info, err := process.FindByLocalAddress(ctx.Inbound.Source)
+		if err != nil {
+			newError("failed to find process of ", ctx.Inbound.Source).Base(err).AtDebug().WriteToLog()
+			info = &process.Info{}
+		}
+		ctx.Inbound.Process = info
+	}
+	return ctx.Inbound.Process
+}
+
 // AsRoutingContext creates a context from context.context with session info.
 func AsRoutingContext(ctx context.Context) routing.Context {
 	return &Context{
@@ -56,6 +56,24 @@ func (v *Address) Build() *net.IPOrDomain {
 	return net.NewIPOrDomain(v.Address)
 }
 
+// AddressList is a list of addresses, or a single address.
+type AddressList []*Address
+
+func (v *AddressList) UnmarshalJSON(data []byte) error {
+	var list []*Address
+	if err := json.Unmarshal(data, &list); err == nil {
+		*v = list
+		return nil
+	}
+
+	address := new(Address)
+	if err := address.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*v = AddressList{address}
+	return nil
+}
+
 type Network string
 
 func (v Network) Build() net.Network {
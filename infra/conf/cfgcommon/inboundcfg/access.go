@@ -0,0 +1,74 @@
+package inboundcfg
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/v2fly/v2ray-core/v5/app/proxyman"
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+)
+
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
+type AccessControlConfig struct {
+	Allow *cfgcommon.StringList `json:"allow"`
+	Deny  *cfgcommon.StringList `json:"deny"`
+}
+
+// parseCIDR parses an IP address, or a CIDR such as "10.0.0.0/8".
+func parseCIDR(s string) (*routercommon.CIDR, error) {
+	addr, mask := s, ""
+	if i := strings.Index(s, "/"); i >= 0 {
+		addr, mask = s[:i], s[i+1:]
+	}
+	ip := net.ParseAddress(addr)
+	if !ip.Family().IsIP() {
+		return nil, newError("invalid IP: ", s)
+	}
+	bits := uint32(len(ip.IP()) * 8)
+	prefix := bits
+	if len(mask) > 0 {
+		p, err := strconv.ParseUint(mask, 10, 32)
+		if err != nil || uint32(p) > bits {
+			return nil, newError("invalid network mask: ", s)
+		}
+		prefix = uint32(p)
+	}
+	return &routercommon.CIDR{
+		Ip:     ip.IP(),
+		Prefix: prefix,
+	}, nil
+}
+
+func parseCIDRs(list *cfgcommon.StringList) ([]*routercommon.CIDR, error) {
+	if list == nil {
+		return nil, nil
+	}
+	cidrs := make([]*routercommon.CIDR, 0, len(*list))
+	for _, s := range *list {
+		cidr, err := parseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
+// Build implements Buildable.
+func (c *AccessControlConfig) Build() (*proxyman.AccessControlConfig, error) {
+	allow, err := parseCIDRs(c.Allow)
+	if err != nil {
+		return nil, newError("failed to parse allowed sources").Base(err)
+	}
+	deny, err := parseCIDRs(c.Deny)
+	if err != nil {
+		return nil, newError("failed to parse denied sources").Base(err)
+	}
+	return &proxyman.AccessControlConfig{
+		Allow: allow,
+		Deny:  deny,
+	}, nil
+}
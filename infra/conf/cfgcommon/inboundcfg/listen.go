@@ -0,0 +1,84 @@
+package inboundcfg
+
+import (
+	"github.com/v2fly/v2ray-core/v5/app/proxyman"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+)
+
+// PortList is a comma separated list of ports and port ranges, such as
+// "80,443,1000-2000", or a single port range in any format of PortRange.
+type PortList struct {
+	cfgcommon.PortList
+}
+
+// UnmarshalJSON implements encoding/json.Unmarshaler.UnmarshalJSON
+func (list *PortList) UnmarshalJSON(data []byte) error {
+	var portRange cfgcommon.PortRange
+	if err := portRange.UnmarshalJSON(data); err == nil {
+		list.Range = []cfgcommon.PortRange{portRange}
+		return nil
+	}
+	return list.PortList.UnmarshalJSON(data)
+}
+
+func isDomainSocket(address *cfgcommon.Address) bool {
+	return address.Family().IsDomain() && (address.Domain()[0] == '/' || address.Domain()[0] == '@')
+}
+
+func isIP(address *cfgcommon.Address) bool {
+	return address.Family().IsIP() || (address.Family().IsDomain() && address.Domain() == "localhost")
+}
+
+// BuildListen sets the listen addresses and ports of an inbound on receiver.
+// IP addresses listen on all the ports, while unix domain sockets take no port.
+func BuildListen(protocol string, listen cfgcommon.AddressList, ports *PortList, receiver *proxyman.ReceiverConfig) error {
+	if protocol == "tun" {
+		// TUN inbound reads packets from a device, and listens on nothing.
+		if ports != nil || len(listen) > 0 {
+			return newError("tun inbound does not listen on any address or port")
+		}
+		return nil
+	}
+
+	needPorts := len(listen) == 0
+	for idx, address := range listen {
+		switch {
+		case isIP(address):
+			needPorts = true
+		case isDomainSocket(address):
+		default:
+			return newError("unable to listen on domain address: ", address.Domain())
+		}
+		if idx == 0 {
+			receiver.Listen = address.Build()
+		} else {
+			receiver.AdditionalListen = append(receiver.AdditionalListen, address.Build())
+		}
+	}
+	if !needPorts {
+		// Listen on Unix Domain Sockets only, ports should be nil.
+		return nil
+	}
+	if ports == nil || len(ports.Range) == 0 {
+		if len(listen) == 0 {
+			return newError("Listen on AnyIP but no Port(s) set in InboundDetour.")
+		}
+		return newError("Listen on specific ip without port in InboundDetour.")
+	}
+	for _, portRange := range ports.Range {
+		if portRange.From > portRange.To {
+			return newError("invalid port range ", portRange.From, " -> ", portRange.To)
+		}
+	}
+
+	// The first range stays in PortRange, which port allocation works on.
+	receiver.PortRange = ports.Range[0].Build()
+	if len(ports.Range) > 1 {
+		receiver.PortList = &net.PortList{}
+		for _, portRange := range ports.Range[1:] {
+			receiver.PortList.Range = append(receiver.PortList.Range, portRange.Build())
+		}
+	}
+	return nil
+}
@@ -14,6 +14,11 @@ type SocketConfig struct {
 	TCPKeepAliveInterval int32  `json:"tcpKeepAliveInterval"`
 	TCPKeepAliveIdle     int32  `json:"tcpKeepAliveIdle"`
 	TFOQueueLength       uint32 `json:"tcpFastOpenQueueLength"`
+	BindToDevice         string `json:"bindToDevice"`
+	TCPCongestion        string `json:"tcpCongestion"`
+	TCPUserTimeout       uint32 `json:"tcpUserTimeout"`
+	V6Only               bool   `json:"v6only"`
+	MPTCP                bool   `json:"mptcp"`
 }
 
 // Build implements Buildable.
@@ -50,5 +55,10 @@ func (c *SocketConfig) Build() (*internet.SocketConfig, error) {
 		AcceptProxyProtocol:  c.AcceptProxyProtocol,
 		TcpKeepAliveInterval: c.TCPKeepAliveInterval,
 		TcpKeepAliveIdle:     c.TCPKeepAliveIdle,
+		BindToDevice:         c.BindToDevice,
+		TcpCongestion:        c.TCPCongestion,
+		TcpUserTimeout:       c.TCPUserTimeout,
+		V6Only:               c.V6Only,
+		Mptcp:                c.MPTCP,
 	}, nil
 }
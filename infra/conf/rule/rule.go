@@ -201,7 +201,130 @@ func toCidrList(ctx context.Context, ips cfgcommon.StringList) ([]*routercommon.
 	return geoipList, nil
 }
 
-func parseFieldRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRule, error) {
+type LogicalRule struct {
+	Mode   string            `json:"mode"`
+	Rules  []json.RawMessage `json:"rules"`
+	Invert bool              `json:"invert"`
+}
+
+func (r *LogicalRule) Build(ctx context.Context) (*router.LogicalRule, error) {
+	if len(r.Rules) == 0 {
+		return nil, newError("empty logical rule")
+	}
+	rule := &router.LogicalRule{
+		Invert: r.Invert,
+	}
+	switch strings.ToLower(r.Mode) {
+	case "and", "":
+		rule.Mode = router.LogicalRule_And
+	case "or":
+		rule.Mode = router.LogicalRule_Or
+	case "not":
+		rule.Mode = router.LogicalRule_And
+		rule.Invert = !r.Invert
+	default:
+		return nil, newError("unknown logical rule mode: ", r.Mode)
+	}
+	for _, rawRule := range r.Rules {
+		nestedRule, err := parseFieldRule(ctx, rawRule, true)
+		if err != nil {
+			return nil, newError("invalid nested rule").Base(err)
+		}
+		rule.Rule = append(rule.Rule, nestedRule)
+	}
+	return rule, nil
+}
+
+type ScheduleWindow struct {
+	Weekdays *cfgcommon.StringList `json:"weekdays"`
+	Time     string                `json:"time"`
+}
+
+var weekdayNames = map[string]uint32{
+	"sun": 0, "sunday": 0,
+	"mon": 1, "monday": 1,
+	"tue": 2, "tuesday": 2,
+	"wed": 3, "wednesday": 3,
+	"thu": 4, "thursday": 4,
+	"fri": 5, "friday": 5,
+	"sat": 6, "saturday": 6,
+}
+
+// parseTimeOfDay parses "hh:mm" into minutes since midnight. "24:00" is
+// accepted as the end of a day.
+func parseTimeOfDay(s string) (uint32, error) {
+	hour, minute, found := strings.Cut(strings.TrimSpace(s), ":")
+	if !found {
+		return 0, newError("invalid time of day: ", s)
+	}
+	h, err := strconv.ParseUint(hour, 10, 32)
+	if err != nil {
+		return 0, newError("invalid time of day: ", s).Base(err)
+	}
+	m, err := strconv.ParseUint(minute, 10, 32)
+	if err != nil {
+		return 0, newError("invalid time of day: ", s).Base(err)
+	}
+	if m >= 60 || h*60+m > 24*60 {
+		return 0, newError("invalid time of day: ", s)
+	}
+	return uint32(h*60+m) % (24 * 60), nil
+}
+
+func (w *ScheduleWindow) Build() (*router.Schedule_Window, error) {
+	start, end, found := strings.Cut(w.Time, "-")
+	if !found {
+		return nil, newError("invalid time range: ", w.Time)
+	}
+	window := new(router.Schedule_Window)
+	var err error
+	if window.Start, err = parseTimeOfDay(start); err != nil {
+		return nil, err
+	}
+	if window.End, err = parseTimeOfDay(end); err != nil {
+		return nil, err
+	}
+	if w.Weekdays != nil {
+		for _, day := range *w.Weekdays {
+			weekday, found := weekdayNames[strings.ToLower(day)]
+			if !found {
+				n, err := strconv.ParseUint(day, 10, 32)
+				if err != nil || n > 6 {
+					return nil, newError("invalid weekday: ", day)
+				}
+				weekday = uint32(n)
+			}
+			window.Weekday = append(window.Weekday, weekday)
+		}
+	}
+	return window, nil
+}
+
+type Schedule struct {
+	Timezone string            `json:"timezone"`
+	Windows  []*ScheduleWindow `json:"windows"`
+}
+
+func (s *Schedule) Build() (*router.Schedule, error) {
+	if len(s.Windows) == 0 {
+		return nil, newError("empty schedule")
+	}
+	schedule := &router.Schedule{
+		Timezone: s.Timezone,
+	}
+	for _, w := range s.Windows {
+		window, err := w.Build()
+		if err != nil {
+			return nil, err
+		}
+		schedule.Window = append(schedule.Window, window)
+	}
+	return schedule, nil
+}
+
+// parseFieldRule parses a field rule. Nested rules in logical rules have no
+// target tags.
+func parseFieldRule(ctx context.Context, msg json.RawMessage, nested bool) (*router.RoutingRule, error) {
 	type RawFieldRule struct {
 		RouterRule
 		Domain       *cfgcommon.StringList  `json:"domain"`
@@ -218,6 +341,12 @@ func parseFieldRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRu
 		UidList      *cfgcommon.UidList     `json:"uidList"`
 		WifiSSIDList *cfgcommon.StringList  `json:"ssidList"`
 		NetworkType  string                 `json:"networkType"`
+		ProcessName  *cfgcommon.StringList  `json:"processName"`
+		ProcessPath  *cfgcommon.StringList  `json:"processPath"`
+		RuleSet      *cfgcommon.StringList  `json:"ruleSet"`
+		Logical      *LogicalRule           `json:"logical"`
+		RuleTag      string                 `json:"ruleTag"`
+		Schedule     *Schedule              `json:"schedule"`
 	}
 	rawFieldRule := new(RawFieldRule)
 	err := json.Unmarshal(msg, rawFieldRule)
@@ -227,6 +356,7 @@ func parseFieldRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRu
 
 	rule := new(router.RoutingRule)
 	switch {
+	case nested:
 	case len(rawFieldRule.OutboundTag) > 0:
 		rule.TargetTag = &router.RoutingRule_Tag{
 			Tag: rawFieldRule.OutboundTag,
@@ -321,6 +451,36 @@ func parseFieldRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRu
 		rule.WifiSsidList = *rawFieldRule.WifiSSIDList
 	}
 
+	if rawFieldRule.ProcessName != nil && rawFieldRule.ProcessName.Len() > 0 {
+		rule.ProcessName = *rawFieldRule.ProcessName
+	}
+
+	if rawFieldRule.ProcessPath != nil && rawFieldRule.ProcessPath.Len() > 0 {
+		rule.ProcessPath = *rawFieldRule.ProcessPath
+	}
+
+	if rawFieldRule.RuleSet != nil && rawFieldRule.RuleSet.Len() > 0 {
+		rule.RuleSet = *rawFieldRule.RuleSet
+	}
+
+	if len(rawFieldRule.RuleTag) > 0 {
+		rule.RuleTag = rawFieldRule.RuleTag
+	}
+
+	if rawFieldRule.Schedule != nil {
+		rule.Schedule, err = rawFieldRule.Schedule.Build()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rawFieldRule.Logical != nil {
+		rule.Logical, err = rawFieldRule.Logical.Build(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return rule, nil
 }
 
@@ -331,7 +491,7 @@ func ParseRule(ctx context.Context, msg json.RawMessage) (*router.RoutingRule, e
 		return nil, newError("invalid router rule").Base(err)
 	}
 	if strings.EqualFold(rawRule.Type, "field") {
-		fieldrule, err := parseFieldRule(ctx, msg)
+		fieldrule, err := parseFieldRule(ctx, msg, false)
 		if err != nil {
 			return nil, newError("invalid field rule").Base(err)
 		}
@@ -26,6 +26,8 @@ type NameServerConfig struct {
 	Domains      []string
 	ExpectIPs    cfgcommon.StringList
 	Concurrency  bool
+	DNSSEC       bool
+	TimeoutMs    uint32
 
 	cfgctx context.Context
 }
@@ -43,6 +45,8 @@ func (c *NameServerConfig) UnmarshalJSON(data []byte) (err error) {
 			Domains      []string             `json:"domains"`
 			ExpectIPs    cfgcommon.StringList `json:"expectIps"`
 			Concurrency  bool                 `json:"concurrency"`
+			DNSSEC       bool                 `json:"dnssec"`
+			TimeoutMs    uint32               `json:"timeoutMs"`
 		}
 		if err = json.Unmarshal(data, &advanced); err == nil {
 			c.Address = advanced.Address
@@ -52,6 +56,8 @@ func (c *NameServerConfig) UnmarshalJSON(data []byte) (err error) {
 			c.Domains = advanced.Domains
 			c.ExpectIPs = advanced.ExpectIPs
 			c.Concurrency = advanced.Concurrency
+			c.DNSSEC = advanced.DNSSEC
+			c.TimeoutMs = advanced.TimeoutMs
 		}
 	}
 
@@ -86,25 +92,14 @@ func toDomainMatchingType(t routercommon.Domain_Type) dns.DomainMatchingType {
 	}
 }
 
-func (c *NameServerConfig) BuildV5(ctx context.Context) (*dns.NameServer, error) {
-	c.cfgctx = ctx
-	return c.Build()
-}
-
-func (c *NameServerConfig) Build() (*dns.NameServer, error) {
-	cfgctx := c.cfgctx
-
-	if c.Address == nil {
-		return nil, newError("NameServer address is not specified.")
-	}
-
+func parseDomainRules(ctx context.Context, rules []string) ([]*dns.NameServer_PriorityDomain, []*dns.NameServer_OriginalRule, error) {
 	var domains []*dns.NameServer_PriorityDomain
 	var originalRules []*dns.NameServer_OriginalRule
 
-	for _, rule := range c.Domains {
-		parsedDomain, err := rule2.ParseDomainRule(cfgctx, rule)
+	for _, rule := range rules {
+		parsedDomain, err := rule2.ParseDomainRule(ctx, rule)
 		if err != nil {
-			return nil, newError("invalid domain rule: ", rule).Base(err)
+			return nil, nil, newError("invalid domain rule: ", rule).Base(err)
 		}
 
 		for _, pd := range parsedDomain {
@@ -119,6 +114,37 @@ func (c *NameServerConfig) Build() (*dns.NameServer, error) {
 		})
 	}
 
+	return domains, originalRules, nil
+}
+
+func toQueryStrategy(s string) dns.QueryStrategy {
+	switch strings.ToLower(s) {
+	case "useip4", "useipv4", "use_ip4", "use_ipv4", "use_ip_v4", "use-ip4", "use-ipv4", "use-ip-v4":
+		return dns.QueryStrategy_USE_IP4
+	case "useip6", "useipv6", "use_ip6", "use_ipv6", "use_ip_v6", "use-ip6", "use-ipv6", "use-ip-v6":
+		return dns.QueryStrategy_USE_IP6
+	default:
+		return dns.QueryStrategy_USE_IP
+	}
+}
+
+func (c *NameServerConfig) BuildV5(ctx context.Context) (*dns.NameServer, error) {
+	c.cfgctx = ctx
+	return c.Build()
+}
+
+func (c *NameServerConfig) Build() (*dns.NameServer, error) {
+	cfgctx := c.cfgctx
+
+	if c.Address == nil {
+		return nil, newError("NameServer address is not specified.")
+	}
+
+	domains, originalRules, err := parseDomainRules(cfgctx, c.Domains)
+	if err != nil {
+		return nil, err
+	}
+
 	geoipList, err := rule2.ToCidrList(cfgctx, c.ExpectIPs)
 	if err != nil {
 		return nil, newError("invalid IP rule: ", c.ExpectIPs).Base(err)
@@ -144,9 +170,37 @@ func (c *NameServerConfig) Build() (*dns.NameServer, error) {
 		Geoip:             geoipList,
 		OriginalRules:     originalRules,
 		Concurrency:       c.Concurrency,
+		Dnssec:            c.DNSSEC,
+		TimeoutMs:         c.TimeoutMs,
 	}, nil
 }
 
+// DomainOverrideConfig is a JSON serializable object for dns.DomainOverride.
+type DomainOverrideConfig struct {
+	Domains       []string           `json:"domains"`
+	ClientIP      *cfgcommon.Address `json:"clientIp"`
+	QueryStrategy string             `json:"queryStrategy"`
+}
+
+func (c *DomainOverrideConfig) Build(ctx context.Context) (*dns.DomainOverride, error) {
+	domains, _, err := parseDomainRules(ctx, c.Domains)
+	if err != nil {
+		return nil, err
+	}
+
+	override := &dns.DomainOverride{
+		Domain:        domains,
+		QueryStrategy: toQueryStrategy(c.QueryStrategy),
+	}
+	if c.ClientIP != nil {
+		if !c.ClientIP.Family().IsIP() {
+			return nil, newError("not an IP address:", c.ClientIP.String())
+		}
+		override.ClientIp = []byte(c.ClientIP.IP())
+	}
+	return override, nil
+}
+
 var typeMap = map[routercommon.Domain_Type]dns.DomainMatchingType{
 	routercommon.Domain_Full:       dns.DomainMatchingType_Full,
 	routercommon.Domain_RootDomain: dns.DomainMatchingType_Subdomain,
@@ -165,6 +219,10 @@ type DNSConfig struct { // nolint: revive
 	DisableFallback        bool                    `json:"disableFallback"`
 	DisableFallbackIfMatch bool                    `json:"disableFallbackIfMatch"`
 	DisableExpire          bool                    `json:"disableExpire"`
+	DNSSECTrustAnchor      cfgcommon.StringList    `json:"dnssecTrustAnchor"`
+	DomainOverrides        []*DomainOverrideConfig `json:"domainOverrides"`
+	ServerStrategy         string                  `json:"serverStrategy"`
+	ParallelServers        uint32                  `json:"parallelServers"`
 	cfgctx                 context.Context
 }
 
@@ -244,6 +302,8 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 		DisableFallback:        c.DisableFallback,
 		DisableFallbackIfMatch: c.DisableFallbackIfMatch,
 		DisableExpire:          c.DisableExpire,
+		DnssecTrustAnchor:      c.DNSSECTrustAnchor,
+		ParallelServerCount:    c.ParallelServers,
 	}
 
 	if c.ClientIP != nil {
@@ -253,14 +313,17 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 		config.ClientIp = []byte(c.ClientIP.IP())
 	}
 
-	config.QueryStrategy = dns.QueryStrategy_USE_IP
-	switch strings.ToLower(c.QueryStrategy) {
-	case "useip", "use_ip", "use-ip":
-		config.QueryStrategy = dns.QueryStrategy_USE_IP
-	case "useip4", "useipv4", "use_ip4", "use_ipv4", "use_ip_v4", "use-ip4", "use-ipv4", "use-ip-v4":
-		config.QueryStrategy = dns.QueryStrategy_USE_IP4
-	case "useip6", "useipv6", "use_ip6", "use_ipv6", "use_ip_v6", "use-ip6", "use-ipv6", "use-ip-v6":
-		config.QueryStrategy = dns.QueryStrategy_USE_IP6
+	config.QueryStrategy = toQueryStrategy(c.QueryStrategy)
+
+	switch strings.ToLower(c.ServerStrategy) {
+	case "", "sequential":
+		config.ServerStrategy = dns.ServerStrategy_SEQUENTIAL
+	case "fastest":
+		config.ServerStrategy = dns.ServerStrategy_FASTEST
+	case "merge":
+		config.ServerStrategy = dns.ServerStrategy_MERGE
+	default:
+		return nil, newError("unknown server strategy: ", c.ServerStrategy)
 	}
 
 	for _, server := range c.Servers {
@@ -272,6 +335,14 @@ func (c *DNSConfig) Build() (*dns.Config, error) {
 		config.NameServer = append(config.NameServer, ns)
 	}
 
+	for _, override := range c.DomainOverrides {
+		o, err := override.Build(c.cfgctx)
+		if err != nil {
+			return nil, newError("failed to build domain override").Base(err)
+		}
+		config.DomainOverride = append(config.DomainOverride, o)
+	}
+
 	if c.Hosts != nil {
 		mappings := make([]*dns.HostMapping, 0, 20)
 
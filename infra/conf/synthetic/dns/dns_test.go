@@ -148,5 +148,96 @@ func TestDNSConfigParsing(t *testing.T) {
 				DisableFallback: true,
 			},
 		},
+		{
+			Input: `{
+				"servers": ["8.8.8.8"],
+				"domainOverrides": [{
+					"domains": ["domain:v2fly.org", "full:example.com"],
+					"clientIp": "10.0.0.1"
+				}, {
+					"domains": ["keyword:google"],
+					"clientIp": "0.0.0.0",
+					"queryStrategy": "UseIPv6"
+				}]
+			}`,
+			Parser: parserCreator(),
+			Output: &dns.Config{
+				NameServer: []*dns.NameServer{
+					{
+						Address: &net.Endpoint{
+							Address: &net.IPOrDomain{
+								Address: &net.IPOrDomain_Ip{
+									Ip: []byte{8, 8, 8, 8},
+								},
+							},
+							Network: net.Network_UDP,
+						},
+					},
+				},
+				DomainOverride: []*dns.DomainOverride{
+					{
+						Domain: []*dns.NameServer_PriorityDomain{
+							{
+								Type:   dns.DomainMatchingType_Subdomain,
+								Domain: "v2fly.org",
+							},
+							{
+								Type:   dns.DomainMatchingType_Full,
+								Domain: "example.com",
+							},
+						},
+						ClientIp: []byte{10, 0, 0, 1},
+					},
+					{
+						Domain: []*dns.NameServer_PriorityDomain{
+							{
+								Type:   dns.DomainMatchingType_Keyword,
+								Domain: "google",
+							},
+						},
+						ClientIp:      []byte{0, 0, 0, 0},
+						QueryStrategy: dns.QueryStrategy_USE_IP6,
+					},
+				},
+			},
+		},
+		{
+			Input: `{
+				"servers": [{
+					"address": "8.8.8.8",
+					"timeoutMs": 500
+				}, "1.1.1.1"],
+				"serverStrategy": "merge",
+				"parallelServers": 2
+			}`,
+			Parser: parserCreator(),
+			Output: &dns.Config{
+				NameServer: []*dns.NameServer{
+					{
+						Address: &net.Endpoint{
+							Address: &net.IPOrDomain{
+								Address: &net.IPOrDomain_Ip{
+									Ip: []byte{8, 8, 8, 8},
+								},
+							},
+							Network: net.Network_UDP,
+						},
+						TimeoutMs: 500,
+					},
+					{
+						Address: &net.Endpoint{
+							Address: &net.IPOrDomain{
+								Address: &net.IPOrDomain_Ip{
+									Ip: []byte{1, 1, 1, 1},
+								},
+							},
+							Network: net.Network_UDP,
+						},
+					},
+				},
+				ServerStrategy:      dns.ServerStrategy_MERGE,
+				ParallelServerCount: 2,
+			},
+		},
 	})
 }
@@ -52,6 +52,14 @@ func (r *BalancingRule) Build() (*router.BalancingRule, error) {
 		strategy = strategyLeastLoad
 	case strategyLeastPing:
 		strategy = "leastping"
+	case strategyConsistentHash:
+		strategy = strategyConsistentHash
+	case strategyFallback:
+		strategy = strategyFallback
+	case strategyRoundRobin:
+		strategy = strategyRoundRobin
+	case strategyWeighted:
+		strategy = strategyWeighted
 	default:
 		return nil, newError("unknown balancing strategy: " + r.Strategy.Type)
 	}
@@ -81,14 +89,60 @@ func (r *BalancingRule) Build() (*router.BalancingRule, error) {
 	}, nil
 }
 
+type RuleSetConfig struct {
+	Tag                 string `json:"tag"`
+	Path                string `json:"path"`
+	URL                 string `json:"url"`
+	Format              string `json:"format"`
+	Code                string `json:"code"`
+	DownloadOutboundTag string `json:"downloadOutboundTag"`
+	RefreshInterval     uint32 `json:"refreshInterval"`
+}
+
+// Build builds the rule set
+func (c *RuleSetConfig) Build() (*router.RuleSet, error) {
+	if c.Tag == "" {
+		return nil, newError("empty rule set tag")
+	}
+	if (c.Path == "") == (c.URL == "") {
+		return nil, newError("exactly one of path and url must be set for rule set ", c.Tag)
+	}
+
+	ruleSet := &router.RuleSet{
+		Tag:                 c.Tag,
+		Path:                c.Path,
+		Url:                 c.URL,
+		Code:                c.Code,
+		DownloadOutboundTag: c.DownloadOutboundTag,
+		RefreshInterval:     c.RefreshInterval,
+	}
+	switch strings.ToLower(c.Format) {
+	case "plain", "text", "":
+		ruleSet.Format = router.RuleSet_Plain
+	case "geosite":
+		ruleSet.Format = router.RuleSet_GeoSite
+	case "geoip":
+		ruleSet.Format = router.RuleSet_GeoIP
+	default:
+		return nil, newError("unknown rule set format: ", c.Format)
+	}
+	if ruleSet.Format != router.RuleSet_Plain && c.Code == "" {
+		return nil, newError("empty code for rule set ", c.Tag)
+	}
+	return ruleSet, nil
+}
+
 type RouterConfig struct { // nolint: revive
 	Settings       *RouterRulesConfig `json:"settings"` // Deprecated
 	RuleList       []json.RawMessage  `json:"rules"`
 	DomainStrategy *string            `json:"domainStrategy"`
 	Balancers      []*BalancingRule   `json:"balancers"`
+	RuleSets       []*RuleSetConfig   `json:"ruleSets"`
 
 	DomainMatcher string `json:"domainMatcher"`
 
+	TraceSampleRate float32 `json:"traceSampleRate"`
+
 	cfgctx context.Context
 }
 
@@ -120,6 +174,10 @@ func (c *RouterConfig) BuildV5(ctx context.Context) (*router.Config, error) {
 func (c *RouterConfig) Build() (*router.Config, error) {
 	config := new(router.Config)
 	config.DomainStrategy = c.getDomainStrategy()
+	if c.TraceSampleRate < 0 || c.TraceSampleRate > 1 {
+		return nil, newError("trace sample rate must be between 0 and 1")
+	}
+	config.TraceSampleRate = c.TraceSampleRate
 
 	if c.cfgctx == nil {
 		c.cfgctx = cfgcommon.NewConfigureLoadingContext(context.Background())
@@ -163,5 +221,12 @@ func (c *RouterConfig) Build() (*router.Config, error) {
 		}
 		config.BalancingRule = append(config.BalancingRule, balancer)
 	}
+	for _, rawRuleSet := range c.RuleSets {
+		ruleSet, err := rawRuleSet.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.RuleSet = append(config.RuleSet, ruleSet)
+	}
 	return config, nil
 }
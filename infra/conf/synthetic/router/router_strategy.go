@@ -1,6 +1,8 @@
 package router
 
 import (
+	"strings"
+
 	"github.com/golang/protobuf/proto"
 	"github.com/v2fly/v2ray-core/v5/app/observatory/burst"
 	"github.com/v2fly/v2ray-core/v5/app/router"
@@ -12,12 +14,22 @@ const (
 	strategyRandom    string = "random"
 	strategyLeastLoad string = "leastload"
 	strategyLeastPing string = "leastping"
+
+	strategyConsistentHash string = "consistenthash"
+	strategyFallback       string = "fallback"
+	strategyRoundRobin     string = "roundrobin"
+	strategyWeighted       string = "weighted"
 )
 
 var strategyConfigLoader = loader.NewJSONConfigLoader(loader.ConfigCreatorCache{
 	strategyRandom:    func() interface{} { return new(strategyEmptyConfig) },
 	strategyLeastLoad: func() interface{} { return new(strategyLeastLoadConfig) },
 	strategyLeastPing: func() interface{} { return new(strategyLeastPingConfig) },
+
+	strategyConsistentHash: func() interface{} { return new(strategyConsistentHashConfig) },
+	strategyFallback:       func() interface{} { return new(strategyFallbackConfig) },
+	strategyRoundRobin:     func() interface{} { return new(strategyRoundRobinConfig) },
+	strategyWeighted:       func() interface{} { return new(strategyWeightedConfig) },
 }, "type", "settings")
 
 type strategyEmptyConfig struct{}
@@ -97,3 +109,69 @@ type strategyLeastPingConfig struct {
 func (s strategyLeastPingConfig) Build() (proto.Message, error) {
 	return &router.StrategyLeastPingConfig{ObserverTag: s.ObserverTag}, nil
 }
+
+type strategyConsistentHashConfig struct {
+	Keys        []string `json:"keys,omitempty"`
+	ObserverTag string   `json:"observerTag,omitempty"`
+}
+
+func (s strategyConsistentHashConfig) Build() (proto.Message, error) {
+	config := &router.StrategyConsistentHashConfig{ObserverTag: s.ObserverTag}
+	for _, key := range s.Keys {
+		switch strings.ToLower(key) {
+		case "sourceip", "source":
+			config.Key = append(config.Key, router.StrategyConsistentHashConfig_SourceIP)
+		case "destination", "domain":
+			config.Key = append(config.Key, router.StrategyConsistentHashConfig_Destination)
+		case "user", "email":
+			config.Key = append(config.Key, router.StrategyConsistentHashConfig_User)
+		default:
+			return nil, newError("unknown consistent hash key: ", key)
+		}
+	}
+	return config, nil
+}
+
+type strategyFallbackConfig struct {
+	ObserverTag   string            `json:"observerTag,omitempty"`
+	MaxFailures   uint32            `json:"maxFailures,omitempty"`
+	FailureWindow duration.Duration `json:"failureWindow,omitempty"`
+	DownDuration  duration.Duration `json:"downDuration,omitempty"`
+}
+
+func (s strategyFallbackConfig) Build() (proto.Message, error) {
+	if s.FailureWindow < 0 || s.DownDuration < 0 {
+		return nil, newError("negative duration in fallback strategy")
+	}
+	return &router.StrategyFallbackConfig{
+		ObserverTag:   s.ObserverTag,
+		MaxFailures:   s.MaxFailures,
+		FailureWindow: int64(s.FailureWindow),
+		DownDuration:  int64(s.DownDuration),
+	}, nil
+}
+
+type strategyRoundRobinConfig struct {
+	ObserverTag string `json:"observerTag,omitempty"`
+}
+
+func (s strategyRoundRobinConfig) Build() (proto.Message, error) {
+	return &router.StrategyRoundRobinConfig{ObserverTag: s.ObserverTag}, nil
+}
+
+type strategyWeightedConfig struct {
+	Weights     []*router.StrategyWeight `json:"weights,omitempty"`
+	ObserverTag string                   `json:"observerTag,omitempty"`
+}
+
+func (s strategyWeightedConfig) Build() (proto.Message, error) {
+	for _, w := range s.Weights {
+		if w.Value < 0 {
+			return nil, newError("negative weight for ", w.Match)
+		}
+	}
+	return &router.StrategyWeightedConfig{
+		Weights:     s.Weights,
+		ObserverTag: s.ObserverTag,
+	}, nil
+}
@@ -387,5 +387,250 @@ func TestRouterConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"rules": [
+					{
+						"type": "field",
+						"ruleSet": ["ads", "cn"],
+						"outboundTag": "blocked"
+					}
+				],
+				"ruleSets": [
+					{
+						"tag": "ads",
+						"url": "https://example.com/ads.txt",
+						"downloadOutboundTag": "proxy",
+						"refreshInterval": 86400
+					},
+					{
+						"tag": "cn",
+						"path": "geoip.dat",
+						"format": "geoip",
+						"code": "cn"
+					}
+				]
+			}`,
+			Parser: createParser(),
+			Output: &router.Config{
+				DomainStrategy: router.DomainStrategy_AsIs,
+				Rule: []*router.RoutingRule{
+					{
+						RuleSet: []string{"ads", "cn"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "blocked",
+						},
+					},
+				},
+				RuleSet: []*router.RuleSet{
+					{
+						Tag:                 "ads",
+						Url:                 "https://example.com/ads.txt",
+						DownloadOutboundTag: "proxy",
+						RefreshInterval:     86400,
+					},
+					{
+						Tag:    "cn",
+						Path:   "geoip.dat",
+						Format: router.RuleSet_GeoIP,
+						Code:   "cn",
+					},
+				},
+			},
+		},
+		{
+			Input: `{
+				"rules": [
+					{
+						"type": "field",
+						"protocol": ["bittorrent"],
+						"logical": {
+							"mode": "or",
+							"rules": [
+								{"inboundTag": ["a"]},
+								{
+									"user": ["b@v2fly.org"],
+									"logical": {"mode": "not", "rules": [{"port": 443}]}
+								}
+							]
+						},
+						"outboundTag": "blocked",
+						"ruleTag": "block-bt"
+					}
+				],
+				"traceSampleRate": 0.5
+			}`,
+			Parser: createParser(),
+			Output: &router.Config{
+				DomainStrategy: router.DomainStrategy_AsIs,
+				Rule: []*router.RoutingRule{
+					{
+						Protocol: []string{"bittorrent"},
+						Logical: &router.LogicalRule{
+							Mode: router.LogicalRule_Or,
+							Rule: []*router.RoutingRule{
+								{InboundTag: []string{"a"}},
+								{
+									UserEmail: []string{"b@v2fly.org"},
+									Logical: &router.LogicalRule{
+										Rule: []*router.RoutingRule{
+											{PortList: &net.PortList{Range: []*net.PortRange{{From: 443, To: 443}}}},
+										},
+										Invert: true,
+									},
+								},
+							},
+						},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "blocked",
+						},
+						RuleTag: "block-bt",
+					},
+				},
+				TraceSampleRate: 0.5,
+			},
+		},
+		{
+			Input: `{
+				"balancers": [
+					{
+						"tag": "sticky",
+						"selector": ["proxy"],
+						"strategy": {
+							"type": "consistenthash",
+							"settings": {
+								"keys": ["sourceIP", "destination", "user"],
+								"observerTag": "probe"
+							}
+						}
+					},
+					{
+						"tag": "failover",
+						"selector": ["primary", "backup"],
+						"strategy": {
+							"type": "fallback",
+							"settings": {
+								"maxFailures": 5,
+								"failureWindow": "30s",
+								"downDuration": "2m"
+							}
+						},
+						"fallbackTag": "direct"
+					},
+					{
+						"tag": "rr",
+						"selector": ["link"],
+						"strategy": {
+							"type": "roundrobin",
+							"settings": {"observerTag": "probe"}
+						}
+					},
+					{
+						"tag": "split",
+						"selector": ["link"],
+						"strategy": {
+							"type": "weighted",
+							"settings": {
+								"weights": [
+									{"match": "link-a", "value": 3},
+									{"regexp": true, "match": "\\d+M$"}
+								]
+							}
+						}
+					}
+				]
+			}`,
+			Parser: createParser(),
+			Output: &router.Config{
+				DomainStrategy: router.DomainStrategy_AsIs,
+				BalancingRule: []*router.BalancingRule{
+					{
+						Tag:              "sticky",
+						OutboundSelector: []string{"proxy"},
+						Strategy:         "consistenthash",
+						StrategySettings: serial.ToTypedMessage(&router.StrategyConsistentHashConfig{
+							Key: []router.StrategyConsistentHashConfig_Key{
+								router.StrategyConsistentHashConfig_SourceIP,
+								router.StrategyConsistentHashConfig_Destination,
+								router.StrategyConsistentHashConfig_User,
+							},
+							ObserverTag: "probe",
+						}),
+					},
+					{
+						Tag:              "failover",
+						OutboundSelector: []string{"primary", "backup"},
+						Strategy:         "fallback",
+						StrategySettings: serial.ToTypedMessage(&router.StrategyFallbackConfig{
+							MaxFailures:   5,
+							FailureWindow: int64(30 * time.Second),
+							DownDuration:  int64(2 * time.Minute),
+						}),
+						FallbackTag: "direct",
+					},
+					{
+						Tag:              "rr",
+						OutboundSelector: []string{"link"},
+						Strategy:         "roundrobin",
+						StrategySettings: serial.ToTypedMessage(&router.StrategyRoundRobinConfig{
+							ObserverTag: "probe",
+						}),
+					},
+					{
+						Tag:              "split",
+						OutboundSelector: []string{"link"},
+						Strategy:         "weighted",
+						StrategySettings: serial.ToTypedMessage(&router.StrategyWeightedConfig{
+							Weights: []*router.StrategyWeight{
+								{Match: "link-a", Value: 3},
+								{Regexp: true, Match: `\d+M$`},
+							},
+						}),
+					},
+				},
+			},
+		},
+		{
+			Input: `{
+				"rules": [
+					{
+						"type": "field",
+						"domain": ["video.example"],
+						"schedule": {
+							"timezone": "Asia/Shanghai",
+							"windows": [
+								{"weekdays": ["mon", "Tuesday", "3"], "time": "22:30-07:00"},
+								{"time": "12:00-24:00"}
+							]
+						},
+						"outboundTag": "blocked"
+					}
+				]
+			}`,
+			Parser: createParser(),
+			Output: &router.Config{
+				DomainStrategy: router.DomainStrategy_AsIs,
+				Rule: []*router.RoutingRule{
+					{
+						Domain: []*routercommon.Domain{
+							{
+								Type:  routercommon.Domain_Plain,
+								Value: "video.example",
+							},
+						},
+						Schedule: &router.Schedule{
+							Window: []*router.Schedule_Window{
+								{Weekday: []uint32{1, 2, 3}, Start: 1350, End: 420},
+								{Start: 720, End: 0},
+							},
+							Timezone: "Asia/Shanghai",
+						},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "blocked",
+						},
+					},
+				},
+			},
+		},
 	})
 }
@@ -0,0 +1,25 @@
+package v4
+
+import (
+	"github.com/v2fly/v2ray-core/v5/common/protocol/authprovider"
+)
+
+type AuthProviderConfig struct {
+	URL         string `json:"url"`
+	File        string `json:"file"`
+	TTL         uint32 `json:"ttl"`
+	NegativeTTL uint32 `json:"negativeTtl"`
+}
+
+// Build implements Buildable
+func (c *AuthProviderConfig) Build() (*authprovider.Config, error) {
+	if (c.URL == "") == (c.File == "") {
+		return nil, newError(`authProvider: exactly one of "url" and "file" must be set`)
+	}
+	return &authprovider.Config{
+		Url:         c.URL,
+		File:        c.File,
+		Ttl:         c.TTL,
+		NegativeTtl: c.NegativeTTL,
+	}, nil
+}
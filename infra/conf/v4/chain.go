@@ -0,0 +1,17 @@
+package v4
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/v2fly/v2ray-core/v5/proxy/chain"
+)
+
+type ChainConfig struct {
+	Outbounds []string `json:"outbounds"`
+}
+
+func (c *ChainConfig) Build() (proto.Message, error) {
+	if len(c.Outbounds) == 0 {
+		return nil, newError("no outbound in chain")
+	}
+	return &chain.Config{OutboundTag: c.Outbounds}, nil
+}
@@ -0,0 +1,28 @@
+package v4_test
+
+import (
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/testassist"
+	v4 "github.com/v2fly/v2ray-core/v5/infra/conf/v4"
+	"github.com/v2fly/v2ray-core/v5/proxy/chain"
+)
+
+func TestChainConfig(t *testing.T) {
+	creator := func() cfgcommon.Buildable {
+		return new(v4.ChainConfig)
+	}
+
+	testassist.RunMultiTestCase(t, []testassist.TestCase{
+		{
+			Input: `{
+				"outbounds": ["entry", "relay", "exit"]
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &chain.Config{
+				OutboundTag: []string{"entry", "relay", "exit"},
+			},
+		},
+	})
+}
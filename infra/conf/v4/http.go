@@ -7,7 +7,9 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/tlscfg"
 	"github.com/v2fly/v2ray-core/v5/proxy/http"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
 )
 
 type HTTPAccount struct {
@@ -47,9 +49,11 @@ func (c *HTTPServerConfig) Build() (proto.Message, error) {
 }
 
 type HTTPRemoteConfig struct {
-	Address *cfgcommon.Address `json:"address"`
-	Port    uint16             `json:"port"`
-	Users   []json.RawMessage  `json:"users"`
+	Address     *cfgcommon.Address `json:"address"`
+	Port        uint16             `json:"port"`
+	Users       []json.RawMessage  `json:"users"`
+	Headers     map[string]string  `json:"headers"`
+	TLSSettings *tlscfg.TLSConfig  `json:"tlsSettings"`
 }
 
 type HTTPClientConfig struct {
@@ -58,8 +62,7 @@ type HTTPClientConfig struct {
 
 func (v *HTTPClientConfig) Build() (proto.Message, error) {
 	config := new(http.ClientConfig)
-	config.Server = make([]*protocol.ServerEndpoint, len(v.Servers))
-	for idx, serverConfig := range v.Servers {
+	for _, serverConfig := range v.Servers {
 		server := &protocol.ServerEndpoint{
 			Address: serverConfig.Address.Build(),
 			Port:    uint32(serverConfig.Port),
@@ -76,7 +79,23 @@ func (v *HTTPClientConfig) Build() (proto.Message, error) {
 			user.Account = serial.ToTypedMessage(account.Build())
 			server.User = append(server.User, user)
 		}
-		config.Server[idx] = server
+
+		if len(serverConfig.Headers) == 0 && serverConfig.TLSSettings == nil {
+			config.Server = append(config.Server, server)
+			continue
+		}
+		entry := &http.ServerEntry{
+			Endpoint: server,
+			Headers:  serverConfig.Headers,
+		}
+		if serverConfig.TLSSettings != nil {
+			tlsSettings, err := serverConfig.TLSSettings.Build()
+			if err != nil {
+				return nil, newError("failed to build HTTP server TLS settings").Base(err)
+			}
+			entry.TlsSettings = tlsSettings.(*tls.Config)
+		}
+		config.ServerEntry = append(config.ServerEntry, entry)
 	}
 	return config, nil
 }
@@ -3,10 +3,14 @@ package v4_test
 import (
 	"testing"
 
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/testassist"
 	v4 "github.com/v2fly/v2ray-core/v5/infra/conf/v4"
 	"github.com/v2fly/v2ray-core/v5/proxy/http"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
 )
 
 func TestHTTPServerConfig(t *testing.T) {
@@ -39,3 +43,69 @@ func TestHTTPServerConfig(t *testing.T) {
 		},
 	})
 }
+
+func TestHTTPClientConfig(t *testing.T) {
+	creator := func() cfgcommon.Buildable {
+		return new(v4.HTTPClientConfig)
+	}
+
+	testassist.RunMultiTestCase(t, []testassist.TestCase{
+		{
+			Input: `{
+				"servers": [
+					{
+						"address": "127.0.0.1",
+						"port": 8080
+					},
+					{
+						"address": "proxy.example.com",
+						"port": 443,
+						"users": [
+							{
+								"user": "my-username",
+								"pass": "my-password"
+							}
+						],
+						"headers": {
+							"X-Proxy-Token": "token"
+						},
+						"tlsSettings": {
+							"serverName": "proxy.example.com"
+						}
+					}
+				]
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &http.ClientConfig{
+				Server: []*protocol.ServerEndpoint{
+					{
+						Address: net.NewIPOrDomain(net.LocalHostIP),
+						Port:    8080,
+					},
+				},
+				ServerEntry: []*http.ServerEntry{
+					{
+						Endpoint: &protocol.ServerEndpoint{
+							Address: net.NewIPOrDomain(net.DomainAddress("proxy.example.com")),
+							Port:    443,
+							User: []*protocol.User{
+								{
+									Account: serial.ToTypedMessage(&http.Account{
+										Username: "my-username",
+										Password: "my-password",
+									}),
+								},
+							},
+						},
+						Headers: map[string]string{
+							"X-Proxy-Token": "token",
+						},
+						TlsSettings: &tls.Config{
+							ServerName: "proxy.example.com",
+						},
+					},
+				},
+			},
+		},
+	})
+}
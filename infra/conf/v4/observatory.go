@@ -2,6 +2,8 @@ package v4
 
 import (
 	"encoding/json"
+	"net/url"
+	"strings"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/v2fly/v2ray-core/v5/app/observatory"
@@ -14,20 +16,79 @@ import (
 	"google.golang.org/protobuf/types/known/anypb"
 )
 
+type ProbeOverrideConfig struct {
+	Selector      string            `json:"selector"`
+	ProbeURL      string            `json:"probeURL"`
+	ProbeInterval duration.Duration `json:"probeInterval"`
+	ProbeTimeout  duration.Duration `json:"probeTimeout"`
+}
+
 type ObservatoryConfig struct {
 	SubjectSelector   []string          `json:"subjectSelector"`
 	ProbeURL          string            `json:"probeURL"`
 	ProbeInterval     duration.Duration `json:"probeInterval"`
 	EnableConcurrency bool              `json:"enableConcurrency"`
+	ProbeKind         string            `json:"probeKind"`
+	ProbeDestination  string            `json:"probeDestination"`
+	ProbeServerName   string            `json:"probeServerName"`
+
+	LatencyChangeThreshold duration.Duration `json:"latencyChangeThreshold"`
+	WebhookURL             string            `json:"webhookURL"`
+
+	ProbeTimeout   duration.Duration     `json:"probeTimeout"`
+	ProbeOverrides []ProbeOverrideConfig `json:"probeOverrides"`
+	PersistPath    string                `json:"persistPath"`
 }
 
 func (o *ObservatoryConfig) Build() (proto.Message, error) {
-	return &observatory.Config{
+	config := &observatory.Config{
 		SubjectSelector:   o.SubjectSelector,
 		ProbeUrl:          o.ProbeURL,
 		ProbeInterval:     int64(o.ProbeInterval),
 		EnableConcurrency: o.EnableConcurrency,
-	}, nil
+		ProbeDestination:  o.ProbeDestination,
+		ProbeServerName:   o.ProbeServerName,
+
+		LatencyChangeThreshold: int64(o.LatencyChangeThreshold),
+		WebhookUrl:             o.WebhookURL,
+
+		ProbeTimeout: int64(o.ProbeTimeout),
+		PersistPath:  o.PersistPath,
+	}
+	for _, override := range o.ProbeOverrides {
+		if override.Selector == "" {
+			return nil, newError("selector of probe override is not set")
+		}
+		config.ProbeOverride = append(config.ProbeOverride, &observatory.ProbeOverride{
+			Selector:      override.Selector,
+			ProbeUrl:      override.ProbeURL,
+			ProbeInterval: int64(override.ProbeInterval),
+			ProbeTimeout:  int64(override.ProbeTimeout),
+		})
+	}
+	switch strings.ToLower(o.ProbeKind) {
+	case "http", "":
+		config.ProbeKind = observatory.ProbeKind_HTTP
+	case "tcp":
+		config.ProbeKind = observatory.ProbeKind_TCP
+	case "tls":
+		config.ProbeKind = observatory.ProbeKind_TLS
+	case "dns":
+		config.ProbeKind = observatory.ProbeKind_DNS
+	case "icmp", "ping":
+		config.ProbeKind = observatory.ProbeKind_ICMP
+	default:
+		return nil, newError("unknown probe kind: ", o.ProbeKind)
+	}
+	if config.ProbeKind != observatory.ProbeKind_HTTP && config.ProbeDestination == "" {
+		return nil, newError("probeDestination is required for ", o.ProbeKind, " probes")
+	}
+	if o.WebhookURL != "" {
+		if u, err := url.Parse(o.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, newError("invalid webhookURL: ", o.WebhookURL)
+		}
+	}
+	return config, nil
 }
 
 type BurstObservatoryConfig struct {
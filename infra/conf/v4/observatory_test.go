@@ -0,0 +1,103 @@
+package v4_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/app/observatory"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/testassist"
+	v4 "github.com/v2fly/v2ray-core/v5/infra/conf/v4"
+)
+
+func TestObservatoryConfig(t *testing.T) {
+	creator := func() cfgcommon.Buildable {
+		return new(v4.ObservatoryConfig)
+	}
+
+	testassist.RunMultiTestCase(t, []testassist.TestCase{
+		{
+			Input: `{
+				"subjectSelector": ["proxy"],
+				"probeURL": "https://www.google.com/generate_204",
+				"probeInterval": "30s"
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &observatory.Config{
+				SubjectSelector: []string{"proxy"},
+				ProbeUrl:        "https://www.google.com/generate_204",
+				ProbeInterval:   int64(30 * time.Second),
+			},
+		},
+		{
+			Input: `{
+				"subjectSelector": ["wg"],
+				"probeKind": "ICMP",
+				"probeDestination": "1.1.1.1"
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &observatory.Config{
+				SubjectSelector:  []string{"wg"},
+				ProbeKind:        observatory.ProbeKind_ICMP,
+				ProbeDestination: "1.1.1.1",
+			},
+		},
+		{
+			Input: `{
+				"subjectSelector": ["proxy"],
+				"probeKind": "tls",
+				"probeDestination": "1.1.1.1:443",
+				"probeServerName": "one.one.one.one"
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &observatory.Config{
+				SubjectSelector:  []string{"proxy"},
+				ProbeKind:        observatory.ProbeKind_TLS,
+				ProbeDestination: "1.1.1.1:443",
+				ProbeServerName:  "one.one.one.one",
+			},
+		},
+		{
+			Input: `{
+				"subjectSelector": ["proxy"],
+				"latencyChangeThreshold": "200ms",
+				"webhookURL": "https://hooks.example.com/v2ray"
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &observatory.Config{
+				SubjectSelector:        []string{"proxy"},
+				LatencyChangeThreshold: int64(200 * time.Millisecond),
+				WebhookUrl:             "https://hooks.example.com/v2ray",
+			},
+		},
+		{
+			Input: `{
+				"subjectSelector": ["eu-", "asia-"],
+				"probeURL": "https://www.google.com/generate_204",
+				"probeTimeout": "3s",
+				"probeOverrides": [{
+					"selector": "asia-",
+					"probeURL": "https://www.baidu.com",
+					"probeInterval": "1m",
+					"probeTimeout": "10s"
+				}],
+				"persistPath": "/var/lib/v2ray/observatory"
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &observatory.Config{
+				SubjectSelector: []string{"eu-", "asia-"},
+				ProbeUrl:        "https://www.google.com/generate_204",
+				ProbeTimeout:    int64(3 * time.Second),
+				ProbeOverride: []*observatory.ProbeOverride{
+					{
+						Selector:      "asia-",
+						ProbeUrl:      "https://www.baidu.com",
+						ProbeInterval: int64(time.Minute),
+						ProbeTimeout:  int64(10 * time.Second),
+					},
+				},
+				PersistPath: "/var/lib/v2ray/observatory",
+			},
+		},
+	})
+}
@@ -30,7 +30,7 @@ const (
 
 type SocksServerConfig struct {
 	AuthMethod string             `json:"auth"`
-	Accounts   []*SocksAccount    `json:"accounts"`
+	Accounts   []json.RawMessage  `json:"accounts"`
 	UDP        bool               `json:"udp"`
 	Host       *cfgcommon.Address `json:"ip"`
 	Timeout    uint32             `json:"timeout"`
@@ -49,11 +49,28 @@ func (v *SocksServerConfig) Build() (proto.Message, error) {
 		config.AuthType = socks.AuthType_NO_AUTH
 	}
 
-	if len(v.Accounts) > 0 {
-		config.Accounts = make(map[string]string, len(v.Accounts))
-		for _, account := range v.Accounts {
+	for _, rawAccount := range v.Accounts {
+		user := new(protocol.User)
+		if err := json.Unmarshal(rawAccount, user); err != nil {
+			return nil, newError("failed to parse Socks user").Base(err).AtError()
+		}
+		account := new(SocksAccount)
+		if err := json.Unmarshal(rawAccount, account); err != nil {
+			return nil, newError("failed to parse socks account").Base(err).AtError()
+		}
+		// Plain accounts are users named after their username, at userLevel.
+		if user.Email == "" && user.Level == 0 {
+			if config.Accounts == nil {
+				config.Accounts = make(map[string]string, len(v.Accounts))
+			}
 			config.Accounts[account.Username] = account.Password
+			continue
+		}
+		if user.Level == 0 {
+			user.Level = v.UserLevel
 		}
+		user.Account = serial.ToTypedMessage(account.Build())
+		config.Users = append(config.Users, user)
 	}
 
 	config.UdpEnabled = v.UDP
@@ -48,6 +48,43 @@ func TestSocksInboundConfig(t *testing.T) {
 				UserLevel: 1,
 			},
 		},
+		{
+			Input: `{
+				"auth": "password",
+				"accounts": [
+					{"user": "plain", "pass": "plain-password"},
+					{"user": "alice", "pass": "alice-password", "email": "alice@v2fly.org"},
+					{"user": "bob", "pass": "bob-password", "email": "bob@v2fly.org", "level": 2}
+				],
+				"userLevel": 1
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &socks.ServerConfig{
+				AuthType: socks.AuthType_PASSWORD,
+				Accounts: map[string]string{
+					"plain": "plain-password",
+				},
+				UserLevel: 1,
+				Users: []*protocol.User{
+					{
+						Email: "alice@v2fly.org",
+						Level: 1,
+						Account: serial.ToTypedMessage(&socks.Account{
+							Username: "alice",
+							Password: "alice-password",
+						}),
+					},
+					{
+						Email: "bob@v2fly.org",
+						Level: 2,
+						Account: serial.ToTypedMessage(&socks.Account{
+							Username: "bob",
+							Password: "bob-password",
+						}),
+					},
+				},
+			},
+		},
 	})
 }
 
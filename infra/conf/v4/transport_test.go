@@ -46,6 +46,24 @@ func TestSocketConfig(t *testing.T) {
 				TfoQueueLength: 1024,
 			},
 		},
+		{
+			Input: `{
+				"bindToDevice": "eth1",
+				"tcpCongestion": "bbr",
+				"tcpUserTimeout": 30000,
+				"v6only": true,
+				"mptcp": true
+			}`,
+			Parser: createParser(),
+			Output: &internet.SocketConfig{
+				TfoQueueLength: 4096,
+				BindToDevice:   "eth1",
+				TcpCongestion:  "bbr",
+				TcpUserTimeout: 30000,
+				V6Only:         true,
+				Mptcp:          true,
+			},
+		},
 	})
 }
 
@@ -92,9 +92,10 @@ type TrojanUserConfig struct {
 
 // TrojanServerConfig is Inbound configuration
 type TrojanServerConfig struct {
-	Clients   []*TrojanUserConfig      `json:"clients"`
-	Fallback  json.RawMessage          `json:"fallback"`
-	Fallbacks []*TrojanInboundFallback `json:"fallbacks"`
+	Clients      []*TrojanUserConfig      `json:"clients"`
+	Fallback     json.RawMessage          `json:"fallback"`
+	Fallbacks    []*TrojanInboundFallback `json:"fallbacks"`
+	AuthProvider *AuthProviderConfig      `json:"authProvider"`
 }
 
 // Build implements Buildable
@@ -168,10 +169,26 @@ func (c *TrojanServerConfig) Build() (proto.Message, error) {
 		if fb.Type == "" {
 			return nil, newError(`Trojan fallbacks: please fill in a valid value for every "dest"`)
 		}
+		if fb.Type == "inbound" {
+			if fb.Dest == "" {
+				return nil, newError(`Trojan fallbacks: please fill in the inbound tag as "dest" for "type":"inbound"`)
+			}
+			if fb.Xver != 0 {
+				return nil, newError(`Trojan fallbacks: "xver" does not apply to "type":"inbound"`)
+			}
+		}
 		if fb.Xver > 2 {
 			return nil, newError(`Trojan fallbacks: invalid PROXY protocol version, "xver" only accepts 0, 1, 2`)
 		}
 	}
 
+	if c.AuthProvider != nil {
+		authProvider, err := c.AuthProvider.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.AuthProvider = authProvider
+	}
+
 	return config, nil
 }
@@ -0,0 +1,46 @@
+package v4
+
+import (
+	"net"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+	"github.com/v2fly/v2ray-core/v5/proxy/tun"
+)
+
+type TunConfig struct {
+	Name       string               `json:"name"`
+	FD         int32                `json:"fd"`
+	MTU        uint32               `json:"mtu"`
+	UserLevel  uint32               `json:"userLevel"`
+	AutoRoute  bool                 `json:"autoRoute"`
+	Address    cfgcommon.StringList `json:"address"`
+	Route      cfgcommon.StringList `json:"route"`
+	BypassMark uint32               `json:"bypassMark"`
+	RouteTable uint32               `json:"routeTable"`
+}
+
+func (c *TunConfig) Build() (proto.Message, error) {
+	for _, cidr := range append(append([]string(nil), c.Address...), c.Route...) {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, newError("invalid CIDR ", cidr).Base(err)
+		}
+	}
+	if c.AutoRoute && c.FD > 0 && c.Name == "" {
+		return nil, newError("name of the tun device is required to set up routes")
+	}
+	if c.AutoRoute && len(c.Route) == 0 && c.BypassMark == 0 {
+		return nil, newError("bypassMark is required to route all destinations to the tun device, or connections of outbounds loop back")
+	}
+	return &tun.Config{
+		Name:       c.Name,
+		Fd:         c.FD,
+		Mtu:        c.MTU,
+		UserLevel:  c.UserLevel,
+		AutoRoute:  c.AutoRoute,
+		Address:    c.Address,
+		Route:      c.Route,
+		BypassMark: c.BypassMark,
+		RouteTable: c.RouteTable,
+	}, nil
+}
@@ -0,0 +1,56 @@
+package v4_test
+
+import (
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/testassist"
+	v4 "github.com/v2fly/v2ray-core/v5/infra/conf/v4"
+	"github.com/v2fly/v2ray-core/v5/proxy/tun"
+)
+
+func TestTunConfig(t *testing.T) {
+	creator := func() cfgcommon.Buildable {
+		return new(v4.TunConfig)
+	}
+
+	testassist.RunMultiTestCase(t, []testassist.TestCase{
+		{
+			Input: `{
+				"name": "tun1",
+				"mtu": 9000,
+				"userLevel": 1,
+				"autoRoute": true,
+				"address": ["172.19.0.1/30", "fdfe:dcba:9876::1/126"],
+				"route": "10.0.0.0/8",
+				"bypassMark": 255,
+				"routeTable": 100
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &tun.Config{
+				Name:       "tun1",
+				Mtu:        9000,
+				UserLevel:  1,
+				AutoRoute:  true,
+				Address:    []string{"172.19.0.1/30", "fdfe:dcba:9876::1/126"},
+				Route:      []string{"10.0.0.0/8"},
+				BypassMark: 255,
+				RouteTable: 100,
+			},
+		},
+		{
+			Input: `{
+				"fd": 3
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &tun.Config{
+				Fd: 3,
+			},
+		},
+	})
+
+	// Routing all destinations without bypass mark would loop back.
+	if _, err := testassist.LoadJSON(creator)(`{"autoRoute": true}`); err == nil {
+		t.Error("expected error for auto route without bypass mark")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/app/stats"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/inboundcfg"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/loader"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/muxcfg"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/proxycfg"
@@ -29,11 +30,13 @@ var (
 		"vless":         func() interface{} { return new(VLessInboundConfig) },
 		"vmess":         func() interface{} { return new(VMessInboundConfig) },
 		"trojan":        func() interface{} { return new(TrojanServerConfig) },
+		"tun":           func() interface{} { return new(TunConfig) },
 		//"vliteu":        func() interface{} { return new(VLiteUDPInboundConfig) },
 	}, "protocol", "settings")
 
 	outboundConfigLoader = loader.NewJSONConfigLoader(loader.ConfigCreatorCache{
 		"blackhole":   func() interface{} { return new(BlackholeConfig) },
+		"chain":       func() interface{} { return new(ChainConfig) },
 		"freedom":     func() interface{} { return new(FreedomConfig) },
 		"http":        func() interface{} { return new(HTTPClientConfig) },
 		"shadowsocks": func() interface{} { return new(ShadowsocksClientConfig) },
@@ -101,48 +104,31 @@ func (c *InboundDetourAllocationConfig) Build() (*proxyman.AllocationStrategy, e
 }
 
 type InboundDetourConfig struct {
-	Protocol       string                         `json:"protocol"`
-	PortRange      *cfgcommon.PortRange           `json:"port"`
-	ListenOn       *cfgcommon.Address             `json:"listen"`
-	Settings       *json.RawMessage               `json:"settings"`
-	Tag            string                         `json:"tag"`
-	Allocation     *InboundDetourAllocationConfig `json:"allocate"`
-	StreamSetting  *StreamConfig                  `json:"streamSettings"`
-	DomainOverride *cfgcommon.StringList          `json:"domainOverride"`
-	SniffingConfig *sniffer.SniffingConfig        `json:"sniffing"`
+	Protocol       string                          `json:"protocol"`
+	PortList       *inboundcfg.PortList            `json:"port"`
+	ListenOn       cfgcommon.AddressList           `json:"listen"`
+	Settings       *json.RawMessage                `json:"settings"`
+	Tag            string                          `json:"tag"`
+	Allocation     *InboundDetourAllocationConfig  `json:"allocate"`
+	StreamSetting  *StreamConfig                   `json:"streamSettings"`
+	DomainOverride *cfgcommon.StringList           `json:"domainOverride"`
+	SniffingConfig *sniffer.SniffingConfig         `json:"sniffing"`
+	AccessControl  *inboundcfg.AccessControlConfig `json:"accessControl"`
 }
 
 // Build implements Buildable.
 func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 	receiverSettings := &proxyman.ReceiverConfig{}
 
-	if c.ListenOn == nil {
-		// Listen on anyip, must set PortRange
-		if c.PortRange == nil {
-			return nil, newError("Listen on AnyIP but no Port(s) set in InboundDetour.")
-		}
-		receiverSettings.PortRange = c.PortRange.Build()
-	} else {
-		// Listen on specific IP or Unix Domain Socket
-		receiverSettings.Listen = c.ListenOn.Build()
-		listenDS := c.ListenOn.Family().IsDomain() && (c.ListenOn.Domain()[0] == '/' || c.ListenOn.Domain()[0] == '@')
-		listenIP := c.ListenOn.Family().IsIP() || (c.ListenOn.Family().IsDomain() && c.ListenOn.Domain() == "localhost")
-		switch {
-		case listenIP:
-			// Listen on specific IP, must set PortRange
-			if c.PortRange == nil {
-				return nil, newError("Listen on specific ip without port in InboundDetour.")
-			}
-			// Listen on IP:Port
-			receiverSettings.PortRange = c.PortRange.Build()
-		case listenDS:
-			if c.PortRange != nil {
-				// Listen on Unix Domain Socket, PortRange should be nil
-				receiverSettings.PortRange = nil
-			}
-		default:
-			return nil, newError("unable to listen on domain address: ", c.ListenOn.Domain())
+	if err := inboundcfg.BuildListen(c.Protocol, c.ListenOn, c.PortList, receiverSettings); err != nil {
+		return nil, err
+	}
+	if c.AccessControl != nil {
+		ac, err := c.AccessControl.Build()
+		if err != nil {
+			return nil, newError("failed to build access control config").Base(err)
 		}
+		receiverSettings.AccessControl = ac
 	}
 
 	if c.Allocation != nil {
@@ -150,9 +136,16 @@ func (c *InboundDetourConfig) Build() (*core.InboundHandlerConfig, error) {
 		if c.Allocation.Concurrency != nil && c.Allocation.Strategy == "random" {
 			concurrency = int(*c.Allocation.Concurrency)
 		}
-		portRange := int(c.PortRange.To - c.PortRange.From + 1)
+		pr := receiverSettings.PortRange
+		if pr == nil || receiverSettings.PortList != nil {
+			return nil, newError("port allocation requires a single port range")
+		}
+		if len(receiverSettings.AdditionalListen) > 0 {
+			return nil, newError("port allocation requires a single listen address")
+		}
+		portRange := int(pr.To - pr.From + 1)
 		if concurrency >= 0 && concurrency >= portRange {
-			return nil, newError("not enough ports. concurrency = ", concurrency, " ports: ", c.PortRange.From, " - ", c.PortRange.To)
+			return nil, newError("not enough ports. concurrency = ", concurrency, " ports: ", pr.From, " - ", pr.To)
 		}
 
 		as, err := c.Allocation.Build()
@@ -512,10 +505,12 @@ func (c *Config) Build() (*core.Config, error) {
 	}
 
 	// Backward compatibility.
-	if len(inbounds) > 0 && inbounds[0].PortRange == nil && c.Port > 0 {
-		inbounds[0].PortRange = &cfgcommon.PortRange{
-			From: uint32(c.Port),
-			To:   uint32(c.Port),
+	if len(inbounds) > 0 && inbounds[0].PortList == nil && c.Port > 0 {
+		inbounds[0].PortList = &inboundcfg.PortList{
+			PortList: cfgcommon.PortList{Range: []cfgcommon.PortRange{{
+				From: uint32(c.Port),
+				To:   uint32(c.Port),
+			}}},
 		}
 	}
 
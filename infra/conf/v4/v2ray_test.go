@@ -380,3 +380,74 @@ func TestMuxConfig_Build(t *testing.T) {
 		})
 	}
 }
+
+func TestInboundDetourConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *proxyman.ReceiverConfig
+		wantErr bool
+	}{
+		{"single", `{"protocol": "http", "listen": "127.0.0.1", "port": "1000-1001"}`, &proxyman.ReceiverConfig{
+			Listen:    net.NewIPOrDomain(net.LocalHostIP),
+			PortRange: &net.PortRange{From: 1000, To: 1001},
+		}, false},
+		{"multiple", `{
+			"protocol": "http",
+			"listen": ["127.0.0.1", "::1", "/tmp/http.sock"],
+			"port": "80,443,1000-2000",
+			"accessControl": {
+				"allow": ["10.0.0.0/8", "::1"],
+				"deny": ["10.0.0.1"]
+			}
+		}`, &proxyman.ReceiverConfig{
+			Listen: net.NewIPOrDomain(net.LocalHostIP),
+			AdditionalListen: []*net.IPOrDomain{
+				net.NewIPOrDomain(net.LocalHostIPv6),
+				net.NewIPOrDomain(net.DomainAddress("/tmp/http.sock")),
+			},
+			PortRange: &net.PortRange{From: 80, To: 80},
+			PortList: &net.PortList{Range: []*net.PortRange{
+				{From: 443, To: 443},
+				{From: 1000, To: 2000},
+			}},
+			AccessControl: &proxyman.AccessControlConfig{
+				Allow: []*routercommon.CIDR{
+					{Ip: []byte{10, 0, 0, 0}, Prefix: 8},
+					{Ip: net.LocalHostIPv6.IP(), Prefix: 128},
+				},
+				Deny: []*routercommon.CIDR{
+					{Ip: []byte{10, 0, 0, 1}, Prefix: 32},
+				},
+			},
+		}, false},
+		{"unix only", `{"protocol": "http", "listen": "/tmp/http.sock", "port": 80}`, &proxyman.ReceiverConfig{
+			Listen: net.NewIPOrDomain(net.DomainAddress("/tmp/http.sock")),
+		}, false},
+		{"no port", `{"protocol": "http", "listen": ["/tmp/http.sock", "127.0.0.1"]}`, nil, true},
+		{"invalid cidr", `{"protocol": "http", "port": 80, "accessControl": {"deny": ["10.0.0.0/33"]}}`, nil, true},
+		{"allocate port list", `{"protocol": "http", "port": "80,443", "allocate": {"strategy": "random"}}`, nil, true},
+		{"allocate multiple listen", `{"protocol": "http", "listen": ["127.0.0.1", "::1"], "port": "1000-2000", "allocate": {"strategy": "random"}}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &v4.InboundDetourConfig{}
+			common.Must(json.Unmarshal([]byte(tt.input), c))
+			config, err := c.Build()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := serial.GetInstanceOf(config.ReceiverSettings)
+			common.Must(err)
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("ReceiverConfig = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 )
 
 type VLessInboundFallback struct {
+	Name string          `json:"name"`
 	Alpn string          `json:"alpn"`
 	Path string          `json:"path"`
 	Type string          `json:"type"`
@@ -26,10 +27,11 @@ type VLessInboundFallback struct {
 }
 
 type VLessInboundConfig struct {
-	Clients    []json.RawMessage       `json:"clients"`
-	Decryption string                  `json:"decryption"`
-	Fallback   json.RawMessage         `json:"fallback"`
-	Fallbacks  []*VLessInboundFallback `json:"fallbacks"`
+	Clients      []json.RawMessage       `json:"clients"`
+	Decryption   string                  `json:"decryption"`
+	Fallback     json.RawMessage         `json:"fallback"`
+	Fallbacks    []*VLessInboundFallback `json:"fallbacks"`
+	AuthProvider *AuthProviderConfig     `json:"authProvider"`
 }
 
 // Build implements Buildable
@@ -71,6 +73,7 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 			_ = json.Unmarshal(fb.Dest, &s)
 		}
 		config.Fallbacks = append(config.Fallbacks, &inbound.Fallback{
+			Name: fb.Name,
 			Alpn: fb.Alpn,
 			Path: fb.Path,
 			Type: fb.Type,
@@ -112,11 +115,27 @@ func (c *VLessInboundConfig) Build() (proto.Message, error) {
 		if fb.Type == "" {
 			return nil, newError(`VLESS fallbacks: please fill in a valid value for every "dest"`)
 		}
+		if fb.Type == "inbound" {
+			if fb.Dest == "" {
+				return nil, newError(`VLESS fallbacks: please fill in the inbound tag as "dest" for "type":"inbound"`)
+			}
+			if fb.Xver != 0 {
+				return nil, newError(`VLESS fallbacks: "xver" does not apply to "type":"inbound"`)
+			}
+		}
 		if fb.Xver > 2 {
 			return nil, newError(`VLESS fallbacks: invalid PROXY protocol version, "xver" only accepts 0, 1, 2`)
 		}
 	}
 
+	if c.AuthProvider != nil {
+		authProvider, err := c.AuthProvider.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.AuthProvider = authProvider
+	}
+
 	return config, nil
 }
 
@@ -5,6 +5,7 @@ import (
 
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/authprovider"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/testassist"
@@ -128,5 +129,67 @@ func TestVLessInbound(t *testing.T) {
 				},
 			},
 		},
+		{
+			Input: `{
+				"clients": [
+					{
+						"id": "27848739-7e62-4138-9fd3-098a63964b6b"
+					}
+				],
+				"decryption": "none",
+				"fallbacks": [
+					{
+						"dest": "/run/web.socket",
+						"xver": 2
+					},
+					{
+						"name": "blog.example.com",
+						"type": "inbound",
+						"dest": "blog-in"
+					}
+				]
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &inbound.Config{
+				Clients: []*protocol.User{
+					{
+						Account: serial.ToTypedMessage(&vless.Account{
+							Id: "27848739-7e62-4138-9fd3-098a63964b6b",
+						}),
+					},
+				},
+				Decryption: "none",
+				Fallbacks: []*inbound.Fallback{
+					{
+						Type: "unix",
+						Dest: "/run/web.socket",
+						Xver: 2,
+					},
+					{
+						Name: "blog.example.com",
+						Type: "inbound",
+						Dest: "blog-in",
+					},
+				},
+			},
+		},
+		{
+			Input: `{
+				"decryption": "none",
+				"authProvider": {
+					"url": "http://127.0.0.1:8080/users",
+					"ttl": 600
+				}
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &inbound.Config{
+				Clients:    []*protocol.User{},
+				Decryption: "none",
+				AuthProvider: &authprovider.Config{
+					Url: "http://127.0.0.1:8080/users",
+					Ttl: 600,
+				},
+			},
+		},
 	})
 }
@@ -82,6 +82,7 @@ type VMessInboundConfig struct {
 	Defaults     *VMessDefaultConfig `json:"default"`
 	DetourConfig *VMessDetourConfig  `json:"detour"`
 	SecureOnly   bool                `json:"disableInsecureEncryption"`
+	AuthProvider *AuthProviderConfig `json:"authProvider"`
 }
 
 // Build implements Buildable
@@ -114,6 +115,14 @@ func (c *VMessInboundConfig) Build() (proto.Message, error) {
 		config.User[idx] = user
 	}
 
+	if c.AuthProvider != nil {
+		authProvider, err := c.AuthProvider.Build()
+		if err != nil {
+			return nil, err
+		}
+		config.AuthProvider = authProvider
+	}
+
 	return config, nil
 }
 
@@ -5,6 +5,7 @@ import (
 
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/authprovider"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/testassist"
@@ -115,5 +116,23 @@ func TestVMessInbound(t *testing.T) {
 				SecureEncryptionOnly: true,
 			},
 		},
+		{
+			Input: `{
+				"authProvider": {
+					"file": "/etc/v2ray/users.json",
+					"ttl": 60,
+					"negativeTtl": 10
+				}
+			}`,
+			Parser: testassist.LoadJSON(creator),
+			Output: &inbound.Config{
+				User: []*protocol.User{},
+				AuthProvider: &authprovider.Config{
+					File:        "/etc/v2ray/users.json",
+					Ttl:         60,
+					NegativeTtl: 10,
+				},
+			},
+		},
 	})
 }
@@ -7,6 +7,7 @@ import (
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/app/proxyman"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/inboundcfg"
 	"github.com/v2fly/v2ray-core/v5/proxy/dokodemo"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
 )
@@ -14,33 +15,15 @@ import (
 func (c InboundConfig) BuildV5(ctx context.Context) (proto.Message, error) {
 	receiverSettings := &proxyman.ReceiverConfig{}
 
-	if c.ListenOn == nil {
-		// Listen on anyip, must set PortRange
-		if c.PortRange == nil {
-			return nil, newError("Listen on AnyIP but no Port(s) set in InboundDetour.")
-		}
-		receiverSettings.PortRange = c.PortRange.Build()
-	} else {
-		// Listen on specific IP or Unix Domain Socket
-		receiverSettings.Listen = c.ListenOn.Build()
-		listenDS := c.ListenOn.Family().IsDomain() && (c.ListenOn.Domain()[0] == '/' || c.ListenOn.Domain()[0] == '@')
-		listenIP := c.ListenOn.Family().IsIP() || (c.ListenOn.Family().IsDomain() && c.ListenOn.Domain() == "localhost")
-		switch {
-		case listenIP:
-			// Listen on specific IP, must set PortRange
-			if c.PortRange == nil {
-				return nil, newError("Listen on specific ip without port in InboundDetour.")
-			}
-			// Listen on IP:Port
-			receiverSettings.PortRange = c.PortRange.Build()
-		case listenDS:
-			if c.PortRange != nil {
-				// Listen on Unix Domain Socket, PortRange should be nil
-				receiverSettings.PortRange = nil
-			}
-		default:
-			return nil, newError("unable to listen on domain address: ", c.ListenOn.Domain())
+	if err := inboundcfg.BuildListen(c.Protocol, c.ListenOn, c.PortList, receiverSettings); err != nil {
+		return nil, err
+	}
+	if c.AccessControl != nil {
+		ac, err := c.AccessControl.Build()
+		if err != nil {
+			return nil, newError("failed to build access control config").Base(err)
 		}
+		receiverSettings.AccessControl = ac
 	}
 
 	if c.StreamSetting != nil {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon"
+	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/inboundcfg"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/muxcfg"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/proxycfg"
 	"github.com/v2fly/v2ray-core/v5/infra/conf/cfgcommon/sniffer"
@@ -21,13 +22,14 @@ type RootConfig struct {
 }
 
 type InboundConfig struct {
-	Protocol       string                  `json:"protocol"`
-	PortRange      *cfgcommon.PortRange    `json:"port"`
-	ListenOn       *cfgcommon.Address      `json:"listen"`
-	Settings       json.RawMessage         `json:"settings"`
-	Tag            string                  `json:"tag"`
-	SniffingConfig *sniffer.SniffingConfig `json:"sniffing"`
-	StreamSetting  *StreamConfig           `json:"streamSettings"`
+	Protocol       string                          `json:"protocol"`
+	PortList       *inboundcfg.PortList            `json:"port"`
+	ListenOn       cfgcommon.AddressList           `json:"listen"`
+	Settings       json.RawMessage                 `json:"settings"`
+	Tag            string                          `json:"tag"`
+	SniffingConfig *sniffer.SniffingConfig         `json:"sniffing"`
+	StreamSetting  *StreamConfig                   `json:"streamSettings"`
+	AccessControl  *inboundcfg.AccessControlConfig `json:"accessControl"`
 }
 
 type OutboundConfig struct {
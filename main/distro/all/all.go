@@ -36,6 +36,7 @@ import (
 
 	// Inbound and outbound proxies.
 	_ "github.com/v2fly/v2ray-core/v5/proxy/blackhole"
+	_ "github.com/v2fly/v2ray-core/v5/proxy/chain"
 	_ "github.com/v2fly/v2ray-core/v5/proxy/dns"
 	_ "github.com/v2fly/v2ray-core/v5/proxy/dokodemo"
 	_ "github.com/v2fly/v2ray-core/v5/proxy/freedom"
@@ -47,6 +48,7 @@ import (
 	_ "github.com/v2fly/v2ray-core/v5/proxy/socks"
 	_ "github.com/v2fly/v2ray-core/v5/proxy/trojan"
 	_ "github.com/v2fly/v2ray-core/v5/proxy/trojan_sing"
+	_ "github.com/v2fly/v2ray-core/v5/proxy/tun"
 	_ "github.com/v2fly/v2ray-core/v5/proxy/vless/inbound"
 	_ "github.com/v2fly/v2ray-core/v5/proxy/vless/outbound"
 	_ "github.com/v2fly/v2ray-core/v5/proxy/vmess/inbound"
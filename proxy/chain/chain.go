@@ -0,0 +1,84 @@
+package chain
+
+import (
+	"context"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/outbound"
+	"github.com/v2fly/v2ray-core/v5/proxy"
+	"github.com/v2fly/v2ray-core/v5/transport"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		o := new(Outbound)
+		err := core.RequireFeatures(ctx, func(ohm outbound.Manager) error {
+			return o.init(config.(*Config), ohm)
+		})
+		return o, err
+	}))
+}
+
+var _ proxy.ChainOutbound = (*Outbound)(nil)
+
+// Outbound sends connections through a chain of outbounds.
+type Outbound struct {
+	tags []string
+	ohm  outbound.Manager
+}
+
+func (o *Outbound) init(config *Config, ohm outbound.Manager) error {
+	if len(config.OutboundTag) == 0 {
+		return newError("no outbound in chain")
+	}
+	seen := make(map[string]bool)
+	for _, tag := range config.OutboundTag {
+		if tag == "" {
+			return newError("empty outbound tag in chain")
+		}
+		if seen[tag] {
+			return newError("outbound ", tag, " appears more than once in chain")
+		}
+		seen[tag] = true
+	}
+	o.tags = config.OutboundTag
+	o.ohm = ohm
+	return nil
+}
+
+// Chain implements proxy.ChainOutbound.
+func (o *Outbound) Chain() []string {
+	return o.tags
+}
+
+// Process implements proxy.Outbound.
+func (o *Outbound) Process(ctx context.Context, link *transport.Link, _ internet.Dialer) error {
+	outbound := session.OutboundFromContext(ctx)
+	if outbound == nil || !outbound.Target.IsValid() {
+		return newError("target not specified")
+	}
+	if session.ChainFromContext(ctx) != nil {
+		return newError("chain cannot be a hop of another chain")
+	}
+
+	for _, tag := range o.tags {
+		if o.ohm.GetHandler(tag) == nil {
+			return newError("outbound ", tag, " in chain not found")
+		}
+	}
+	last := len(o.tags) - 1
+	handler := o.ohm.GetHandler(o.tags[last])
+
+	chain := &session.Chain{Tags: o.tags}
+	ctx = session.ContextWithChain(ctx, chain)
+	newError("sending ", outbound.Target, " through ", len(o.tags), " hops").AtDebug().WriteToLog(session.ExportIDToError(ctx))
+	handler.Dispatch(session.ContextWithChainHop(ctx, chain, last), link)
+
+	if hop, err := chain.FirstError(); err != nil {
+		return newError("hop ", hop+1, " [", o.tags[hop], "] failed").Base(err)
+	}
+	return nil
+}
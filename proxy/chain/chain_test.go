@@ -0,0 +1,95 @@
+package chain
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/outbound"
+	"github.com/v2fly/v2ray-core/v5/transport"
+	"github.com/v2fly/v2ray-core/v5/transport/pipe"
+)
+
+type handlerManager struct {
+	outbound.Manager
+	handlers map[string]outbound.Handler
+}
+
+func (m *handlerManager) GetHandler(tag string) outbound.Handler {
+	if h, found := m.handlers[tag]; found {
+		return h
+	}
+	return nil
+}
+
+// hopHandler dispatches through the previous hop like proxyman handlers do,
+// and fails with err.
+type hopHandler struct {
+	tag     string
+	err     error
+	manager *handlerManager
+}
+
+func (*hopHandler) Start() error  { return nil }
+func (*hopHandler) Close() error  { return nil }
+func (h *hopHandler) Tag() string { return h.tag }
+
+func (h *hopHandler) Dispatch(ctx context.Context, link *transport.Link) {
+	chain := session.ChainFromContext(ctx)
+	if hop := chain.Hop(h.tag); hop > 0 {
+		previous := h.manager.GetHandler(chain.Tags[hop-1])
+		previous.Dispatch(session.ContextWithChainHop(ctx, chain, hop-1), link)
+	}
+	session.SubmitOutboundErrorToOriginator(ctx, h.err)
+}
+
+func newTestOutbound(t *testing.T, errs map[string]error, tags ...string) *Outbound {
+	manager := &handlerManager{handlers: make(map[string]outbound.Handler)}
+	for _, tag := range tags {
+		manager.handlers[tag] = &hopHandler{tag: tag, err: errs[tag], manager: manager}
+	}
+	o := new(Outbound)
+	if err := o.init(&Config{OutboundTag: tags}, manager); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func process(o *Outbound) error {
+	ctx := session.ContextWithOutbound(context.Background(), &session.Outbound{
+		Target: net.TCPDestination(net.DomainAddress("example.com"), 443),
+	})
+	reader, writer := pipe.New()
+	return o.Process(ctx, &transport.Link{Reader: reader, Writer: writer}, nil)
+}
+
+func TestChainHopError(t *testing.T) {
+	o := newTestOutbound(t, map[string]error{
+		"b": newError("handshake failed"),
+		"c": newError("unexpected EOF"),
+	}, "a", "b", "c")
+
+	err := process(o)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "hop 2 [b] failed") || !strings.Contains(err.Error(), "handshake failed") {
+		t.Error("unexpected error ", err)
+	}
+}
+
+func TestChainSuccess(t *testing.T) {
+	o := newTestOutbound(t, nil, "a", "b")
+	common.Must(process(o))
+}
+
+func TestChainConfig(t *testing.T) {
+	for _, tags := range [][]string{nil, {"a", ""}, {"a", "b", "a"}} {
+		if err := new(Outbound).init(&Config{OutboundTag: tags}, nil); err == nil {
+			t.Error("expected error for chain ", tags)
+		}
+	}
+}
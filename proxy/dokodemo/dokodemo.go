@@ -135,6 +135,9 @@ func (d *Door) Process(ctx context.Context, network net.Network, conn internet.C
 	ctx = proxyman.SetPreferUseIP(ctx, true)
 
 	if network == net.Network_TCP {
+		if inbound := session.InboundFromContext(ctx); inbound != nil {
+			inbound.CanSpliceCopy = true
+		}
 		return dispatcher.DispatchConn(ctx, dest, conn, true)
 	}
 
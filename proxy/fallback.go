@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/inbound"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+)
+
+// FallbackToInbound hands conn over to the inbound of tag, without a loopback
+// port. reader reads the bytes already read from conn, and then the rest of it.
+func FallbackToInbound(ctx context.Context, manager inbound.Manager, tag string, reader buf.Reader, conn internet.Connection) error {
+	if in := session.InboundFromContext(ctx); in != nil && in.Tag == tag {
+		return newError("inbound " + tag + " falls back to itself").AtWarning()
+	}
+	handler, err := manager.GetHandler(ctx, tag)
+	if err != nil {
+		return newError("failed to find the fallback inbound " + tag).Base(err).AtWarning()
+	}
+	connHandler, ok := handler.(inbound.ConnectionHandler)
+	if !ok {
+		return newError("inbound " + tag + " does not accept fallback connections").AtWarning()
+	}
+	return connHandler.HandleConnection(buf.NewConnection(
+		buf.ConnectionOutputMulti(reader),
+		buf.ConnectionInput(conn),
+		buf.ConnectionLocalAddr(conn.LocalAddr()),
+		buf.ConnectionRemoteAddr(conn.RemoteAddr()),
+		buf.ConnectionOnClose(conn),
+	))
+}
+
+// ProxyProtocolTLVs returns the PROXY protocol v2 TLVs of the SNI and ALPN of
+// a TLS connection, so that the fallback can log them.
+func ProxyProtocolTLVs(name, alpn string) []byte {
+	var tlvs []byte
+	if alpn != "" {
+		tlvs = append(tlvs, 0x01, byte(len(alpn)>>8), byte(len(alpn))) // PP2_TYPE_ALPN
+		tlvs = append(tlvs, alpn...)
+	}
+	if name != "" {
+		tlvs = append(tlvs, 0x02, byte(len(name)>>8), byte(len(name))) // PP2_TYPE_AUTHORITY
+		tlvs = append(tlvs, name...)
+	}
+	return tlvs
+}
@@ -0,0 +1,27 @@
+package proxy_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	. "github.com/v2fly/v2ray-core/v5/proxy"
+)
+
+func TestFallbackToItself(t *testing.T) {
+	ctx := session.ContextWithInbound(context.Background(), &session.Inbound{Tag: "in"})
+	if err := FallbackToInbound(ctx, nil, "in", nil, nil); err == nil {
+		t.Error("expected error for falling back to the same inbound")
+	}
+}
+
+func TestProxyProtocolTLVs(t *testing.T) {
+	tlvs := ProxyProtocolTLVs("example.com", "h2")
+	expected := "\x01\x00\x02h2\x02\x00\x0bexample.com"
+	if string(tlvs) != expected {
+		t.Errorf("expected %q, but got %q", expected, tlvs)
+	}
+	if tlvs := ProxyProtocolTLVs("", ""); len(tlvs) != 0 {
+		t.Error("expected no TLVs, but got ", tlvs)
+	}
+}
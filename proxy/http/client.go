@@ -27,7 +27,11 @@ import (
 
 type Client struct {
 	serverPicker  protocol.ServerPicker
+	serverEntries map[*protocol.ServerSpec]*ServerEntry
 	policyManager policy.Manager
+
+	cachedH2Mutex sync.Mutex
+	cachedH2Conns map[*protocol.ServerSpec]h2Conn
 }
 
 type h2Conn struct {
@@ -35,11 +39,6 @@ type h2Conn struct {
 	h2Conn  *http2.ClientConn
 }
 
-var (
-	cachedH2Mutex sync.Mutex
-	cachedH2Conns map[net.Destination]h2Conn
-)
-
 // NewClient create a new http client based on the given config.
 func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	serverList := protocol.NewServerList()
@@ -50,6 +49,15 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 		}
 		serverList.AddServer(s)
 	}
+	serverEntries := make(map[*protocol.ServerSpec]*ServerEntry)
+	for _, entry := range config.ServerEntry {
+		s, err := protocol.NewServerSpecFromPB(entry.Endpoint)
+		if err != nil {
+			return nil, newError("failed to get server spec").Base(err)
+		}
+		serverList.AddServer(s)
+		serverEntries[s] = entry
+	}
 	if serverList.Size() == 0 {
 		return nil, newError("0 target server")
 	}
@@ -57,6 +65,7 @@ func NewClient(ctx context.Context, config *ClientConfig) (*Client, error) {
 	v := core.MustFromContext(ctx)
 	return &Client{
 		serverPicker:  protocol.NewRoundRobinServerPicker(serverList),
+		serverEntries: serverEntries,
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
 	}, nil
 }
@@ -91,10 +100,9 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 
 	if err := retry.ExponentialBackoff(5, 100).On(func() error {
 		server := c.serverPicker.PickServer()
-		dest := server.Destination()
 		user = server.PickUser()
 
-		netConn, err := setUpHTTPTunnel(ctx, dest, targetAddr, user, dialer, firstPayload)
+		netConn, err := c.setUpHTTPTunnel(ctx, server, targetAddr, user, dialer, firstPayload)
 		if netConn != nil {
 			if _, ok := netConn.(*http2Conn); !ok {
 				if _, err := netConn.Write(firstPayload); err != nil {
@@ -138,7 +146,10 @@ func (c *Client) Process(ctx context.Context, link *transport.Link, dialer inter
 }
 
 // setUpHTTPTunnel will create a socket tunnel via HTTP CONNECT method
-func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, user *protocol.MemoryUser, dialer internet.Dialer, firstPayload []byte) (net.Conn, error) {
+func (c *Client) setUpHTTPTunnel(ctx context.Context, server *protocol.ServerSpec, target string, user *protocol.MemoryUser, dialer internet.Dialer, firstPayload []byte) (net.Conn, error) {
+	dest := server.Destination()
+	entry := c.serverEntries[server]
+
 	req := &http.Request{
 		Method: http.MethodConnect,
 		URL:    &url.URL{Host: target},
@@ -152,6 +163,10 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
 	}
 
+	for key, value := range entry.GetHeaders() {
+		req.Header.Set(key, value)
+	}
+
 	connectHTTP1 := func(rawConn net.Conn) (net.Conn, error) {
 		req.Header.Set("Proxy-Connection", "Keep-Alive")
 
@@ -188,28 +203,30 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			wg.Done()
 		}()
 
+		// rawConn may carry other tunnels, so errors only end this stream.
 		resp, err := h2clientConn.RoundTrip(req) // nolint: bodyclose
 		if err != nil {
-			rawConn.Close()
+			pw.Close()
 			return nil, err
 		}
 
 		wg.Wait()
 		if pErr != nil {
-			rawConn.Close()
+			resp.Body.Close()
 			return nil, pErr
 		}
 
 		if resp.StatusCode != http.StatusOK {
-			rawConn.Close()
+			pw.Close()
+			resp.Body.Close()
 			return nil, newError("Proxy responded with non 200 code: " + resp.Status)
 		}
 		return newHTTP2Conn(rawConn, pw, resp.Body), nil
 	}
 
-	cachedH2Mutex.Lock()
-	cachedConn, cachedConnFound := cachedH2Conns[dest]
-	cachedH2Mutex.Unlock()
+	c.cachedH2Mutex.Lock()
+	cachedConn, cachedConnFound := c.cachedH2Conns[server]
+	c.cachedH2Mutex.Unlock()
 
 	if cachedConnFound {
 		rc, cc := cachedConn.rawConn, cachedConn.h2Conn
@@ -227,6 +244,9 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 	if err != nil {
 		return nil, err
 	}
+	if entry.GetTlsSettings() != nil {
+		rawConn = tls.Client(rawConn, entry.TlsSettings.GetTLSConfig(tls.WithDestination(dest)))
+	}
 
 	iConn := rawConn
 	if statConn, ok := iConn.(*internet.StatCounterConn); ok {
@@ -259,16 +279,16 @@ func setUpHTTPTunnel(ctx context.Context, dest net.Destination, target string, u
 			return nil, err
 		}
 
-		cachedH2Mutex.Lock()
-		if cachedH2Conns == nil {
-			cachedH2Conns = make(map[net.Destination]h2Conn)
+		c.cachedH2Mutex.Lock()
+		if c.cachedH2Conns == nil {
+			c.cachedH2Conns = make(map[*protocol.ServerSpec]h2Conn)
 		}
 
-		cachedH2Conns[dest] = h2Conn{
+		c.cachedH2Conns[server] = h2Conn{
 			rawConn: rawConn,
 			h2Conn:  h2clientConn,
 		}
-		cachedH2Mutex.Unlock()
+		c.cachedH2Mutex.Unlock()
 
 		return proxyConn, err
 	default:
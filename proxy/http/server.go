@@ -175,6 +175,9 @@ func (s *Server) handleConnect(ctx context.Context, _ *http.Request, reader *buf
 		conn = I.NewCachedConn(conn, buffered)
 	}
 
+	if inbound := session.InboundFromContext(ctx); inbound != nil {
+		inbound.CanSpliceCopy = true
+	}
 	return dispatcher.DispatchConn(ctx, dest, conn, true)
 }
 
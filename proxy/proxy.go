@@ -5,6 +5,8 @@
 // 2. Register a config creator through common.RegisterConfig.
 package proxy
 
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
 import (
 	"context"
 
@@ -24,12 +26,33 @@ type Inbound interface {
 	Process(context.Context, net.Network, internet.Connection, routing.Dispatcher) error
 }
 
+// A StandaloneInbound accepts connections by itself, such as from a TUN
+// device, instead of from the listeners of its handler.
+type StandaloneInbound interface {
+	Inbound
+
+	// Serve starts accepting connections and dispatches them with dispatcher.
+	// The inbound and content in ctx are the base of those of each connection.
+	Serve(ctx context.Context, dispatcher routing.Dispatcher) error
+
+	// Close stops accepting connections.
+	Close() error
+}
+
 // An Outbound process outbound connections.
 type Outbound interface {
 	// Process processes the given connection. The given dialer may be used to dial a system outbound connection.
 	Process(context.Context, *transport.Link, internet.Dialer) error
 }
 
+// A ChainOutbound sends connections through other outbounds in order.
+type ChainOutbound interface {
+	Outbound
+
+	// Chain returns the tags of the outbounds, from the first hop to the last.
+	Chain() []string
+}
+
 type RawOutbound interface {
 	ProcessConn(context.Context, net.Conn, internet.Dialer) error
 }
@@ -0,0 +1,77 @@
+package socks
+
+import (
+	"sync"
+
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/signal/done"
+)
+
+type association struct {
+	count int
+	user  *protocol.MemoryUser
+	done  *done.Instance
+}
+
+// associationTable tracks the client IPs with an open UDP ASSOCIATE control
+// connection. UDP datagrams are only accepted from those IPs, and the UDP
+// sessions of an IP end with its last control connection.
+type associationTable struct {
+	access       sync.Mutex
+	associations map[string]*association
+}
+
+func newAssociationTable() *associationTable {
+	return &associationTable{
+		associations: make(map[string]*association),
+	}
+}
+
+// Add registers a control connection from the client IP. If there are
+// several, datagrams are attributed to the user of the latest one.
+func (t *associationTable) Add(ip net.Address, user *protocol.MemoryUser) {
+	key := ip.String()
+
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	a, found := t.associations[key]
+	if !found {
+		a = &association{done: done.New()}
+		t.associations[key] = a
+	}
+	a.count++
+	a.user = user
+}
+
+// Remove unregisters a control connection from the client IP.
+func (t *associationTable) Remove(ip net.Address) {
+	key := ip.String()
+
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	a, found := t.associations[key]
+	if !found {
+		return
+	}
+	a.count--
+	if a.count == 0 {
+		delete(t.associations, key)
+		a.done.Close()
+	}
+}
+
+// Get returns the user of the client IP and a channel that is closed when
+// its association ends, or false if it has none.
+func (t *associationTable) Get(ip net.Address) (*protocol.MemoryUser, <-chan struct{}, bool) {
+	t.access.Lock()
+	defer t.access.Unlock()
+
+	a, found := t.associations[ip.String()]
+	if !found {
+		return nil, nil, false
+	}
+	return a.user, a.done.Wait(), true
+}
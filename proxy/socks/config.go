@@ -12,14 +12,3 @@ func (a *Account) Equals(another protocol.Account) bool {
 func (a *Account) AsAccount() (protocol.Account, error) {
 	return a, nil
 }
-
-func (c *ServerConfig) HasAccount(username, password string) bool {
-	if c.Accounts == nil {
-		return false
-	}
-	storedPassed, found := c.Accounts[username]
-	if !found {
-		return false
-	}
-	return storedPassed == password
-}
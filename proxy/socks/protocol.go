@@ -44,6 +44,8 @@ type ServerSession struct {
 	address       net.Address
 	port          net.Port
 	clientAddress net.Address
+	validator     *Validator
+	associations  *associationTable
 }
 
 func (s *ServerSession) handshake4(cmd byte, reader io.Reader, writer io.Writer) (*protocol.RequestHeader, error) {
@@ -95,12 +97,12 @@ func (s *ServerSession) handshake4(cmd byte, reader io.Reader, writer io.Writer)
 	}
 }
 
-func (s *ServerSession) auth5(nMethod byte, reader io.Reader, writer io.Writer) (username string, err error) {
+func (s *ServerSession) auth5(nMethod byte, reader io.Reader, writer io.Writer) (*protocol.MemoryUser, error) {
 	buffer := buf.StackNew()
 	defer buffer.Release()
 
-	if _, err = buffer.ReadFullFrom(reader, int32(nMethod)); err != nil {
-		return "", newError("failed to read auth methods").Base(err)
+	if _, err := buffer.ReadFullFrom(reader, int32(nMethod)); err != nil {
+		return nil, newError("failed to read auth methods").Base(err)
 	}
 
 	var expectedAuth byte = authNotRequired
@@ -110,39 +112,37 @@ func (s *ServerSession) auth5(nMethod byte, reader io.Reader, writer io.Writer)
 
 	if !hasAuthMethod(expectedAuth, buffer.BytesRange(0, int32(nMethod))) {
 		writeSocks5AuthenticationResponse(writer, socks5Version, authNoMatchingMethod)
-		return "", newError("no matching auth method")
+		return nil, newError("no matching auth method")
 	}
 
 	if err := writeSocks5AuthenticationResponse(writer, socks5Version, expectedAuth); err != nil {
-		return "", newError("failed to write auth response").Base(err)
+		return nil, newError("failed to write auth response").Base(err)
 	}
 
 	if expectedAuth == authPassword {
 		username, password, err := ReadUsernamePassword(reader)
 		if err != nil {
-			return "", newError("failed to read username and password for authentication").Base(err)
+			return nil, newError("failed to read username and password for authentication").Base(err)
 		}
 
-		if !s.config.HasAccount(username, password) {
+		user := s.validator.Get(username, password)
+		if user == nil {
 			writeSocks5AuthenticationResponse(writer, 0x01, 0xFF)
-			return "", newError("invalid username or password")
+			return nil, newError("invalid username or password")
 		}
 
 		if err := writeSocks5AuthenticationResponse(writer, 0x01, 0x00); err != nil {
-			return "", newError("failed to write auth response").Base(err)
+			return nil, newError("failed to write auth response").Base(err)
 		}
-		return username, nil
+		return user, nil
 	}
 
-	return "", nil
+	return nil, nil
 }
 
 func (s *ServerSession) handshake5(nMethod byte, reader io.Reader, writer io.Writer) (*protocol.RequestHeader, error) {
-	var (
-		username string
-		err      error
-	)
-	if username, err = s.auth5(nMethod, reader, writer); err != nil {
+	user, err := s.auth5(nMethod, reader, writer)
+	if err != nil {
 		return nil, err
 	}
 
@@ -157,10 +157,7 @@ func (s *ServerSession) handshake5(nMethod byte, reader io.Reader, writer io.Wri
 		buffer.Release()
 	}
 
-	request := new(protocol.RequestHeader)
-	if username != "" {
-		request.User = &protocol.MemoryUser{Email: username}
-	}
+	request := &protocol.RequestHeader{User: user}
 	switch cmd {
 	case cmdTCPConnect, cmdTorResolve, cmdTorResolvePTR:
 		// We don't have a solution for Tor case now. Simply treat it as connect command.
@@ -202,8 +199,14 @@ func (s *ServerSession) handshake5(nMethod byte, reader io.Reader, writer io.Wri
 			// For non-localhost clients use inbound listening address
 			responseAddress = s.address
 		}
+		// Register the association before replying, since the client may
+		// send datagrams as soon as it has the reply.
+		s.associations.Add(s.clientAddress, request.User)
 	}
 	if err := writeSocks5Response(writer, statusSuccess, responseAddress, responsePort); err != nil {
+		if request.Command == protocol.RequestCommandUDP {
+			s.associations.Remove(s.clientAddress)
+		}
 		return nil, err
 	}
 
@@ -28,6 +28,8 @@ import (
 type Server struct {
 	config        *ServerConfig
 	policyManager policy.Manager
+	validator     *Validator
+	associations  *associationTable
 }
 
 // NewServer creates a new Server object.
@@ -36,17 +38,51 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	s := &Server{
 		config:        config,
 		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
+		validator:     new(Validator),
+		associations:  newAssociationTable(),
 	}
+
+	for username, password := range config.Accounts {
+		if err := s.validator.Add(&protocol.MemoryUser{
+			Email:   username,
+			Level:   config.UserLevel,
+			Account: &Account{Username: username, Password: password},
+		}); err != nil {
+			return nil, newError("failed to add account ", username).Base(err)
+		}
+	}
+	for _, user := range config.Users {
+		mUser, err := user.ToMemoryUser()
+		if err != nil {
+			return nil, newError("failed to get SOCKS user").Base(err).AtError()
+		}
+		if err := s.validator.Add(mUser); err != nil {
+			return nil, newError("failed to add user").Base(err).AtError()
+		}
+	}
+
 	return s, nil
 }
 
-func (s *Server) policy() policy.Session {
+// AddUser implements proxy.UserManager.AddUser().
+func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
+	return s.validator.Add(u)
+}
+
+// RemoveUser implements proxy.UserManager.RemoveUser().
+func (s *Server) RemoveUser(ctx context.Context, e string) error {
+	return s.validator.Del(e)
+}
+
+// policy returns the policy of the given user level. The server level applies
+// until the user is authenticated.
+func (s *Server) policy(level uint32) policy.Session {
 	config := s.config
-	p := s.policyManager.ForLevel(config.UserLevel)
+	p := s.policyManager.ForLevel(level)
 	if config.Timeout > 0 {
 		features.PrintDeprecatedFeatureWarning("Socks timeout")
 	}
-	if config.Timeout > 0 && config.UserLevel == 0 {
+	if config.Timeout > 0 && level == 0 {
 		p.Timeouts.ConnectionIdle = time.Duration(config.Timeout) * time.Second
 	}
 	return p
@@ -80,7 +116,7 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 }
 
 func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispatcher routing.Dispatcher) error {
-	plcy := s.policy()
+	plcy := s.policy(s.config.UserLevel)
 	if err := conn.SetReadDeadline(time.Now().Add(plcy.Timeouts.Handshake)); err != nil {
 		newError("failed to set deadline").Base(err).WriteToLog(session.ExportIDToError(ctx))
 	}
@@ -95,6 +131,8 @@ func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispa
 		address:       inbound.Gateway.Address,
 		port:          inbound.Gateway.Port,
 		clientAddress: inbound.Source.Address,
+		validator:     s.validator,
+		associations:  s.associations,
 	}
 
 	request, err := svrSession.Handshake(conn, conn)
@@ -110,7 +148,7 @@ func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispa
 		return newError("failed to read request").Base(err)
 	}
 	if request.User != nil {
-		inbound.User.Email = request.User.Email
+		inbound.User = request.User
 	}
 
 	if err := conn.SetReadDeadline(time.Time{}); err != nil {
@@ -126,6 +164,7 @@ func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispa
 				To:     dest,
 				Status: log.AccessAccepted,
 				Reason: "",
+				Email:  inbound.User.Email,
 			})
 		}
 
@@ -162,11 +201,19 @@ func (s *Server) processTCP(ctx context.Context, conn internet.Connection, dispa
 			return nil
 		}
 
+		// Traffic of users is counted, and limited by their policy, on links
+		// from Dispatch only.
+		if inbound.User.Email != "" {
+			return s.transport(ctx, conn, conn, dest, dispatcher)
+		}
+		inbound.CanSpliceCopy = true
 		return dispatcher.DispatchConn(ctx, dest, conn, true)
-		// return s.transport(ctx, reader, conn, dest, dispatcher)
 	}
 
 	if request.Command == protocol.RequestCommandUDP {
+		// The association was registered during the handshake, and UDP
+		// sessions from the client end once it is removed.
+		defer s.associations.Remove(inbound.Source.Address)
 		return s.handleUDP(conn)
 	}
 
@@ -180,10 +227,15 @@ func (*Server) handleUDP(c io.Reader) error {
 }
 
 func (s *Server) transport(ctx context.Context, reader io.Reader, writer io.Writer, dest net.Destination, dispatcher routing.Dispatcher) error {
+	level := s.config.UserLevel
+	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.User != nil {
+		level = inbound.User.Level
+	}
+	plcy := s.policy(level)
+
 	ctx, cancel := context.WithCancel(ctx)
-	timer := signal.CancelAfterInactivity(ctx, cancel, s.policy().Timeouts.ConnectionIdle)
+	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
 
-	plcy := s.policy()
 	ctx = policy.ContextWithBufferPolicy(ctx, plcy.Buffer)
 	link, err := dispatcher.Dispatch(ctx, dest)
 	if err != nil {
@@ -221,6 +273,35 @@ func (s *Server) transport(ctx context.Context, reader io.Reader, writer io.Writ
 }
 
 func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection, dispatcher routing.Dispatcher) error {
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || !inbound.Source.IsValid() {
+		return newError("inbound source not specified")
+	}
+	user, associationDone, found := s.associations.Get(inbound.Source.Address)
+	if !found {
+		log.Record(&log.AccessMessage{
+			From:   inbound.Source,
+			To:     "",
+			Status: log.AccessRejected,
+			Reason: "no UDP association",
+		})
+		return newError("rejected UDP packets from ", inbound.Source, " without association")
+	}
+	if user != nil {
+		inbound.User = user
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-associationDone:
+			newError("UDP association of ", inbound.Source, " ended").AtDebug().WriteToLog(session.ExportIDToError(ctx))
+			conn.Close()
+		case <-ctx.Done():
+		}
+	}()
+
 	udpDispatcherConstructor := udp.NewSplitDispatcher
 	switch s.config.PacketEncoding {
 	case packetaddr.PacketAddrType_None:
@@ -253,10 +334,9 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 
 		conn.Write(udpMessage.Bytes())
 	})
+	defer udpServer.Close()
 
-	if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.IsValid() {
-		newError("client UDP connection from ", inbound.Source).WriteToLog(session.ExportIDToError(ctx))
-	}
+	newError("client UDP connection from ", inbound.Source).WriteToLog(session.ExportIDToError(ctx))
 
 	reader := buf.NewPacketReader(conn)
 	for {
@@ -277,16 +357,14 @@ func (s *Server) handleUDPPayload(ctx context.Context, conn internet.Connection,
 				payload.Release()
 				continue
 			}
-			currentPacketCtx := ctx
 			newError("send packet to ", request.Destination(), " with ", payload.Len(), " bytes").AtDebug().WriteToLog(session.ExportIDToError(ctx))
-			if inbound := session.InboundFromContext(ctx); inbound != nil && inbound.Source.IsValid() {
-				currentPacketCtx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
-					From:   inbound.Source,
-					To:     request.Destination(),
-					Status: log.AccessAccepted,
-					Reason: "",
-				})
-			}
+			currentPacketCtx := log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+				From:   inbound.Source,
+				To:     request.Destination(),
+				Status: log.AccessAccepted,
+				Reason: "",
+				Email:  inbound.User.Email,
+			})
 
 			currentPacketCtx = protocol.ContextWithRequestHeader(currentPacketCtx, request)
 			udpServer.Dispatch(currentPacketCtx, request.Destination(), payload)
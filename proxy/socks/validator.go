@@ -0,0 +1,84 @@
+package socks
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+)
+
+// Validator stores valid SOCKS users by username.
+type Validator struct {
+	access sync.RWMutex
+	email  map[string]*protocol.MemoryUser
+	users  map[string]*protocol.MemoryUser
+}
+
+// Add a SOCKS user, Email must be empty or unique, and so must the username.
+func (v *Validator) Add(u *protocol.MemoryUser) error {
+	account, ok := u.Account.(*Account)
+	if !ok {
+		return newError("User ", u.Email, " does not have a SOCKS account.")
+	}
+	le := strings.ToLower(u.Email)
+
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	if v.users == nil {
+		v.email = make(map[string]*protocol.MemoryUser)
+		v.users = make(map[string]*protocol.MemoryUser)
+	}
+	if le != "" {
+		if _, found := v.email[le]; found {
+			return newError("User ", u.Email, " already exists.")
+		}
+	}
+	if _, found := v.users[account.Username]; found {
+		return newError("User with username ", account.Username, " already exists.")
+	}
+	if le != "" {
+		v.email[le] = u
+	}
+	v.users[account.Username] = u
+	return nil
+}
+
+// Del a SOCKS user with a non-empty Email.
+func (v *Validator) Del(e string) error {
+	if e == "" {
+		return newError("Email must not be empty.")
+	}
+	le := strings.ToLower(e)
+
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	u, found := v.email[le]
+	if !found {
+		return newError("User ", e, " not found.")
+	}
+	delete(v.email, le)
+	delete(v.users, u.Account.(*Account).Username)
+	return nil
+}
+
+// Get a SOCKS user by username and password, nil if there is no such user or
+// the password does not match.
+func (v *Validator) Get(username, password string) *protocol.MemoryUser {
+	v.access.RLock()
+	u := v.users[username]
+	v.access.RUnlock()
+
+	if u == nil || u.Account.(*Account).Password != password {
+		return nil
+	}
+	return u
+}
+
+// GetByEmail returns the user with the given Email, nil if user doesn't exist.
+func (v *Validator) GetByEmail(e string) *protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return v.email[strings.ToLower(e)]
+}
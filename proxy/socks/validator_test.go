@@ -0,0 +1,54 @@
+package socks_test
+
+import (
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	. "github.com/v2fly/v2ray-core/v5/proxy/socks"
+)
+
+func newUser(email, username, password string) *protocol.MemoryUser {
+	return &protocol.MemoryUser{
+		Email:   email,
+		Account: &Account{Username: username, Password: password},
+	}
+}
+
+func TestValidator(t *testing.T) {
+	v := new(Validator)
+
+	a := newUser("a@v2fly.org", "a", "password-a")
+	b := newUser("b@v2fly.org", "b", "password-b")
+	common.Must(v.Add(a))
+	common.Must(v.Add(b))
+
+	if err := v.Add(newUser("A@v2fly.org", "c", "password-c")); err == nil {
+		t.Error("expected error for duplicate email")
+	}
+	if err := v.Add(newUser("c@v2fly.org", "a", "password-c")); err == nil {
+		t.Error("expected error for duplicate username")
+	}
+	if err := v.Add(&protocol.MemoryUser{Email: "d@v2fly.org"}); err == nil {
+		t.Error("expected error for user without account")
+	}
+
+	if u := v.Get("a", "password-a"); u != a {
+		t.Error("unexpected user ", u)
+	}
+	if u := v.Get("a", "password-b"); u != nil {
+		t.Error("unexpected user for wrong password ", u)
+	}
+	if u := v.GetByEmail("B@V2FLY.ORG"); u != b {
+		t.Error("unexpected user ", u)
+	}
+
+	common.Must(v.Del("a@v2fly.org"))
+	if v.Get("a", "password-a") != nil {
+		t.Error("removed user must not be found")
+	}
+	if err := v.Del("a@v2fly.org"); err == nil {
+		t.Error("expected error for removed user")
+	}
+	common.Must(v.Add(newUser("c@v2fly.org", "a", "password-c")))
+}
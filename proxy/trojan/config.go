@@ -3,7 +3,6 @@ package trojan
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"fmt"
 
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
@@ -42,11 +41,3 @@ func hexSha224(password string) []byte {
 	hex.Encode(buf, hash.Sum(nil))
 	return buf
 }
-
-func hexString(data []byte) string {
-	str := ""
-	for _, v := range data {
-		str += fmt.Sprintf("%02x", v)
-	}
-	return str
-}
@@ -16,14 +16,17 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/platform"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/authprovider"
 	udp_proto "github.com/v2fly/v2ray-core/v5/common/protocol/udp"
 	"github.com/v2fly/v2ray-core/v5/common/retry"
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/common/signal"
 	"github.com/v2fly/v2ray-core/v5/common/task"
+	feature_inbound "github.com/v2fly/v2ray-core/v5/features/inbound"
 	"github.com/v2fly/v2ray-core/v5/features/policy"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
 	"github.com/v2fly/v2ray-core/v5/features/stats"
+	"github.com/v2fly/v2ray-core/v5/proxy"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
 	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
 	"github.com/v2fly/v2ray-core/v5/transport/internet/udp"
@@ -45,29 +48,45 @@ func init() {
 
 // Server is an inbound connection handler that handles messages in trojan protocol.
 type Server struct {
-	policyManager policy.Manager
-	validator     *Validator
-	fallbacks     map[string]map[string]map[string]*Fallback // or nil
+	inboundHandlerManager feature_inbound.Manager
+	policyManager         policy.Manager
+	statsManager          stats.Manager
+	validator             *Validator
+	resolver              *authprovider.Resolver
+	fallbacks             map[string]map[string]map[string]*Fallback // or nil
 }
 
 // NewServer creates a new trojan inbound handler.
 func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	validator := new(Validator)
+	v := core.MustFromContext(ctx)
+	server := &Server{
+		inboundHandlerManager: v.GetFeature(feature_inbound.ManagerType()).(feature_inbound.Manager),
+		policyManager:         v.GetFeature(policy.ManagerType()).(policy.Manager),
+		statsManager:          v.GetFeature(stats.ManagerType()).(stats.Manager),
+		validator:             validator,
+	}
+
 	for _, user := range config.Users {
 		u, err := user.ToMemoryUser()
 		if err != nil {
 			return nil, newError("failed to get trojan user").Base(err).AtError()
 		}
 
-		if err := validator.Add(u); err != nil {
+		if err := server.AddUser(ctx, u); err != nil {
 			return nil, newError("failed to add user").Base(err).AtError()
 		}
 	}
 
-	v := core.MustFromContext(ctx)
-	server := &Server{
-		policyManager: v.GetFeature(policy.ManagerType()).(policy.Manager),
-		validator:     validator,
+	if config.AuthProvider != nil {
+		resolver, err := authprovider.NewResolver(config.AuthProvider, toMemoryUser)
+		if err != nil {
+			return nil, newError("failed to create auth provider").Base(err).AtError()
+		}
+		server.resolver = resolver
+		validator.SetResolver(func(key []byte) *protocol.MemoryUser {
+			return resolver.Resolve(string(key))
+		})
 	}
 
 	if config.Fallbacks != nil {
@@ -123,14 +142,108 @@ func NewServer(ctx context.Context, config *ServerConfig) (*Server, error) {
 	return server, nil
 }
 
+func toMemoryUser(user *authprovider.User, key string) (*protocol.MemoryUser, error) {
+	account, err := (&Account{Password: user.Password, Flow: user.Flow}).AsAccount()
+	if err != nil {
+		return nil, err
+	}
+	if string(account.(*MemoryAccount).Key) != key {
+		return nil, newError("password does not match ", key)
+	}
+	return &protocol.MemoryUser{
+		Account: account,
+		Email:   user.Email,
+		Level:   user.Level,
+	}, nil
+}
+
+// Close implements common.Closable.
+func (s *Server) Close() error {
+	if s.resolver != nil {
+		return s.resolver.Close()
+	}
+	return nil
+}
+
 // AddUser implements proxy.UserManager.AddUser().
 func (s *Server) AddUser(ctx context.Context, u *protocol.MemoryUser) error {
-	return s.validator.Add(u)
+	if err := s.validator.Add(u); err != nil {
+		return err
+	}
+	s.registerUserCounters(u)
+	return nil
 }
 
 // RemoveUser implements proxy.UserManager.RemoveUser().
 func (s *Server) RemoveUser(ctx context.Context, e string) error {
-	return s.validator.Del(e)
+	u := s.validator.GetByEmail(e)
+	if err := s.validator.Del(e); err != nil {
+		return err
+	}
+	s.unregisterUserCounters(u)
+	return nil
+}
+
+// GetUser returns the user with the given email, nil if there is none.
+func (s *Server) GetUser(email string) *protocol.MemoryUser {
+	return s.validator.GetByEmail(email)
+}
+
+// GetUsers returns all users added by config or through the API.
+func (s *Server) GetUsers() []*protocol.MemoryUser {
+	return s.validator.GetAll()
+}
+
+// GetUsersCount returns the number of users added by config or through the API.
+func (s *Server) GetUsersCount() int {
+	return s.validator.GetCount()
+}
+
+// trafficCounterNames returns the names of the per-user traffic counters
+// enabled by the policy of u's level. They are counted by the dispatcher and
+// shared with other inbounds, so the server only registers them.
+func (s *Server) trafficCounterNames(u *protocol.MemoryUser) []string {
+	var names []string
+	p := s.policyManager.ForLevel(u.Level)
+	if p.Stats.UserUplink {
+		names = append(names, "user>>>"+u.Email+">>>traffic>>>uplink")
+	}
+	if p.Stats.UserDownlink {
+		names = append(names, "user>>>"+u.Email+">>>traffic>>>downlink")
+	}
+	return names
+}
+
+func udpAssociateCounterName(email string) string {
+	return "user>>>" + email + ">>>udp>>>associate"
+}
+
+// registerUserCounters registers the counters of u up front, so they are
+// listed before the user has any traffic.
+func (s *Server) registerUserCounters(u *protocol.MemoryUser) {
+	if u.Email == "" {
+		return
+	}
+	for _, name := range s.trafficCounterNames(u) {
+		stats.GetOrRegisterCounter(s.statsManager, name)
+	}
+	stats.GetOrRegisterCounter(s.statsManager, udpAssociateCounterName(u.Email))
+}
+
+// unregisterUserCounters unregisters the counter of u that the server counts.
+func (s *Server) unregisterUserCounters(u *protocol.MemoryUser) {
+	if u.Email == "" {
+		return
+	}
+	s.statsManager.UnregisterCounter(udpAssociateCounterName(u.Email))
+}
+
+func (s *Server) udpAssociateCounter(u *protocol.MemoryUser) stats.Counter {
+	if u.Email == "" {
+		return nil
+	}
+	c, _ := stats.GetOrRegisterCounter(s.statsManager, udpAssociateCounterName(u.Email))
+	return c
 }
 
 // Network implements proxy.Inbound.Network().
@@ -185,7 +298,7 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 
 		shouldFallback = true
 	} else {
-		user = s.validator.Get(hexString(first.BytesTo(56)))
+		user = s.validator.Get(first.BytesTo(KeyLength))
 		if user == nil {
 			// invalid user, let's fallback
 			err = newError("not a valid user")
@@ -230,6 +343,9 @@ func (s *Server) Process(ctx context.Context, network net.Network, conn internet
 	sessionPolicy = s.policyManager.ForLevel(user.Level)
 
 	if destination.Network == net.Network_UDP { // handle udp request
+		if c := s.udpAssociateCounter(user); c != nil {
+			c.Add(1)
+		}
 		return s.handleUDPPayload(ctx, &PacketReader{Reader: clientReader}, &PacketWriter{Writer: conn}, dispatcher)
 	}
 
@@ -408,6 +524,7 @@ func (s *Server) fallback(ctx context.Context, sid errors.ExportOption, err erro
 	}
 	name = strings.ToLower(name)
 	alpn = strings.ToLower(alpn)
+	realName, realAlpn := name, alpn
 
 	if len(napfb) > 1 || napfb[""] == nil {
 		if name != "" && napfb[name] == nil {
@@ -471,6 +588,11 @@ func (s *Server) fallback(ctx context.Context, sid errors.ExportOption, err erro
 		return newError(`failed to find the default "path" config`).AtWarning()
 	}
 
+	// Hands the connection over to another inbound, without a loopback port.
+	if fb.Type == "inbound" {
+		return proxy.FallbackToInbound(ctx, s.inboundHandlerManager, fb.Dest, reader, connection)
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
 	ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
@@ -530,18 +652,22 @@ func (s *Server) fallback(ctx context.Context, sid errors.ExportOption, err erro
 					common.Must2(pro.Write([]byte("\x20\x00\x00\x00"))) // v2 + LOCAL + UNSPEC + UNSPEC + 0 bytes
 					break
 				}
+				tlvs := proxy.ProxyProtocolTLVs(realName, realAlpn)
 				if ipType == 4 {
-					common.Must2(pro.Write([]byte("\x21\x11\x00\x0C"))) // v2 + PROXY + AF_INET + STREAM + 12 bytes
+					l := 12 + len(tlvs)
+					common.Must2(pro.Write([]byte{0x21, 0x11, byte(l >> 8), byte(l)})) // v2 + PROXY + AF_INET + STREAM + 12 bytes and TLVs
 					common.Must2(pro.Write(net.ParseIP(remoteAddr).To4()))
 					common.Must2(pro.Write(net.ParseIP(localAddr).To4()))
 				} else {
-					common.Must2(pro.Write([]byte("\x21\x21\x00\x24"))) // v2 + PROXY + AF_INET6 + STREAM + 36 bytes
+					l := 36 + len(tlvs)
+					common.Must2(pro.Write([]byte{0x21, 0x21, byte(l >> 8), byte(l)})) // v2 + PROXY + AF_INET6 + STREAM + 36 bytes and TLVs
 					common.Must2(pro.Write(net.ParseIP(remoteAddr).To16()))
 					common.Must2(pro.Write(net.ParseIP(localAddr).To16()))
 				}
 				p1, _ := strconv.ParseUint(remotePort, 10, 16)
 				p2, _ := strconv.ParseUint(localPort, 10, 16)
 				common.Must2(pro.Write([]byte{byte(p1 >> 8), byte(p1), byte(p2 >> 8), byte(p2)}))
+				common.Must2(pro.Write(tlvs))
 			}
 			if err := serverWriter.WriteMultiBuffer(buf.MultiBuffer{pro}); err != nil {
 				return newError("failed to set PROXY protocol v", fb.Xver).Base(err).AtWarning()
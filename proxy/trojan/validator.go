@@ -7,22 +7,58 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 )
 
-// Validator stores valid trojan users.
+// KeyLength is the length of the hex encoded SHA-224 password hash that
+// identifies a trojan user.
+const KeyLength = 56
+
+type validatorKey [KeyLength]byte
+
+// Validator stores valid trojan users. Lookups by key do not allocate, so it
+// scales to large numbers of users.
 type Validator struct {
-	// Considering email's usage here, map + sync.Mutex/RWMutex may have better performance.
-	email sync.Map
-	users sync.Map
+	access sync.RWMutex
+	email  map[string]*protocol.MemoryUser
+	users  map[validatorKey]*protocol.MemoryUser
+
+	resolve func(key []byte) *protocol.MemoryUser
+}
+
+// SetResolver sets a function that Get falls back to for users that have not
+// been added, if the key is well-formed. It must be called before the validator is in use.
+func (v *Validator) SetResolver(resolve func(key []byte) *protocol.MemoryUser) {
+	v.resolve = resolve
+}
+
+func keyOf(u *protocol.MemoryUser) (key validatorKey) {
+	copy(key[:], u.Account.(*MemoryAccount).Key)
+	return
 }
 
-// Add a trojan user, Email must be empty or unique.
+// Add a trojan user, Email must be empty or unique. A user with the password
+// of another one replaces it, which is deprecated.
 func (v *Validator) Add(u *protocol.MemoryUser) error {
-	if u.Email != "" {
-		_, loaded := v.email.LoadOrStore(strings.ToLower(u.Email), u)
-		if loaded {
+	key := keyOf(u)
+	le := strings.ToLower(u.Email)
+
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	if v.users == nil {
+		v.email = make(map[string]*protocol.MemoryUser)
+		v.users = make(map[validatorKey]*protocol.MemoryUser)
+	}
+	if le != "" {
+		if _, found := v.email[le]; found {
 			return newError("User ", u.Email, " already exists.")
 		}
 	}
-	v.users.Store(hexString(u.Account.(*MemoryAccount).Key), u)
+	if _, found := v.users[key]; found {
+		newError("users with the same password are deprecated and will be rejected in the future, the last one of them is used").AtWarning().WriteToLog()
+	}
+	if le != "" {
+		v.email[le] = u
+	}
+	v.users[key] = u
 	return nil
 }
 
@@ -32,20 +68,73 @@ func (v *Validator) Del(e string) error {
 		return newError("Email must not be empty.")
 	}
 	le := strings.ToLower(e)
-	u, _ := v.email.Load(le)
-	if u == nil {
+
+	v.access.Lock()
+	defer v.access.Unlock()
+
+	u, found := v.email[le]
+	if !found {
 		return newError("User ", e, " not found.")
 	}
-	v.email.Delete(le)
-	v.users.Delete(hexString(u.(*protocol.MemoryUser).Account.(*MemoryAccount).Key))
+	delete(v.email, le)
+	// The user may have been replaced by another one with the same password.
+	if key := keyOf(u); v.users[key] == u {
+		delete(v.users, key)
+	}
 	return nil
 }
 
 // Get a trojan user with hashed key, nil if user doesn't exist.
-func (v *Validator) Get(hash string) *protocol.MemoryUser {
-	u, _ := v.users.Load(hash)
-	if u != nil {
-		return u.(*protocol.MemoryUser)
+func (v *Validator) Get(hash []byte) *protocol.MemoryUser {
+	var key validatorKey
+	copy(key[:], hash)
+
+	v.access.RLock()
+	u := v.users[key]
+	v.access.RUnlock()
+
+	if u == nil && v.resolve != nil && isKey(hash) {
+		return v.resolve(hash)
 	}
-	return nil
+	return u
+}
+
+// isKey reports whether b has the form of a key, so that fallback traffic is
+// not resolved.
+func isKey(b []byte) bool {
+	if len(b) != KeyLength {
+		return false
+	}
+	for _, c := range b {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// GetByEmail returns the user with the given Email, nil if user doesn't exist.
+func (v *Validator) GetByEmail(e string) *protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return v.email[strings.ToLower(e)]
+}
+
+// GetAll returns all users that have been added.
+func (v *Validator) GetAll() []*protocol.MemoryUser {
+	v.access.RLock()
+	defer v.access.RUnlock()
+
+	users := make([]*protocol.MemoryUser, 0, len(v.users))
+	for _, u := range v.users {
+		users = append(users, u)
+	}
+	return users
+}
+
+// GetCount returns the number of users that have been added.
+func (v *Validator) GetCount() int {
+	v.access.RLock()
+	defer v.access.RUnlock()
+	return len(v.users)
 }
@@ -0,0 +1,141 @@
+package trojan_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	. "github.com/v2fly/v2ray-core/v5/proxy/trojan"
+)
+
+func newUser(email, password string) *protocol.MemoryUser {
+	return &protocol.MemoryUser{
+		Email:   email,
+		Account: toAccount(&Account{Password: password}),
+	}
+}
+
+func keyOf(u *protocol.MemoryUser) []byte {
+	return u.Account.(*MemoryAccount).Key
+}
+
+func TestValidator(t *testing.T) {
+	v := new(Validator)
+
+	a := newUser("a@v2fly.org", "password-a")
+	b := newUser("b@v2fly.org", "password-b")
+	common.Must(v.Add(a))
+	common.Must(v.Add(b))
+
+	if err := v.Add(newUser("A@v2fly.org", "password-c")); err == nil {
+		t.Error("expected error for duplicate email")
+	}
+	if v.GetByEmail("c@v2fly.org") != nil {
+		t.Error("rejected user must not be added")
+	}
+
+	if u := v.Get(keyOf(a)); u != a {
+		t.Error("unexpected user ", u)
+	}
+	if u := v.GetByEmail("B@V2FLY.ORG"); u != b {
+		t.Error("unexpected user ", u)
+	}
+	if c := v.GetCount(); c != 2 {
+		t.Error("expected 2 users, got ", c)
+	}
+
+	common.Must(v.Del("a@v2fly.org"))
+	if v.Get(keyOf(a)) != nil {
+		t.Error("removed user must not be found")
+	}
+	if err := v.Del("a@v2fly.org"); err == nil {
+		t.Error("expected error for removed user")
+	}
+	if users := v.GetAll(); len(users) != 1 || users[0] != b {
+		t.Error("unexpected users ", users)
+	}
+}
+
+func TestValidatorSamePassword(t *testing.T) {
+	v := new(Validator)
+
+	a := newUser("a@v2fly.org", "password")
+	b := newUser("b@v2fly.org", "password")
+	common.Must(v.Add(a))
+	// Deprecated, but still accepted as before.
+	common.Must(v.Add(b))
+
+	if u := v.Get(keyOf(a)); u != b {
+		t.Error("expected the last user, got ", u)
+	}
+	common.Must(v.Del("a@v2fly.org"))
+	if u := v.Get(keyOf(b)); u != b {
+		t.Error("removing a replaced user must keep the user with the same password, got ", u)
+	}
+}
+
+func TestValidatorResolver(t *testing.T) {
+	v := new(Validator)
+	a := newUser("a@v2fly.org", "password-a")
+	v.SetResolver(func(key []byte) *protocol.MemoryUser {
+		if string(key) == string(keyOf(a)) {
+			return a
+		}
+		return nil
+	})
+
+	if u := v.Get(keyOf(a)); u != a {
+		t.Error("unexpected user ", u)
+	}
+	if u := v.Get(keyOf(newUser("", "unknown"))); u != nil {
+		t.Error("unexpected user ", u)
+	}
+	if c := v.GetCount(); c != 0 {
+		t.Error("resolved users must not be added, got ", c)
+	}
+}
+
+func TestValidatorResolverMalformedKey(t *testing.T) {
+	v := new(Validator)
+	v.SetResolver(func(key []byte) *protocol.MemoryUser {
+		t.Error("malformed key ", string(key), " must not be resolved")
+		return nil
+	})
+
+	key := keyOf(newUser("", "password"))
+	for _, malformed := range [][]byte{
+		[]byte("GET / HTTP/1.1\r\nHost: www.example.com\r\nUser-Agent: curl/"),
+		[]byte(strings.ToUpper(string(key))),
+		key[:KeyLength-1],
+	} {
+		if u := v.Get(malformed); u != nil {
+			t.Error("unexpected user ", u)
+		}
+	}
+}
+
+func BenchmarkValidatorGet(b *testing.B) {
+	const userCount = 200000
+
+	v := new(Validator)
+	keys := make([][]byte, userCount)
+	for i := 0; i < userCount; i++ {
+		u := newUser("user"+strconv.Itoa(i)+"@v2fly.org", "password"+strconv.Itoa(i))
+		common.Must(v.Add(u))
+		keys[i] = keyOf(u)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if v.Get(keys[i%userCount]) == nil {
+				b.Fatal("user not found")
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package tun
+
+import (
+	"golang.org/x/sys/unix"
+	"gvisor.dev/gvisor/pkg/tcpip/link/fdbased"
+	"gvisor.dev/gvisor/pkg/tcpip/link/tun"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// openDevice opens the TUN device of name, or takes fd if it is positive,
+// which is a TUN device opened by the parent process.
+func openDevice(name string, fd int, mtu uint32) (stack.LinkEndpoint, func() error, error) {
+	if fd > 0 {
+		if err := unix.SetNonblock(fd, true); err != nil {
+			return nil, nil, newError("failed to set fd ", fd, " non-blocking").Base(err)
+		}
+	} else {
+		var err error
+		fd, err = tun.Open(name)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	endpoint, err := fdbased.New(&fdbased.Options{
+		FDs: []int{fd},
+		MTU: mtu,
+	})
+	if err != nil {
+		unix.Close(fd)
+		return nil, nil, newError("failed to create endpoint: ", err)
+	}
+	return endpoint, func() error {
+		return unix.Close(fd)
+	}, nil
+}
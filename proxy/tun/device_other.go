@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package tun
+
+import (
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+func openDevice(string, int, uint32) (stack.LinkEndpoint, func() error, error) {
+	return nil, nil, newError("tun inbound is only supported on Linux")
+}
@@ -0,0 +1,223 @@
+package tun
+
+import (
+	"context"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/common/signal"
+	"github.com/v2fly/v2ray-core/v5/transport"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/nested"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+// pingPort is the port of the UDP destination that ICMP echoes are dispatched
+// to, which outbounds take as ICMP, as in pingproto.
+const pingPort = 7
+
+// pingEndpoint takes ICMP echo requests away from the network stack, which
+// would otherwise answer them by itself.
+type pingEndpoint struct {
+	nested.Endpoint
+
+	server *Server
+	stack  *stack.Stack
+}
+
+func newPingEndpoint(server *Server, ipStack *stack.Stack, child stack.LinkEndpoint) *pingEndpoint {
+	e := &pingEndpoint{
+		server: server,
+		stack:  ipStack,
+	}
+	e.Init(child, e)
+	return e
+}
+
+// DeliverNetworkPacket implements stack.NetworkDispatcher.
+func (e *pingEndpoint) DeliverNetworkPacket(protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) {
+	if e.handleEcho(protocol, pkt) {
+		return
+	}
+	e.Endpoint.DeliverNetworkPacket(protocol, pkt)
+}
+
+func (e *pingEndpoint) handleEcho(protocol tcpip.NetworkProtocolNumber, pkt *stack.PacketBuffer) bool {
+	switch protocol {
+	case header.IPv4ProtocolNumber:
+		view, ok := pkt.Data().PullUp(header.IPv4MinimumSize)
+		if !ok || header.IPv4(view).TransportProtocol() != header.ICMPv4ProtocolNumber {
+			return false
+		}
+		packet := header.IPv4(pkt.Data().AsRange().ToOwnedView())
+		if !packet.IsValid(len(packet)) || packet.More() || packet.FragmentOffset() != 0 {
+			return false
+		}
+		message := header.ICMPv4(packet.Payload())
+		if len(message) < header.ICMPv4MinimumSize || message.Type() != header.ICMPv4Echo {
+			return false
+		}
+		e.server.ping(e.stack, protocol, pingID{packet.SourceAddress(), packet.DestinationAddress()}, message)
+		return true
+	case header.IPv6ProtocolNumber:
+		view, ok := pkt.Data().PullUp(header.IPv6MinimumSize)
+		if !ok || header.IPv6(view).NextHeader() != uint8(header.ICMPv6ProtocolNumber) {
+			return false
+		}
+		packet := header.IPv6(pkt.Data().AsRange().ToOwnedView())
+		if !packet.IsValid(len(packet)) {
+			return false
+		}
+		message := header.ICMPv6(packet.Payload())
+		if len(message) < header.ICMPv6EchoMinimumSize || message.Type() != header.ICMPv6EchoRequest {
+			return false
+		}
+		e.server.ping(e.stack, protocol, pingID{packet.SourceAddress(), packet.DestinationAddress()}, message)
+		return true
+	}
+	return false
+}
+
+type pingID struct {
+	source      tcpip.Address
+	destination tcpip.Address
+}
+
+// pingSession relays the ICMP echoes between two hosts.
+type pingSession struct {
+	link   *transport.Link
+	timer  *signal.ActivityTimer
+	cancel context.CancelFunc
+}
+
+func (p *pingSession) write(message []byte) {
+	b := buf.New()
+	if _, err := b.Write(message); err != nil {
+		b.Release()
+		return
+	}
+	if err := p.link.Writer.WriteMultiBuffer(buf.MultiBuffer{b}); err != nil {
+		newError("failed to write ping request").Base(err).AtDebug().WriteToLog()
+		return
+	}
+	p.timer.Update()
+}
+
+func (p *pingSession) close() {
+	p.cancel()
+	common.Interrupt(p.link.Reader)
+	common.Interrupt(p.link.Writer)
+}
+
+// ping dispatches an ICMP echo request, and writes the replies back to the
+// stack.
+func (s *Server) ping(ipStack *stack.Stack, protocol tcpip.NetworkProtocolNumber, id pingID, message []byte) {
+	s.pingAccess.Lock()
+	p, found := s.pings[id]
+	if !found {
+		var err error
+		p, err = s.newPingSession(ipStack, protocol, id)
+		if err != nil {
+			s.pingAccess.Unlock()
+			newError("failed to dispatch ping to ", id.destination).Base(err).AtDebug().WriteToLog()
+			return
+		}
+		s.pings[id] = p
+	}
+	s.pingAccess.Unlock()
+
+	p.write(message)
+}
+
+func (s *Server) newPingSession(ipStack *stack.Stack, protocol tcpip.NetworkProtocolNumber, id pingID) (*pingSession, error) {
+	source := net.UDPDestination(net.IPAddress([]byte(id.source)), 0)
+	destination := net.UDPDestination(net.IPAddress([]byte(id.destination)), pingPort)
+
+	ctx := s.newContext(source)
+	ctx, cancel := context.WithCancel(ctx)
+	link, err := s.dispatcher.Dispatch(ctx, destination)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	p := &pingSession{
+		link:   link,
+		cancel: cancel,
+	}
+	p.timer = signal.CancelAfterInactivity(ctx, func() {
+		s.pingAccess.Lock()
+		if s.pings[id] == p {
+			delete(s.pings, id)
+		}
+		s.pingAccess.Unlock()
+		p.close()
+	}, s.policy().Timeouts.ConnectionIdle)
+
+	go func() {
+		for {
+			mb, err := link.Reader.ReadMultiBuffer()
+			if err != nil {
+				return
+			}
+			for _, b := range mb {
+				if err := writeReply(ipStack, protocol, id, b.Bytes()); err != nil {
+					newError("failed to write ping reply").Base(err).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+				}
+			}
+			buf.ReleaseMulti(mb)
+			p.timer.Update()
+		}
+	}()
+	return p, nil
+}
+
+// writeReply writes an ICMP message from the destination of id to its source.
+func writeReply(ipStack *stack.Stack, protocol tcpip.NetworkProtocolNumber, id pingID, message []byte) error {
+	route, err := ipStack.FindRoute(defaultNIC, id.destination, id.source, protocol, false)
+	if err != nil {
+		return newError("failed to find route to ", id.source, ": ", err)
+	}
+	defer route.Release()
+
+	data := buffer.NewViewFromBytes(message)
+	var transport tcpip.TransportProtocolNumber
+	switch protocol {
+	case header.IPv4ProtocolNumber:
+		if len(data) < header.ICMPv4MinimumSize {
+			return newError("invalid ICMP message")
+		}
+		transport = header.ICMPv4ProtocolNumber
+		hdr := header.ICMPv4(data)
+		hdr.SetChecksum(0)
+		hdr.SetChecksum(header.ICMPv4Checksum(hdr, 0))
+	default:
+		if len(data) < header.ICMPv6MinimumSize {
+			return newError("invalid ICMP message")
+		}
+		transport = header.ICMPv6ProtocolNumber
+		hdr := header.ICMPv6(data)
+		hdr.SetChecksum(0)
+		hdr.SetChecksum(header.ICMPv6Checksum(header.ICMPv6ChecksumParams{
+			Header: hdr,
+			Src:    id.destination,
+			Dst:    id.source,
+		}))
+	}
+
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{
+		ReserveHeaderBytes: int(route.MaxHeaderLength()),
+		Data:               data.ToVectorisedView(),
+	})
+	defer pkt.DecRef()
+	if err := route.WritePacket(stack.NetworkHeaderParams{
+		Protocol: transport,
+		TTL:      route.DefaultTTL(),
+	}, pkt); err != nil {
+		return newError("failed to write packet: ", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package tun
+
+import (
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/v2fly/v2ray-core/v5/common/errors"
+)
+
+const defaultRouteTable = 2022
+
+// setupRoute brings up the device of name, and routes the destinations in
+// config to it with the ip command. It returns a function that removes the
+// routes.
+func setupRoute(name string, mtu uint32, config *Config) (func() error, error) {
+	if config.Fd > 0 && config.Name == "" {
+		return nil, newError("name of the device is required to set up routes")
+	}
+
+	var undo [][]string
+	removeRoute := func() error {
+		var errs []error
+		for i := len(undo) - 1; i >= 0; i-- {
+			errs = append(errs, runIP(undo[i]...))
+		}
+		undo = nil
+		return errors.Combine(errs...)
+	}
+	apply := func(args []string, undoArgs []string) error {
+		if err := runIP(args...); err != nil {
+			removeRoute()
+			return err
+		}
+		if undoArgs != nil {
+			undo = append(undo, undoArgs)
+		}
+		return nil
+	}
+
+	if err := apply([]string{"link", "set", "dev", name, "mtu", strconv.Itoa(int(mtu)), "up"}, nil); err != nil {
+		return nil, err
+	}
+	for _, address := range config.Address {
+		if _, _, err := net.ParseCIDR(address); err != nil {
+			removeRoute()
+			return nil, newError("invalid address ", address).Base(err)
+		}
+		if err := apply(
+			[]string{"addr", "replace", address, "dev", name},
+			[]string{"addr", "del", address, "dev", name},
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	routes := config.Route
+	table := "main"
+	if config.BypassMark != 0 {
+		routeTable := config.RouteTable
+		if routeTable == 0 {
+			routeTable = defaultRouteTable
+		}
+		table = strconv.Itoa(int(routeTable))
+		if len(routes) == 0 {
+			routes = []string{"0.0.0.0/0", "::/0"}
+		}
+	}
+
+	families := make(map[string]bool)
+	for _, route := range routes {
+		_, ipNet, err := net.ParseCIDR(route)
+		if err != nil {
+			removeRoute()
+			return nil, newError("invalid route ", route).Base(err)
+		}
+		family := familyOf(ipNet)
+		families[family] = true
+		if err := apply(
+			[]string{family, "route", "replace", route, "dev", name, "table", table},
+			[]string{family, "route", "del", route, "dev", name, "table", table},
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.BypassMark != 0 {
+		mark := strconv.FormatUint(uint64(config.BypassMark), 10)
+		for _, family := range []string{"-4", "-6"} {
+			if !families[family] {
+				continue
+			}
+			rule := []string{family, "rule", "add", "not", "fwmark", mark, "table", table}
+			if err := apply(rule, append([]string{family, "rule", "del"}, rule[3:]...)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return removeRoute, nil
+}
+
+func familyOf(ipNet *net.IPNet) string {
+	if ipNet.IP.To4() != nil {
+		return "-4"
+	}
+	return "-6"
+}
+
+func runIP(args ...string) error {
+	output, err := exec.Command("ip", args...).CombinedOutput()
+	if err != nil {
+		return newError("failed to run ip ", strings.Join(args, " "), ": ", strings.TrimSpace(string(output))).Base(err)
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+// +build !linux
+
+package tun
+
+func setupRoute(string, uint32, *Config) (func() error, error) {
+	return nil, newError("auto route is only supported on Linux")
+}
@@ -0,0 +1,177 @@
+package tun
+
+import (
+	"context"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/log"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/common/signal"
+	"github.com/v2fly/v2ray-core/v5/common/task"
+	"github.com/v2fly/v2ray-core/v5/features/policy"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+const (
+	defaultNIC tcpip.NICID = 1
+
+	// maxInFlight is the most TCP connections in handshake at the same time.
+	maxInFlight = 1024
+)
+
+// newStack creates a network stack that terminates every TCP and UDP flow
+// from endpoint, as if it were the destination of them.
+func (s *Server) newStack(endpoint stack.LinkEndpoint) (*stack.Stack, error) {
+	ipStack := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{tcp.NewProtocol, udp.NewProtocol},
+	})
+
+	nic := newPingEndpoint(s, ipStack, endpoint)
+	if err := ipStack.CreateNIC(defaultNIC, nic); err != nil {
+		return nil, newError("failed to create NIC: ", err)
+	}
+	// The stack stands for every host behind the device, so it accepts
+	// packets to any address, and sends packets from any address.
+	if err := ipStack.SetPromiscuousMode(defaultNIC, true); err != nil {
+		return nil, newError("failed to set promiscuous mode: ", err)
+	}
+	if err := ipStack.SetSpoofing(defaultNIC, true); err != nil {
+		return nil, newError("failed to set spoofing: ", err)
+	}
+	ipStack.SetRouteTable([]tcpip.Route{
+		{Destination: header.IPv4EmptySubnet, NIC: defaultNIC},
+		{Destination: header.IPv6EmptySubnet, NIC: defaultNIC},
+	})
+	sack := tcpip.TCPSACKEnabled(true)
+	if err := ipStack.SetTransportProtocolOption(tcp.ProtocolNumber, &sack); err != nil {
+		return nil, newError("failed to enable SACK: ", err)
+	}
+
+	tcpForwarder := tcp.NewForwarder(ipStack, 0, maxInFlight, s.handleTCP)
+	ipStack.SetTransportProtocolHandler(tcp.ProtocolNumber, tcpForwarder.HandlePacket)
+	udpForwarder := udp.NewForwarder(ipStack, func(r *udp.ForwarderRequest) {
+		s.handleUDP(ipStack, r)
+	})
+	ipStack.SetTransportProtocolHandler(udp.ProtocolNumber, udpForwarder.HandlePacket)
+	return ipStack, nil
+}
+
+func endpointDestinations(network net.Network, id stack.TransportEndpointID) (source net.Destination, destination net.Destination) {
+	source = net.Destination{
+		Network: network,
+		Address: net.IPAddress([]byte(id.RemoteAddress)),
+		Port:    net.Port(id.RemotePort),
+	}
+	destination = net.Destination{
+		Network: network,
+		Address: net.IPAddress([]byte(id.LocalAddress)),
+		Port:    net.Port(id.LocalPort),
+	}
+	return
+}
+
+func (s *Server) handleTCP(r *tcp.ForwarderRequest) {
+	source, destination := endpointDestinations(net.Network_TCP, r.ID())
+
+	var wq waiter.Queue
+	ep, err := r.CreateEndpoint(&wq)
+	if err != nil {
+		newError("failed to accept TCP connection from ", source, " to ", destination, ": ", err).AtDebug().WriteToLog()
+		r.Complete(true)
+		return
+	}
+	r.Complete(false)
+
+	conn := gonet.NewTCPConn(&wq, ep)
+	defer conn.Close()
+
+	ctx := s.newContext(source)
+	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+		From:   source,
+		To:     destination,
+		Status: log.AccessAccepted,
+		Reason: "",
+	})
+	ctx = policy.ContextWithBufferPolicy(ctx, s.policy().Buffer)
+	if err := s.dispatcher.DispatchConn(ctx, destination, conn, true); err != nil {
+		newError("connection ends").Base(err).WriteToLog(session.ExportIDToError(ctx))
+	}
+}
+
+func (s *Server) handleUDP(ipStack *stack.Stack, r *udp.ForwarderRequest) {
+	source, destination := endpointDestinations(net.Network_UDP, r.ID())
+
+	var wq waiter.Queue
+	ep, err := r.CreateEndpoint(&wq)
+	if err != nil {
+		newError("failed to accept UDP packets from ", source, " to ", destination, ": ", err).AtDebug().WriteToLog()
+		return
+	}
+	conn := gonet.NewUDPConn(ipStack, &wq, ep)
+
+	go func() {
+		defer conn.Close()
+
+		ctx := s.newContext(source)
+		if err := s.relayPackets(ctx, conn, destination); err != nil {
+			newError("connection ends").Base(err).WriteToLog(session.ExportIDToError(ctx))
+		}
+	}()
+}
+
+// relayPackets dispatches the packets read from conn to destination, and
+// writes the packets back to conn.
+func (s *Server) relayPackets(ctx context.Context, conn net.Conn, destination net.Destination) error {
+	ctx = log.ContextWithAccessMessage(ctx, &log.AccessMessage{
+		From:   conn.RemoteAddr(),
+		To:     destination,
+		Status: log.AccessAccepted,
+		Reason: "",
+	})
+
+	plcy := s.policy()
+	ctx, cancel := context.WithCancel(ctx)
+	timer := signal.CancelAfterInactivity(ctx, cancel, plcy.Timeouts.ConnectionIdle)
+	ctx = policy.ContextWithBufferPolicy(ctx, plcy.Buffer)
+
+	link, err := s.dispatcher.Dispatch(ctx, destination)
+	if err != nil {
+		return newError("failed to dispatch request").Base(err)
+	}
+
+	requestDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.DownlinkOnly)
+
+		if err := buf.Copy(buf.NewPacketReader(conn), link.Writer, buf.UpdateActivity(timer)); err != nil {
+			return newError("failed to transport request").Base(err)
+		}
+		return nil
+	}
+
+	responseDone := func() error {
+		defer timer.SetTimeout(plcy.Timeouts.UplinkOnly)
+
+		if err := buf.Copy(link.Reader, &buf.SequentialWriter{Writer: conn}, buf.UpdateActivity(timer)); err != nil {
+			return newError("failed to transport response").Base(err)
+		}
+		return nil
+	}
+
+	if err := task.Run(ctx, task.OnSuccess(requestDone, task.Close(link.Writer)), responseDone); err != nil {
+		common.Interrupt(link.Reader)
+		common.Interrupt(link.Writer)
+		return newError("connection ends").Base(err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package tun
+
+//go:generate go run github.com/v2fly/v2ray-core/v5/common/errors/errorgen
+
+import (
+	"context"
+	"sync"
+
+	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/features/policy"
+	"github.com/v2fly/v2ray-core/v5/features/routing"
+	"github.com/v2fly/v2ray-core/v5/proxy"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+)
+
+const (
+	defaultName = "tun0"
+	defaultMTU  = 1500
+)
+
+func init() {
+	common.Must(common.RegisterConfig((*Config)(nil), func(ctx context.Context, config interface{}) (interface{}, error) {
+		if c := config.(*Config); c.AutoRoute && len(c.Route) == 0 && c.BypassMark == 0 {
+			return nil, newError("bypass_mark is required to route all destinations to the tun device, or connections of outbounds loop back")
+		}
+		s := new(Server)
+		err := core.RequireFeatures(ctx, func(pm policy.Manager) {
+			s.config = config.(*Config)
+			s.policyManager = pm
+		})
+		return s, err
+	}))
+}
+
+var _ proxy.StandaloneInbound = (*Server)(nil)
+
+// Server is an inbound that reads packets from a TUN device, and dispatches
+// the TCP and UDP connections and ICMP echoes in them to their original
+// destinations.
+type Server struct {
+	config        *Config
+	policyManager policy.Manager
+
+	ctx        context.Context
+	dispatcher routing.Dispatcher
+
+	access      sync.Mutex
+	stack       *stack.Stack
+	closeDevice func() error
+	removeRoute func() error
+
+	pingAccess sync.Mutex
+	pings      map[pingID]*pingSession
+}
+
+// Network implements proxy.Inbound.
+func (s *Server) Network() []net.Network {
+	return nil
+}
+
+// Process implements proxy.Inbound.
+func (s *Server) Process(context.Context, net.Network, internet.Connection, routing.Dispatcher) error {
+	return newError("tun inbound does not accept connections from listeners")
+}
+
+// Serve implements proxy.StandaloneInbound.
+func (s *Server) Serve(ctx context.Context, dispatcher routing.Dispatcher) error {
+	mtu := s.config.Mtu
+	if mtu == 0 {
+		mtu = defaultMTU
+	}
+	name := s.config.Name
+	if name == "" {
+		name = defaultName
+	}
+
+	endpoint, closeDevice, err := openDevice(name, int(s.config.Fd), mtu)
+	if err != nil {
+		return newError("failed to open tun device ", name).Base(err)
+	}
+	if err := s.serve(ctx, dispatcher, endpoint); err != nil {
+		closeDevice()
+		return err
+	}
+	s.closeDevice = closeDevice
+
+	if s.config.AutoRoute {
+		removeRoute, err := setupRoute(name, mtu, s.config)
+		if err != nil {
+			s.Close()
+			return newError("failed to set up routes to ", name).Base(err)
+		}
+		s.removeRoute = removeRoute
+	}
+	newError("tun device ", name, " is up").AtInfo().WriteToLog()
+	return nil
+}
+
+// serve starts the network stack on endpoint.
+func (s *Server) serve(ctx context.Context, dispatcher routing.Dispatcher, endpoint stack.LinkEndpoint) error {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	s.ctx = ctx
+	s.dispatcher = dispatcher
+	s.pings = make(map[pingID]*pingSession)
+	ipStack, err := s.newStack(endpoint)
+	if err != nil {
+		return err
+	}
+	s.stack = ipStack
+	return nil
+}
+
+// Close implements proxy.StandaloneInbound.
+func (s *Server) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	var errs []error
+	if s.removeRoute != nil {
+		errs = append(errs, s.removeRoute())
+		s.removeRoute = nil
+	}
+	if s.stack != nil {
+		// Removing the NIC stops reading packets from the device.
+		s.stack.RemoveNIC(defaultNIC)
+		s.stack.Close()
+		s.stack.Wait()
+		s.stack = nil
+	}
+	if s.closeDevice != nil {
+		errs = append(errs, s.closeDevice())
+		s.closeDevice = nil
+	}
+	s.pingAccess.Lock()
+	for id, ping := range s.pings {
+		ping.close()
+		delete(s.pings, id)
+	}
+	s.pingAccess.Unlock()
+	if err := errors.Combine(errs...); err != nil {
+		return newError("failed to close tun device").Base(err)
+	}
+	return nil
+}
+
+func (s *Server) policy() policy.Session {
+	return s.policyManager.ForLevel(s.config.UserLevel)
+}
+
+// newContext returns the context of a connection from source. It inherits the
+// inbound tag and sniffing settings of the handler.
+func (s *Server) newContext(source net.Destination) context.Context {
+	ctx := session.ContextWithID(s.ctx, session.NewID())
+	inbound := &session.Inbound{
+		Source: source,
+		User: &protocol.MemoryUser{
+			Level: s.config.UserLevel,
+		},
+		CanSpliceCopy: true,
+	}
+	if base := session.InboundFromContext(s.ctx); base != nil {
+		inbound.Tag = base.Tag
+	}
+	ctx = session.ContextWithInbound(ctx, inbound)
+	content := new(session.Content)
+	if base := session.ContentFromContext(s.ctx); base != nil {
+		content.SniffingRequest = base.SniffingRequest
+	}
+	return session.ContextWithContent(ctx, content)
+}
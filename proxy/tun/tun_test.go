@@ -0,0 +1,239 @@
+package tun
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/features/policy"
+	"github.com/v2fly/v2ray-core/v5/transport"
+	"github.com/v2fly/v2ray-core/v5/transport/pipe"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/icmp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/waiter"
+)
+
+// echoDispatcher echoes everything dispatched to it, and reports the
+// destinations.
+type echoDispatcher struct {
+	destinations chan net.Destination
+}
+
+func (*echoDispatcher) Type() interface{} { return nil }
+func (*echoDispatcher) Start() error      { return nil }
+func (*echoDispatcher) Close() error      { return nil }
+
+func (d *echoDispatcher) Dispatch(ctx context.Context, dest net.Destination) (*transport.Link, error) {
+	d.destinations <- dest
+	uplinkReader, uplinkWriter := pipe.New()
+	downlinkReader, downlinkWriter := pipe.New()
+	go func() {
+		for {
+			mb, err := uplinkReader.ReadMultiBuffer()
+			if err != nil {
+				downlinkWriter.Close()
+				return
+			}
+			if dest.Port == pingPort {
+				for _, b := range mb {
+					b.Bytes()[0] = byte(header.ICMPv4EchoReply)
+				}
+			}
+			common.Must(downlinkWriter.WriteMultiBuffer(mb))
+		}
+	}()
+	return &transport.Link{Reader: downlinkReader, Writer: uplinkWriter}, nil
+}
+
+func (d *echoDispatcher) DispatchLink(ctx context.Context, dest net.Destination, link *transport.Link) error {
+	return newError("not implemented")
+}
+
+func (d *echoDispatcher) DispatchConn(ctx context.Context, dest net.Destination, conn net.Conn, wait bool) error {
+	d.destinations <- dest
+	_, err := io.Copy(conn, conn)
+	return err
+}
+
+// forward moves the packets written to from into to, like a TUN device.
+func forward(ctx context.Context, from *channel.Endpoint, to *channel.Endpoint) {
+	for {
+		pkt := from.ReadContext(ctx)
+		if pkt == nil {
+			return
+		}
+		copied := stack.NewPacketBuffer(stack.PacketBufferOptions{
+			Data: buffer.NewVectorisedView(pkt.Size(), pkt.Views()),
+		})
+		to.InjectInbound(pkt.NetworkProtocolNumber, copied)
+		copied.DecRef()
+		pkt.DecRef()
+	}
+}
+
+var clientAddress = tcpip.Address(net.ParseAddress("10.0.0.2").IP())
+
+func newTestServer(t *testing.T) (*stack.Stack, *echoDispatcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	device := channel.New(256, defaultMTU, "")
+	client := channel.New(256, defaultMTU, "")
+
+	dispatcher := &echoDispatcher{destinations: make(chan net.Destination, 4)}
+	server := &Server{
+		config:        &Config{},
+		policyManager: policy.DefaultManager{},
+	}
+	common.Must(server.serve(ctx, dispatcher, device))
+	t.Cleanup(func() { server.Close() })
+
+	clientStack := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{tcp.NewProtocol, udp.NewProtocol, icmp.NewProtocol4},
+	})
+	if err := clientStack.CreateNIC(defaultNIC, client); err != nil {
+		t.Fatal(err)
+	}
+	if err := clientStack.AddProtocolAddress(defaultNIC, tcpip.ProtocolAddress{
+		Protocol:          ipv4.ProtocolNumber,
+		AddressWithPrefix: clientAddress.WithPrefix(),
+	}, stack.AddressProperties{}); err != nil {
+		t.Fatal(err)
+	}
+	clientStack.SetRouteTable([]tcpip.Route{{Destination: header.IPv4EmptySubnet, NIC: defaultNIC}})
+	t.Cleanup(clientStack.Close)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		forward(ctx, device, client)
+	}()
+	go func() {
+		defer wg.Done()
+		forward(ctx, client, device)
+	}()
+	// Cleanups run in reverse, so forwarding stops before the stacks close.
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	return clientStack, dispatcher
+}
+
+func expectDestination(t *testing.T, dispatcher *echoDispatcher, expected net.Destination) {
+	t.Helper()
+	select {
+	case dest := <-dispatcher.destinations:
+		if dest != expected {
+			t.Error("expected destination ", expected, ", but got ", dest)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing dispatched")
+	}
+}
+
+func expectEcho(t *testing.T, conn net.Conn, payload []byte) {
+	t.Helper()
+	common.Must(conn.SetDeadline(time.Now().Add(5 * time.Second)))
+	if _, err := conn.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+	response := make([]byte, len(payload))
+	if _, err := io.ReadFull(conn, response); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(response, payload) {
+		t.Error("unexpected response ", response)
+	}
+}
+
+func TestTCP(t *testing.T) {
+	clientStack, dispatcher := newTestServer(t)
+
+	conn, err := gonet.DialTCP(clientStack, tcpip.FullAddress{
+		NIC:  defaultNIC,
+		Addr: tcpip.Address(net.ParseAddress("1.2.3.4").IP()),
+		Port: 443,
+	}, ipv4.ProtocolNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	expectDestination(t, dispatcher, net.TCPDestination(net.ParseAddress("1.2.3.4"), 443))
+	expectEcho(t, conn, []byte("test payload"))
+}
+
+func TestUDP(t *testing.T) {
+	clientStack, dispatcher := newTestServer(t)
+
+	conn, err := gonet.DialUDP(clientStack, nil, &tcpip.FullAddress{
+		NIC:  defaultNIC,
+		Addr: tcpip.Address(net.ParseAddress("8.8.8.8").IP()),
+		Port: 53,
+	}, ipv4.ProtocolNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	expectEcho(t, conn, []byte("test payload"))
+	expectDestination(t, dispatcher, net.UDPDestination(net.ParseAddress("8.8.8.8"), 53))
+	expectEcho(t, conn, []byte("another payload"))
+}
+
+func TestPing(t *testing.T) {
+	clientStack, dispatcher := newTestServer(t)
+
+	var wq waiter.Queue
+	entry, readable := waiter.NewChannelEntry(waiter.ReadableEvents)
+	wq.EventRegister(&entry)
+	defer wq.EventUnregister(&entry)
+	ep, err := clientStack.NewEndpoint(icmp.ProtocolNumber4, ipv4.ProtocolNumber, &wq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ep.Close()
+	if err := ep.Connect(tcpip.FullAddress{
+		NIC:  defaultNIC,
+		Addr: tcpip.Address(net.ParseAddress("1.1.1.1").IP()),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	request := make(header.ICMPv4, header.ICMPv4MinimumSize)
+	request.SetType(header.ICMPv4Echo)
+	request.SetSequence(1)
+	if _, err := ep.Write(bytes.NewReader(request), tcpip.WriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	expectDestination(t, dispatcher, net.UDPDestination(net.ParseAddress("1.1.1.1"), pingPort))
+
+	select {
+	case <-readable:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no ping reply")
+	}
+	reply := buf.New()
+	defer reply.Release()
+	if _, err := ep.Read(reply, tcpip.ReadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if header.ICMPv4(reply.Bytes()).Type() != header.ICMPv4EchoReply || header.ICMPv4(reply.Bytes()).Sequence() != 1 {
+		t.Error("unexpected reply ", reply.Bytes())
+	}
+}
@@ -0,0 +1,627 @@
+package encoding
+
+import (
+	"bytes"
+	"context"
+	gotls "crypto/tls"
+	"io"
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/dice"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/common/signal"
+	"github.com/v2fly/v2ray-core/v5/features/stats"
+	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+)
+
+// Commands of the Vision padding blocks.
+const (
+	commandPaddingContinue byte = 0x00
+	commandPaddingEnd      byte = 0x01
+	commandPaddingDirect   byte = 0x02
+)
+
+// paddingHeaderSize is the size of the user ID and the block header before
+// the content of a padding block.
+const paddingHeaderSize = 21
+
+// numberOfPacketToFilter is the number of packets inspected for the inner TLS
+// handshake in each direction.
+const numberOfPacketToFilter = 8
+
+var (
+	tls13SupportedVersions  = []byte{0x00, 0x2b, 0x00, 0x02, 0x03, 0x04}
+	tlsClientHandShakeStart = []byte{0x16, 0x03}
+	tlsServerHandShakeStart = []byte{0x16, 0x03, 0x03}
+	tlsApplicationDataStart = []byte{0x17, 0x03, 0x03}
+)
+
+const (
+	tlsHandshakeTypeClientHello byte = 0x01
+	tlsHandshakeTypeServerHello byte = 0x02
+
+	// TLS_AES_128_CCM_8_SHA256 records are not distinguishable enough from
+	// the outer TLS to be copied directly.
+	tlsAES128CCM8SHA256 uint16 = 0x1305
+)
+
+// TrafficState is the state of a Vision connection, shared by both
+// directions.
+type TrafficState struct {
+	userUUID []byte
+
+	// Inner TLS detection, updated by both directions.
+	access                 sync.Mutex
+	numberOfPacketToFilter int
+	enableXtls             bool
+	isTLS12orAbove         bool
+	isTLS                  bool
+	cipher                 uint16
+	remainingServerHello   int32
+
+	// Reader state.
+	withinPaddingBuffers     bool
+	readerSwitchToDirectCopy bool
+	remainingCommand         int32
+	remainingContent         int32
+	remainingPadding         int32
+	currentCommand           int
+
+	// Writer state.
+	isPadding                bool
+	writerSwitchToDirectCopy bool
+}
+
+// NewTrafficState creates the state of a Vision connection of the user.
+func NewTrafficState(userUUID []byte) *TrafficState {
+	return &TrafficState{
+		userUUID:               userUUID,
+		numberOfPacketToFilter: numberOfPacketToFilter,
+		remainingServerHello:   -1,
+		withinPaddingBuffers:   true,
+		remainingCommand:       -1,
+		remainingContent:       -1,
+		remainingPadding:       -1,
+		isPadding:              true,
+	}
+}
+
+// filtering returns whether the inner TLS handshake is still inspected.
+func (s *TrafficState) filtering() bool {
+	s.access.Lock()
+	defer s.access.Unlock()
+	return s.numberOfPacketToFilter > 0
+}
+
+// filterTLS inspects the packets for the inner TLS handshake, and decides
+// whether the inner traffic can be copied directly.
+func (s *TrafficState) filterTLS(ctx context.Context, mb buf.MultiBuffer) {
+	s.access.Lock()
+	defer s.access.Unlock()
+
+	for _, b := range mb {
+		if b == nil || s.numberOfPacketToFilter <= 0 {
+			continue
+		}
+		s.numberOfPacketToFilter--
+		if b.Len() >= 6 {
+			start := b.BytesTo(6)
+			if bytes.Equal(tlsServerHandShakeStart, start[:3]) && start[5] == tlsHandshakeTypeServerHello {
+				s.remainingServerHello = (int32(start[3])<<8 | int32(start[4])) + 5
+				s.isTLS12orAbove = true
+				s.isTLS = true
+				if b.Len() >= 79 && s.remainingServerHello >= 79 {
+					sessionIDLen := int32(b.Byte(43))
+					cipherSuite := b.BytesRange(43+sessionIDLen+1, 43+sessionIDLen+3)
+					s.cipher = uint16(cipherSuite[0])<<8 | uint16(cipherSuite[1])
+				} else {
+					newError("short inner server hello, TLS 1.2 or older? ", b.Len(), " ", s.remainingServerHello).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+				}
+			} else if bytes.Equal(tlsClientHandShakeStart, start[:2]) && start[5] == tlsHandshakeTypeClientHello {
+				s.isTLS = true
+				newError("found inner TLS client hello ", b.Len()).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+			}
+		}
+		if s.remainingServerHello > 0 {
+			end := s.remainingServerHello
+			if end > b.Len() {
+				end = b.Len()
+			}
+			s.remainingServerHello -= b.Len()
+			if bytes.Contains(b.BytesTo(end), tls13SupportedVersions) {
+				if s.cipher != tlsAES128CCM8SHA256 {
+					s.enableXtls = true
+				}
+				newError("found inner TLS 1.3 with cipher suite ", s.cipher).AtDebug().WriteToLog(session.ExportIDToError(ctx))
+				s.numberOfPacketToFilter = 0
+				return
+			} else if s.remainingServerHello <= 0 {
+				newError("found inner TLS 1.2").AtDebug().WriteToLog(session.ExportIDToError(ctx))
+				s.numberOfPacketToFilter = 0
+				return
+			}
+		}
+	}
+}
+
+// VisionReader removes the padding of a Vision connection.
+type VisionReader struct {
+	buf.Reader
+	state *TrafficState
+	conn  *tls.Conn
+	ctx   context.Context
+}
+
+// NewVisionReader creates a VisionReader reading from reader, which reads
+// from conn. The data left in conn is taken over when the peer switches to
+// direct copy.
+func NewVisionReader(reader buf.Reader, state *TrafficState, conn *tls.Conn, ctx context.Context) *VisionReader {
+	return &VisionReader{
+		Reader: reader,
+		state:  state,
+		conn:   conn,
+		ctx:    ctx,
+	}
+}
+
+// ReadMultiBuffer implements buf.Reader.
+func (r *VisionReader) ReadMultiBuffer() (buf.MultiBuffer, error) {
+	mb, err := r.Reader.ReadMultiBuffer()
+	if mb.IsEmpty() {
+		return mb, err
+	}
+	s := r.state
+	if s.withinPaddingBuffers || s.filtering() {
+		unpadded := make(buf.MultiBuffer, 0, len(mb))
+		for _, b := range mb {
+			nb := s.unpad(b)
+			if nb.IsEmpty() {
+				nb.Release()
+				continue
+			}
+			unpadded = append(unpadded, nb)
+		}
+		mb = unpadded
+
+		switch {
+		case s.remainingContent > 0 || s.remainingPadding > 0 || s.currentCommand == int(commandPaddingContinue):
+			s.withinPaddingBuffers = true
+		case s.currentCommand == int(commandPaddingEnd):
+			s.withinPaddingBuffers = false
+		case s.currentCommand == int(commandPaddingDirect):
+			s.withinPaddingBuffers = false
+			s.readerSwitchToDirectCopy = true
+			mb = r.takeOverConn(mb)
+		default:
+			newError("unknown padding command ", s.currentCommand).AtWarning().WriteToLog(session.ExportIDToError(r.ctx))
+		}
+	}
+	if s.filtering() {
+		s.filterTLS(r.ctx, mb)
+	}
+	return mb, err
+}
+
+// takeOverConn appends the data buffered in the TLS connection, which is
+// inner traffic sent directly by the peer, to mb.
+func (r *VisionReader) takeOverConn(mb buf.MultiBuffer) buf.MultiBuffer {
+	if r.conn == nil {
+		return mb
+	}
+	input, rawInput := tlsConnBuffers(r.conn)
+	if inputBuffer, err := buf.ReadFrom(input); err == nil && !inputBuffer.IsEmpty() {
+		mb, _ = buf.MergeMulti(mb, inputBuffer)
+	}
+	if rawInputBuffer, err := buf.ReadFrom(rawInput); err == nil && !rawInputBuffer.IsEmpty() {
+		mb, _ = buf.MergeMulti(mb, rawInputBuffer)
+	}
+	return mb
+}
+
+// Offsets of the buffers of crypto/tls.Conn that Vision reads directly.
+var tlsConnInputOffset, tlsConnRawInputOffset, errTLSConnLayout = tlsConnLayout()
+
+// tlsConnLayout finds the buffers in crypto/tls.Conn, and fails if they are
+// not the fields Vision expects.
+func tlsConnLayout() (uintptr, uintptr, error) {
+	t := reflect.TypeOf(gotls.Conn{})
+	input, found := t.FieldByName("input")
+	if !found || input.Type != reflect.TypeOf(bytes.Reader{}) {
+		return 0, 0, newError("unsupported crypto/tls: Conn.input is not a bytes.Reader")
+	}
+	rawInput, found := t.FieldByName("rawInput")
+	if !found || rawInput.Type != reflect.TypeOf(bytes.Buffer{}) {
+		return 0, 0, newError("unsupported crypto/tls: Conn.rawInput is not a bytes.Buffer")
+	}
+	return input.Offset, rawInput.Offset, nil
+}
+
+// tlsConnBuffers returns the decrypted data and the raw records that the TLS
+// connection has read but not returned. VisionConn makes sure that the
+// layout of crypto/tls.Conn is known.
+func tlsConnBuffers(conn *tls.Conn) (*bytes.Reader, *bytes.Buffer) {
+	p := unsafe.Pointer(conn.Conn)
+	return (*bytes.Reader)(unsafe.Add(p, tlsConnInputOffset)), (*bytes.Buffer)(unsafe.Add(p, tlsConnRawInputOffset))
+}
+
+// unpad removes the padding from b, whose blocks may span multiple buffers.
+func (s *TrafficState) unpad(b *buf.Buffer) *buf.Buffer {
+	if s.remainingCommand == -1 && s.remainingContent == -1 && s.remainingPadding == -1 {
+		// A padding block starts with the user ID, or the data is not padded.
+		if b.Len() >= paddingHeaderSize && bytes.Equal(s.userUUID, b.BytesTo(16)) {
+			b.Advance(16)
+			s.remainingCommand = 5
+		} else {
+			return b
+		}
+	}
+
+	nb := buf.New()
+	for b.Len() > 0 {
+		switch {
+		case s.remainingCommand > 0:
+			data, _ := b.ReadByte()
+			switch s.remainingCommand {
+			case 5:
+				s.currentCommand = int(data)
+			case 4:
+				s.remainingContent = int32(data) << 8
+			case 3:
+				s.remainingContent |= int32(data)
+			case 2:
+				s.remainingPadding = int32(data) << 8
+			case 1:
+				s.remainingPadding |= int32(data)
+			}
+			s.remainingCommand--
+		case s.remainingContent > 0:
+			n := s.remainingContent
+			if b.Len() < n {
+				n = b.Len()
+			}
+			data, _ := b.ReadBytes(n)
+			nb.Write(data)
+			s.remainingContent -= n
+		default:
+			n := s.remainingPadding
+			if b.Len() < n {
+				n = b.Len()
+			}
+			b.Advance(n)
+			s.remainingPadding -= n
+		}
+		if s.remainingCommand <= 0 && s.remainingContent <= 0 && s.remainingPadding <= 0 {
+			if s.currentCommand == int(commandPaddingContinue) {
+				s.remainingCommand = 5
+			} else {
+				s.remainingCommand = -1
+				s.remainingContent = -1
+				s.remainingPadding = -1
+				if b.Len() > 0 {
+					nb.Write(b.Bytes())
+				}
+				break
+			}
+		}
+	}
+	b.Release()
+	return nb
+}
+
+// VisionWriter pads the data written to a Vision connection, until the
+// inner TLS handshake completes.
+type VisionWriter struct {
+	buf.Writer
+	state             *TrafficState
+	ctx               context.Context
+	writeOnceUserUUID []byte
+}
+
+// NewVisionWriter creates a VisionWriter writing to writer.
+func NewVisionWriter(writer buf.Writer, state *TrafficState, ctx context.Context) *VisionWriter {
+	return &VisionWriter{
+		Writer:            writer,
+		state:             state,
+		ctx:               ctx,
+		writeOnceUserUUID: append([]byte(nil), state.userUUID...),
+	}
+}
+
+// WriteMultiBuffer implements buf.Writer. A MultiBuffer of a single nil
+// buffer writes a padding block of no content.
+func (w *VisionWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	s := w.state
+	if s.filtering() {
+		s.filterTLS(w.ctx, mb)
+	}
+	if !s.isPadding {
+		return w.Writer.WriteMultiBuffer(mb)
+	}
+	if len(mb) == 1 && mb[0] == nil {
+		// A long padding hides the length of the request header.
+		mb[0] = w.pad(nil, commandPaddingContinue, true)
+		return w.Writer.WriteMultiBuffer(mb)
+	}
+
+	s.access.Lock()
+	isTLS, isTLS12orAbove, enableXtls, remainingFilter := s.isTLS, s.isTLS12orAbove, s.enableXtls, s.numberOfPacketToFilter
+	s.access.Unlock()
+
+	mb = reshapeMultiBuffer(mb)
+	longPadding := isTLS
+	for i, b := range mb {
+		if isTLS && b.Len() >= 6 && bytes.Equal(tlsApplicationDataStart, b.BytesTo(3)) {
+			// The inner TLS handshake completes, so pads no more.
+			if enableXtls {
+				s.writerSwitchToDirectCopy = true
+			}
+			command := commandPaddingContinue
+			if i == len(mb)-1 {
+				command = commandPaddingEnd
+				if enableXtls {
+					command = commandPaddingDirect
+				}
+			}
+			mb[i] = w.pad(b, command, true)
+			s.isPadding = false
+			longPadding = false
+			continue
+		} else if !isTLS12orAbove && remainingFilter <= 1 {
+			// The inner traffic is not TLS 1.2 or above, and the end of
+			// padding is sent a packet before the filter stops, as the
+			// earlier receivers expect.
+			s.isPadding = false
+			mb[i] = w.pad(b, commandPaddingEnd, longPadding)
+			break
+		}
+		command := commandPaddingContinue
+		if i == len(mb)-1 && !s.isPadding {
+			command = commandPaddingEnd
+			if enableXtls {
+				command = commandPaddingDirect
+			}
+		}
+		mb[i] = w.pad(b, command, longPadding)
+	}
+	return w.Writer.WriteMultiBuffer(mb)
+}
+
+// pad encodes b as a padding block. The first block carries the user ID.
+func (w *VisionWriter) pad(b *buf.Buffer, command byte, longPadding bool) *buf.Buffer {
+	var contentLen int32
+	if b != nil {
+		contentLen = b.Len()
+	}
+	var paddingLen int32
+	if contentLen < 900 && longPadding {
+		paddingLen = int32(dice.Roll(500)) + 900 - contentLen
+	} else {
+		paddingLen = int32(dice.Roll(256))
+	}
+	if paddingLen > buf.Size-paddingHeaderSize-contentLen {
+		paddingLen = buf.Size - paddingHeaderSize - contentLen
+	}
+
+	nb := buf.New()
+	if w.writeOnceUserUUID != nil {
+		nb.Write(w.writeOnceUserUUID)
+		w.writeOnceUserUUID = nil
+	}
+	nb.Write([]byte{command, byte(contentLen >> 8), byte(contentLen), byte(paddingLen >> 8), byte(paddingLen)})
+	if b != nil {
+		nb.Write(b.Bytes())
+		b.Release()
+	}
+	padding := nb.Extend(paddingLen)
+	for i := range padding {
+		padding[i] = 0
+	}
+	return nb
+}
+
+// reshapeMultiBuffer splits the buffers too large to be padded.
+func reshapeMultiBuffer(mb buf.MultiBuffer) buf.MultiBuffer {
+	needReshape := 0
+	for _, b := range mb {
+		if b.Len() >= buf.Size-paddingHeaderSize {
+			needReshape++
+		}
+	}
+	if needReshape == 0 {
+		return mb
+	}
+	reshaped := make(buf.MultiBuffer, 0, len(mb)+needReshape)
+	for _, b := range mb {
+		if b.Len() < buf.Size-paddingHeaderSize {
+			reshaped = append(reshaped, b)
+			continue
+		}
+		// Splits at the start of the last TLS record if possible.
+		index := int32(bytes.LastIndex(b.Bytes(), tlsApplicationDataStart))
+		if index < paddingHeaderSize || index > buf.Size-paddingHeaderSize {
+			index = buf.Size / 2
+		}
+		rest := buf.New()
+		rest.Write(b.BytesFrom(index))
+		b.Resize(0, index)
+		reshaped = append(reshaped, b, rest)
+	}
+	return reshaped
+}
+
+// VisionConn returns the TLS connection beneath conn that Vision works on.
+func VisionConn(conn net.Conn) (*tls.Conn, error) {
+	if statConn, ok := conn.(*internet.StatCounterConn); ok {
+		conn = statConn.Connection
+	}
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil, newError(`Vision requires "security" to be "tls"`)
+	}
+	if errTLSConnLayout != nil {
+		return nil, errTLSConnLayout
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, newError("failed to complete TLS handshake").Base(err)
+	}
+	if version := tlsConn.ConnectionState().Version; version != gotls.VersionTLS13 {
+		return nil, newError("Vision requires TLS 1.3, but found outer TLS version ", version)
+	}
+	return tlsConn, nil
+}
+
+// VisionConnReader reads conn, whose TLS connection is tlsConn, for a
+// VisionReader. Once tls.Conn.Read returns the last byte of a record, it
+// looks at the next record for an alert. After the peer switches to direct
+// copy, that is raw data, which fails the TLS connection and sends an alert
+// to the peer. So the decrypted data is read from the TLS connection
+// directly, and tls.Conn.Read is only used to read one byte of a new record.
+func VisionConnReader(conn net.Conn, tlsConn *tls.Conn) io.Reader {
+	input, _ := tlsConnBuffers(tlsConn)
+	r := &visionConnReader{
+		Conn:  conn,
+		input: input,
+	}
+	if statConn, ok := conn.(*internet.StatCounterConn); ok {
+		r.counter = statConn.ReadCounter
+	}
+	return r
+}
+
+type visionConnReader struct {
+	net.Conn
+	input   *bytes.Reader
+	counter stats.Counter
+}
+
+func (r *visionConnReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	var n int
+	if r.input.Len() == 0 {
+		var err error
+		n, err = r.Conn.Read(b[:1])
+		if err != nil || r.input.Len() == 0 {
+			return n, err
+		}
+	}
+	m, _ := r.input.Read(b[n:])
+	if r.counter != nil {
+		r.counter.Add(int64(m))
+	}
+	return n + m, nil
+}
+
+// XtlsRead copies from reader to writer, and copies from the raw connection
+// beneath conn once the peer switches to direct copy. The raw connection is
+// spliced into the inbound connection of sctx if both are TCP on Linux, the
+// inbound relays its connection as is, and writer writes to it directly.
+func XtlsRead(reader buf.Reader, writer buf.Writer, timer signal.ActivityUpdater, conn *tls.Conn, state *TrafficState, counter stats.Counter, sctx context.Context) error {
+	err := func() error {
+		var ct stats.Counter
+		switched := false
+		for {
+			if state.readerSwitchToDirectCopy && !switched {
+				switched = true
+				if tc := spliceTarget(sctx); tc != nil && writesThrough(writer, session.InboundFromContext(sctx).Conn) {
+					newError("Vision splice").AtDebug().WriteToLog(session.ExportIDToError(sctx))
+					w, err := tc.ReadFrom(conn.NetConn())
+					if counter != nil {
+						counter.Add(w)
+					}
+					if statConn, ok := session.InboundFromContext(sctx).Conn.(*internet.StatCounterConn); ok && statConn.WriteCounter != nil {
+						statConn.WriteCounter.Add(w)
+					}
+					return err
+				}
+				reader = buf.NewReader(conn.NetConn())
+				ct = counter
+			}
+			mb, err := reader.ReadMultiBuffer()
+			if !mb.IsEmpty() {
+				if ct != nil {
+					ct.Add(int64(mb.Len()))
+				}
+				timer.Update()
+				if werr := writer.WriteMultiBuffer(mb); werr != nil {
+					return werr
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}()
+	if err != nil && errors.Cause(err) != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// writesThrough reports whether writer writes to the inbound connection
+// synchronously, so that all data written to it has reached the connection
+// when splicing starts. Data taken by a pipe may still be on its way to the
+// connection, so it is not spliced then.
+func writesThrough(writer buf.Writer, inboundConn net.Conn) bool {
+	w, ok := writer.(*buf.BufferToBytesWriter)
+	return ok && w.Writer == inboundConn
+}
+
+// spliceTarget returns the inbound TCP connection of ctx on Linux, if the
+// inbound relays it as is. Others, like the connections of encrypted
+// protocols, must not get raw data.
+func spliceTarget(ctx context.Context) *net.TCPConn {
+	if ctx == nil || (runtime.GOOS != "linux" && runtime.GOOS != "android") {
+		return nil
+	}
+	inbound := session.InboundFromContext(ctx)
+	if inbound == nil || inbound.Conn == nil || !inbound.CanSpliceCopy {
+		return nil
+	}
+	iConn := inbound.Conn
+	if statConn, ok := iConn.(*internet.StatCounterConn); ok {
+		iConn = statConn.Connection
+	}
+	tc, _ := iConn.(*net.TCPConn)
+	return tc
+}
+
+// XtlsWrite copies from reader to writer, and writes to the raw connection
+// beneath conn once writer switches to direct copy.
+func XtlsWrite(reader buf.Reader, writer buf.Writer, timer signal.ActivityUpdater, conn *tls.Conn, state *TrafficState, counter stats.Counter) error {
+	err := func() error {
+		var ct stats.Counter
+		for {
+			mb, err := reader.ReadMultiBuffer()
+			if state.writerSwitchToDirectCopy {
+				state.writerSwitchToDirectCopy = false
+				writer = buf.NewWriter(conn.NetConn())
+				ct = counter
+			}
+			if !mb.IsEmpty() {
+				if ct != nil {
+					ct.Add(int64(mb.Len()))
+				}
+				timer.Update()
+				if werr := writer.WriteMultiBuffer(mb); werr != nil {
+					return werr
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}()
+	if err != nil && errors.Cause(err) != io.EOF {
+		return err
+	}
+	return nil
+}
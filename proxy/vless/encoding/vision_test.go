@@ -0,0 +1,214 @@
+package encoding
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/buf"
+	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/session"
+	"github.com/v2fly/v2ray-core/v5/common/uuid"
+	"github.com/v2fly/v2ray-core/v5/transport/pipe"
+)
+
+// recordWriter keeps everything written to it.
+type recordWriter struct {
+	mb buf.MultiBuffer
+}
+
+func (w *recordWriter) WriteMultiBuffer(mb buf.MultiBuffer) error {
+	w.mb = append(w.mb, mb...)
+	return nil
+}
+
+func newBuffer(data []byte) *buf.Buffer {
+	b := buf.New()
+	common.Must2(b.Write(data))
+	return b
+}
+
+// serverHello13 is the start of a TLS 1.3 server hello with the
+// TLS_AES_128_GCM_SHA256 cipher suite.
+func serverHello13() []byte {
+	hello := make([]byte, 100)
+	copy(hello, []byte{0x16, 0x03, 0x03, 0x00, 95, tlsHandshakeTypeServerHello})
+	hello[43] = 0 // session ID length
+	hello[44], hello[45] = 0x13, 0x01
+	copy(hello[60:], tls13SupportedVersions)
+	return hello
+}
+
+func readAll(t *testing.T, reader *VisionReader) []byte {
+	t.Helper()
+	var data []byte
+	for {
+		mb, err := reader.ReadMultiBuffer()
+		for _, b := range mb {
+			if b != nil {
+				data = append(data, b.Bytes()...)
+			}
+		}
+		buf.ReleaseMulti(mb)
+		if err != nil {
+			return data
+		}
+	}
+}
+
+func TestVisionPadding(t *testing.T) {
+	id := uuid.New()
+	recorder := &recordWriter{}
+	writer := NewVisionWriter(recorder, NewTrafficState(id.Bytes()), context.Background())
+
+	common.Must(writer.WriteMultiBuffer(make(buf.MultiBuffer, 1)))
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{newBuffer([]byte("first"))}))
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{newBuffer([]byte("second"))}))
+
+	if len(recorder.mb) == 0 || !bytes.Equal(recorder.mb[0].BytesTo(16), id.Bytes()) {
+		t.Fatal("the first block does not start with the user ID")
+	}
+	if recorder.mb[0].Len() < 900 {
+		t.Error("expected long padding for the header, but got ", recorder.mb[0].Len())
+	}
+	for _, b := range recorder.mb[1:] {
+		if bytes.Equal(b.BytesTo(16), id.Bytes()) {
+			t.Error("the user ID is sent more than once")
+		}
+	}
+
+	// Splits the padded data at boundaries other than the blocks.
+	var padded []byte
+	for _, b := range recorder.mb {
+		padded = append(padded, b.Bytes()...)
+	}
+	var mb buf.MultiBuffer
+	for len(padded) > 0 {
+		n := 300
+		if n > len(padded) {
+			n = len(padded)
+		}
+		mb = append(mb, newBuffer(padded[:n]))
+		padded = padded[n:]
+	}
+
+	reader := NewVisionReader(&buf.BufferedReader{Reader: buf.NewReader(bytes.NewReader(nil)), Buffer: mb}, NewTrafficState(id.Bytes()), nil, context.Background())
+	if data := readAll(t, reader); string(data) != "firstsecond" {
+		t.Error("unexpected data ", string(data))
+	}
+}
+
+func TestVisionDirectCopy(t *testing.T) {
+	id := uuid.New()
+	state := NewTrafficState(id.Bytes())
+	recorder := &recordWriter{}
+	writer := NewVisionWriter(recorder, state, context.Background())
+
+	// The inner TLS client hello, as the client would send.
+	clientHello := make([]byte, 64)
+	copy(clientHello, []byte{0x16, 0x03, 0x01, 0x00, 59, tlsHandshakeTypeClientHello})
+	state.filterTLS(context.Background(), buf.MultiBuffer{newBuffer(clientHello)})
+
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{newBuffer(serverHello13())}))
+	if !state.enableXtls {
+		t.Fatal("inner TLS 1.3 not detected")
+	}
+	if !state.isPadding {
+		t.Fatal("padding ends before the inner handshake")
+	}
+
+	applicationData := []byte{0x17, 0x03, 0x03, 0x00, 0x01, 0xff}
+	common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{newBuffer(applicationData)}))
+	if state.isPadding || !state.writerSwitchToDirectCopy {
+		t.Fatal("expected switching to direct copy")
+	}
+
+	reader := NewVisionReader(&buf.BufferedReader{Reader: buf.NewReader(bytes.NewReader(nil)), Buffer: recorder.mb}, NewTrafficState(id.Bytes()), nil, context.Background())
+	data := readAll(t, reader)
+	if !bytes.Equal(data, append(serverHello13(), applicationData...)) {
+		t.Error("unexpected data ", data)
+	}
+	if !reader.state.readerSwitchToDirectCopy {
+		t.Error("expected the reader to switch to direct copy")
+	}
+}
+
+func TestVisionNotTLS(t *testing.T) {
+	id := uuid.New()
+	state := NewTrafficState(id.Bytes())
+	recorder := &recordWriter{}
+	writer := NewVisionWriter(recorder, state, context.Background())
+
+	for i := 0; i < numberOfPacketToFilter; i++ {
+		common.Must(writer.WriteMultiBuffer(buf.MultiBuffer{newBuffer([]byte("plain"))}))
+	}
+	if state.isPadding {
+		t.Error("expected padding to end for non-TLS traffic")
+	}
+	if state.writerSwitchToDirectCopy {
+		t.Error("non-TLS traffic must not be copied directly")
+	}
+}
+
+func TestReshapeMultiBuffer(t *testing.T) {
+	b := buf.New()
+	b.Extend(buf.Size)
+	mb := reshapeMultiBuffer(buf.MultiBuffer{b, newBuffer([]byte("small"))})
+	if len(mb) != 3 {
+		t.Fatal("expected 3 buffers, but got ", len(mb))
+	}
+	for _, b := range mb {
+		if b.Len() >= buf.Size-paddingHeaderSize {
+			t.Error("buffer too large to pad: ", b.Len())
+		}
+	}
+	if mb.Len() != buf.Size+5 {
+		t.Error("unexpected length ", mb.Len())
+	}
+}
+
+func TestSpliceTarget(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer listener.Close()
+	conn, err := net.DialTCP("tcp", nil, listener.Addr().(*net.TCPAddr))
+	common.Must(err)
+	defer conn.Close()
+
+	inbound := &session.Inbound{Conn: conn}
+	ctx := session.ContextWithInbound(context.Background(), inbound)
+	if tc := spliceTarget(ctx); tc != nil {
+		t.Error("connection of an inbound that does not relay it as is must not be spliced")
+	}
+
+	inbound.CanSpliceCopy = true
+	canSplice := runtime.GOOS == "linux" || runtime.GOOS == "android"
+	if tc := spliceTarget(ctx); (tc == conn) != canSplice {
+		t.Error("unexpected splice target ", tc)
+	}
+}
+
+func TestWritesThrough(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{IP: net.LocalHostIP.IP()})
+	common.Must(err)
+	defer listener.Close()
+	conn, err := net.DialTCP("tcp", nil, listener.Addr().(*net.TCPAddr))
+	common.Must(err)
+	defer conn.Close()
+
+	if !writesThrough(buf.NewWriter(conn), conn) {
+		t.Error("direct writer to the inbound connection must be spliceable")
+	}
+	_, pipeWriter := pipe.New()
+	if writesThrough(pipeWriter, conn) {
+		t.Error("pipe writer must not be spliceable")
+	}
+}
+
+func TestTLSConnLayout(t *testing.T) {
+	if errTLSConnLayout != nil {
+		t.Fatal(errTLSConnLayout)
+	}
+}
@@ -17,16 +17,19 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/log"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/authprovider"
 	"github.com/v2fly/v2ray-core/v5/common/retry"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/common/signal"
 	"github.com/v2fly/v2ray-core/v5/common/task"
+	"github.com/v2fly/v2ray-core/v5/common/uuid"
 	"github.com/v2fly/v2ray-core/v5/features/dns"
 	feature_inbound "github.com/v2fly/v2ray-core/v5/features/inbound"
 	"github.com/v2fly/v2ray-core/v5/features/policy"
 	"github.com/v2fly/v2ray-core/v5/features/routing"
 	"github.com/v2fly/v2ray-core/v5/features/stats"
+	"github.com/v2fly/v2ray-core/v5/proxy"
 	"github.com/v2fly/v2ray-core/v5/proxy/vless"
 	"github.com/v2fly/v2ray-core/v5/proxy/vless/encoding"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
@@ -72,6 +75,7 @@ type Handler struct {
 	inboundHandlerManager feature_inbound.Manager
 	policyManager         policy.Manager
 	validator             *vless.Validator
+	resolver              *authprovider.Resolver
 	dns                   dns.Client
 	fallbacks             map[string]map[string]map[string]*Fallback // or nil
 	// regexps               map[string]*regexp.Regexp       // or nil
@@ -97,6 +101,17 @@ func New(ctx context.Context, config *Config, dc dns.Client) (*Handler, error) {
 		}
 	}
 
+	if config.AuthProvider != nil {
+		resolver, err := authprovider.NewResolver(config.AuthProvider, toMemoryUser)
+		if err != nil {
+			return nil, newError("failed to create auth provider").Base(err).AtError()
+		}
+		handler.resolver = resolver
+		handler.validator.SetResolver(func(id uuid.UUID) *protocol.MemoryUser {
+			return resolver.Resolve(id.String())
+		})
+	}
+
 	if config.Fallbacks != nil {
 		handler.fallbacks = make(map[string]map[string]map[string]*Fallback)
 		// handler.regexps = make(map[string]*regexp.Regexp)
@@ -160,9 +175,28 @@ func New(ctx context.Context, config *Config, dc dns.Client) (*Handler, error) {
 	return handler, nil
 }
 
+func toMemoryUser(user *authprovider.User, key string) (*protocol.MemoryUser, error) {
+	account, err := (&vless.Account{Id: user.ID, Flow: user.Flow}).AsAccount()
+	if err != nil {
+		return nil, err
+	}
+	if account.(*vless.MemoryAccount).ID.String() != key {
+		return nil, newError("id does not match ", key)
+	}
+	return &protocol.MemoryUser{
+		Account: account,
+		Email:   user.Email,
+		Level:   user.Level,
+	}, nil
+}
+
 // Close implements common.Closable.Close().
 func (h *Handler) Close() error {
-	return errors.Combine(common.Close(h.validator))
+	errs := []error{common.Close(h.validator)}
+	if h.resolver != nil {
+		errs = append(errs, h.resolver.Close())
+	}
+	return errors.Combine(errs...)
 }
 
 // AddUser implements proxy.UserManager.AddUser().
@@ -243,6 +277,7 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 			}
 			name = strings.ToLower(name)
 			alpn = strings.ToLower(alpn)
+			realName, realAlpn := name, alpn
 
 			if len(napfb) > 1 || napfb[""] == nil {
 				if name != "" && napfb[name] == nil {
@@ -321,6 +356,11 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 				return newError(`failed to find the default "path" config`).AtWarning()
 			}
 
+			// Hands the connection over to another inbound, without a loopback port.
+			if fb.Type == "inbound" {
+				return proxy.FallbackToInbound(ctx, h.inboundHandlerManager, fb.Dest, reader, connection)
+			}
+
 			ctx, cancel := context.WithCancel(ctx)
 			timer := signal.CancelAfterInactivity(ctx, cancel, sessionPolicy.Timeouts.ConnectionIdle)
 			ctx = policy.ContextWithBufferPolicy(ctx, sessionPolicy.Buffer)
@@ -380,18 +420,22 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 							pro.Write([]byte("\x20\x00\x00\x00")) // v2 + LOCAL + UNSPEC + UNSPEC + 0 bytes
 							break
 						}
+						tlvs := proxy.ProxyProtocolTLVs(realName, realAlpn)
 						if ipType == 4 {
-							pro.Write([]byte("\x21\x11\x00\x0C")) // v2 + PROXY + AF_INET + STREAM + 12 bytes
+							l := 12 + len(tlvs)
+							pro.Write([]byte{0x21, 0x11, byte(l >> 8), byte(l)}) // v2 + PROXY + AF_INET + STREAM + 12 bytes and TLVs
 							pro.Write(net.ParseIP(remoteAddr).To4())
 							pro.Write(net.ParseIP(localAddr).To4())
 						} else {
-							pro.Write([]byte("\x21\x21\x00\x24")) // v2 + PROXY + AF_INET6 + STREAM + 36 bytes
+							l := 36 + len(tlvs)
+							pro.Write([]byte{0x21, 0x21, byte(l >> 8), byte(l)}) // v2 + PROXY + AF_INET6 + STREAM + 36 bytes and TLVs
 							pro.Write(net.ParseIP(remoteAddr).To16())
 							pro.Write(net.ParseIP(localAddr).To16())
 						}
 						p1, _ := strconv.ParseUint(remotePort, 10, 16)
 						p2, _ := strconv.ParseUint(localPort, 10, 16)
 						pro.Write([]byte{byte(p1 >> 8), byte(p1), byte(p2 >> 8), byte(p2)})
+						pro.Write(tlvs)
 					}
 					if err := serverWriter.WriteMultiBuffer(buf.MultiBuffer{pro}); err != nil {
 						return newError("failed to set PROXY protocol v", fb.Xver).Base(err).AtWarning()
@@ -451,8 +495,26 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 	}
 
 	var rawConn syscall.RawConn
+	var visionConn *tls.Conn
+	var trafficState *encoding.TrafficState
 
 	switch requestAddons.Flow {
+	case vless.XRV:
+		if account.Flow != requestAddons.Flow {
+			return newError(account.ID.String() + " is not able to use " + requestAddons.Flow).AtWarning()
+		}
+		switch request.Command {
+		case protocol.RequestCommandUDP:
+			return newError(requestAddons.Flow + " doesn't support UDP").AtWarning()
+		case protocol.RequestCommandMux, protocol.RequestCommandTCP:
+			tlsConn, err := encoding.VisionConn(connection)
+			if err != nil {
+				return newError("failed to use " + requestAddons.Flow).Base(err).AtWarning()
+			}
+			visionConn = tlsConn
+			trafficState = encoding.NewTrafficState(account.ID.Bytes())
+			reader.Reader = buf.NewReader(encoding.VisionConnReader(connection, visionConn))
+		}
 	case vless.XRO, vless.XRD:
 		if account.Flow == requestAddons.Flow {
 			switch request.Command {
@@ -479,6 +541,10 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 			return newError(account.ID.String() + " is not able to use " + requestAddons.Flow).AtWarning()
 		}
 	case "":
+		if account.Flow == vless.XRV && request.Command == protocol.RequestCommandTCP {
+			// TLS in TLS without Vision is easy to tell.
+			return newError(account.ID.String() + " is not able to use plain TLS, but " + account.Flow).AtWarning()
+		}
 	default:
 		return newError("unknown request flow " + requestAddons.Flow).AtWarning()
 	}
@@ -520,6 +586,13 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 				counter = statConn.ReadCounter
 			}
 			err = encoding.ReadV(clientReader, serverWriter, timer, iConn.(*xtls.Conn), rawConn, counter, nil)
+		} else if trafficState != nil {
+			var counter stats.Counter
+			if statConn != nil {
+				counter = statConn.ReadCounter
+			}
+			clientReader = encoding.NewVisionReader(clientReader, trafficState, visionConn, ctx)
+			err = encoding.XtlsRead(clientReader, serverWriter, timer, visionConn, trafficState, counter, nil)
 		} else {
 			// from clientReader.ReadMultiBuffer to serverWriter.WriteMultiBufer
 			err = buf.Copy(clientReader, serverWriter, buf.UpdateActivity(timer))
@@ -542,6 +615,9 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 
 		// default: clientWriter := bufferWriter
 		clientWriter := encoding.EncodeBodyAddons(bufferWriter, request, responseAddons)
+		if trafficState != nil {
+			clientWriter = encoding.NewVisionWriter(clientWriter, trafficState, ctx)
+		}
 		{
 			multiBuffer, err := serverReader.ReadMultiBuffer()
 			if err != nil {
@@ -557,8 +633,18 @@ func (h *Handler) Process(ctx context.Context, network net.Network, connection i
 			return newError("failed to write A response payload").Base(err).AtWarning()
 		}
 
-		// from serverReader.ReadMultiBuffer to clientWriter.WriteMultiBufer
-		if err := buf.Copy(serverReader, clientWriter, buf.UpdateActivity(timer)); err != nil {
+		var err error
+		if trafficState != nil {
+			var counter stats.Counter
+			if statConn != nil {
+				counter = statConn.WriteCounter
+			}
+			err = encoding.XtlsWrite(serverReader, clientWriter, timer, visionConn, trafficState, counter)
+		} else {
+			// from serverReader.ReadMultiBuffer to clientWriter.WriteMultiBufer
+			err = buf.Copy(serverReader, clientWriter, buf.UpdateActivity(timer))
+		}
+		if err != nil {
 			return newError("failed to transfer response payload").Base(err).AtInfo()
 		}
 
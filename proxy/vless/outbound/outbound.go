@@ -4,6 +4,7 @@ package outbound
 
 import (
 	"context"
+	"io"
 	"syscall"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/proxy/vless/encoding"
 	"github.com/v2fly/v2ray-core/v5/transport"
 	"github.com/v2fly/v2ray-core/v5/transport/internet"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
 	"github.com/v2fly/v2ray-core/v5/transport/internet/xtls"
 )
 
@@ -149,9 +151,33 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 
 	var rawConn syscall.RawConn
 	var sctx context.Context
+	var visionConn *tls.Conn
+	var trafficState *encoding.TrafficState
 
 	allowUDP443 := false
 	switch requestAddons.Flow {
+	case vless.XRV + "-udp443":
+		allowUDP443 = true
+		requestAddons.Flow = vless.XRV
+		fallthrough
+	case vless.XRV:
+		switch request.Command {
+		case protocol.RequestCommandUDP:
+			if !allowUDP443 && request.Port == 443 {
+				return newError(requestAddons.Flow + " stopped UDP/443").AtInfo()
+			}
+			requestAddons.Flow = ""
+		case protocol.RequestCommandMux, protocol.RequestCommandTCP:
+			tlsConn, err := encoding.VisionConn(conn)
+			if err != nil {
+				return newError("failed to use " + requestAddons.Flow).Base(err).AtWarning()
+			}
+			visionConn = tlsConn
+			trafficState = encoding.NewTrafficState(account.ID.Bytes())
+			if request.Command == protocol.RequestCommandTCP {
+				sctx = ctx
+			}
+		}
 	case vless.XRO + "-udp443", vless.XRD + "-udp443", vless.XRS + "-udp443":
 		allowUDP443 = true
 		requestAddons.Flow = requestAddons.Flow[:16]
@@ -221,6 +247,9 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 
 		// default: serverWriter := bufferWriter
 		serverWriter := encoding.EncodeBodyAddons(bufferWriter, request, requestAddons)
+		if trafficState != nil {
+			serverWriter = encoding.NewVisionWriter(serverWriter, trafficState, ctx)
+		}
 		switch packetEncoding {
 		case packetaddr.PacketAddrType_Packet:
 			serverWriter = packetaddr.NewPacketWriter(serverWriter, target)
@@ -228,7 +257,12 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 			serverWriter = xudp.NewPacketWriter(serverWriter, target)
 		}
 
-		if err := buf.CopyOnceTimeout(clientReader, serverWriter, time.Millisecond*100); err != nil && err != buf.ErrNotTimeoutReader && err != buf.ErrReadTimeout {
+		if err := buf.CopyOnceTimeout(clientReader, serverWriter, time.Millisecond*100); err == buf.ErrReadTimeout && trafficState != nil {
+			// Pads the request header alone, rather than sending it as is.
+			if err := serverWriter.WriteMultiBuffer(make(buf.MultiBuffer, 1)); err != nil {
+				return err
+			}
+		} else if err != nil && err != buf.ErrNotTimeoutReader && err != buf.ErrReadTimeout {
 			return err // ...
 		}
 
@@ -237,8 +271,18 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 			return newError("failed to write A request payload").Base(err).AtWarning()
 		}
 
-		// from clientReader.ReadMultiBuffer to serverWriter.WriteMultiBufer
-		if err := buf.Copy(clientReader, serverWriter, buf.UpdateActivity(timer)); err != nil {
+		var err error
+		if trafficState != nil {
+			var counter stats.Counter
+			if statConn != nil {
+				counter = statConn.WriteCounter
+			}
+			err = encoding.XtlsWrite(clientReader, serverWriter, timer, visionConn, trafficState, counter)
+		} else {
+			// from clientReader.ReadMultiBuffer to serverWriter.WriteMultiBufer
+			err = buf.Copy(clientReader, serverWriter, buf.UpdateActivity(timer))
+		}
+		if err != nil {
 			return newError("failed to transfer request payload").Base(err).AtInfo()
 		}
 
@@ -257,8 +301,16 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 			return newError("failed to decode response header").Base(err).AtInfo()
 		}
 
+		var bodyReader io.Reader = conn
+		if trafficState != nil {
+			bodyReader = encoding.VisionConnReader(conn, visionConn)
+		}
+
 		// default: serverReader := buf.NewReader(conn)
-		serverReader := encoding.DecodeBodyAddons(conn, request, responseAddons)
+		serverReader := encoding.DecodeBodyAddons(bodyReader, request, responseAddons)
+		if trafficState != nil {
+			serverReader = encoding.NewVisionReader(serverReader, trafficState, visionConn, ctx)
+		}
 		switch packetEncoding {
 		case packetaddr.PacketAddrType_Packet:
 			serverReader = packetaddr.NewPacketReader(serverReader)
@@ -272,6 +324,12 @@ func (h *Handler) Process(ctx context.Context, link *transport.Link, dialer inte
 				counter = statConn.ReadCounter
 			}
 			err = encoding.ReadV(serverReader, clientWriter, timer, iConn.(*xtls.Conn), rawConn, counter, sctx)
+		} else if trafficState != nil {
+			var counter stats.Counter
+			if statConn != nil {
+				counter = statConn.ReadCounter
+			}
+			err = encoding.XtlsRead(serverReader, clientWriter, timer, visionConn, trafficState, counter, sctx)
 		} else {
 			// from serverReader.ReadMultiBuffer to clientWriter.WriteMultiBufer
 			err = buf.Copy(serverReader, clientWriter, buf.UpdateActivity(timer))
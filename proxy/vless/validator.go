@@ -13,6 +13,14 @@ type Validator struct {
 	// Considering email's usage here, map + sync.Mutex/RWMutex may have better performance.
 	email sync.Map
 	users sync.Map
+
+	resolve func(id uuid.UUID) *protocol.MemoryUser
+}
+
+// SetResolver sets a function that Get falls back to for users that have not
+// been added. It must be called before the validator is in use.
+func (v *Validator) SetResolver(resolve func(id uuid.UUID) *protocol.MemoryUser) {
+	v.resolve = resolve
 }
 
 // Add a VLESS user, Email must be empty or unique.
@@ -48,5 +56,8 @@ func (v *Validator) Get(id uuid.UUID) *protocol.MemoryUser {
 	if u != nil {
 		return u.(*protocol.MemoryUser)
 	}
+	if v.resolve != nil {
+		return v.resolve(id)
+	}
 	return nil
 }
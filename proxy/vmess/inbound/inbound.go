@@ -17,6 +17,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/platform"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
+	"github.com/v2fly/v2ray-core/v5/common/protocol/authprovider"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
 	"github.com/v2fly/v2ray-core/v5/common/session"
 	"github.com/v2fly/v2ray-core/v5/common/signal"
@@ -105,6 +106,7 @@ type Handler struct {
 	policyManager         policy.Manager
 	inboundHandlerManager feature_inbound.Manager
 	clients               *vmess.TimedUserValidator
+	syncer                *authprovider.Syncer
 	usersByEmail          *userByEmail
 	detours               *DetourConfig
 	sessionHistory        *encoding.SessionHistory
@@ -135,11 +137,39 @@ func New(ctx context.Context, config *Config) (*Handler, error) {
 		}
 	}
 
+	if config.AuthProvider != nil {
+		// AEAD auth IDs can only be matched against keys in memory, so users
+		// are synced from the provider instead of resolved on demand.
+		syncer, err := authprovider.NewSyncer(config.AuthProvider, toMemoryUser, handler.clients.Add, func(u *protocol.MemoryUser) {
+			handler.clients.RemoveUser(u)
+		})
+		if err != nil {
+			handler.Close()
+			return nil, newError("failed to create auth provider").Base(err)
+		}
+		handler.syncer = syncer
+	}
+
 	return handler, nil
 }
 
+func toMemoryUser(user *authprovider.User, _ string) (*protocol.MemoryUser, error) {
+	account, err := (&vmess.Account{Id: user.ID}).AsAccount()
+	if err != nil {
+		return nil, err
+	}
+	return &protocol.MemoryUser{
+		Account: account,
+		Email:   user.Email,
+		Level:   user.Level,
+	}, nil
+}
+
 // Close implements common.Closable.
 func (h *Handler) Close() error {
+	if h.syncer != nil {
+		h.syncer.Close()
+	}
 	return errors.Combine(
 		h.clients.Close(),
 		h.sessionHistory.Close(),
@@ -188,13 +188,26 @@ func (v *TimedUserValidator) GetAEAD(userHash []byte) (*protocol.MemoryUser, boo
 }
 
 func (v *TimedUserValidator) Remove(email string) bool {
+	email = strings.ToLower(email)
+	return v.remove(func(u *user) bool {
+		return strings.EqualFold(u.user.Email, email)
+	})
+}
+
+// RemoveUser removes the user whose account equals the one of the given user.
+func (v *TimedUserValidator) RemoveUser(u *protocol.MemoryUser) bool {
+	return v.remove(func(uu *user) bool {
+		return uu.user.Account.Equals(u.Account)
+	})
+}
+
+func (v *TimedUserValidator) remove(match func(*user) bool) bool {
 	v.Lock()
 	defer v.Unlock()
 
-	email = strings.ToLower(email)
 	idx := -1
 	for i, u := range v.users {
-		if strings.EqualFold(u.user.Email, email) {
+		if match(u) {
 			idx = i
 			var cmdkeyfl [16]byte
 			copy(cmdkeyfl[:], u.user.Account.(*MemoryAccount).ID.CmdKey())
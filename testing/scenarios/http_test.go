@@ -6,7 +6,9 @@ import (
 	"crypto/rand"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,11 +18,15 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common"
 	"github.com/v2fly/v2ray-core/v5/common/buf"
 	"github.com/v2fly/v2ray-core/v5/common/net"
+	"github.com/v2fly/v2ray-core/v5/common/protocol"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
+	"github.com/v2fly/v2ray-core/v5/proxy/dokodemo"
 	"github.com/v2fly/v2ray-core/v5/proxy/freedom"
 	v2http "github.com/v2fly/v2ray-core/v5/proxy/http"
 	v2httptest "github.com/v2fly/v2ray-core/v5/testing/servers/http"
 	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
+	"github.com/v2fly/v2ray-core/v5/transport/internet/tls"
+	"golang.org/x/sync/errgroup"
 )
 
 func TestHttpConformance(t *testing.T) {
@@ -374,3 +380,118 @@ func TestHttpBasicAuth(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPSUpstreamH2(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	var newConns, tunnels int32
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.ProtoMajor != 2 {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("X-Proxy-Token") != "secret" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		conn, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer conn.Close()
+		atomic.AddInt32(&tunnels, 1)
+
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		go func() {
+			io.Copy(conn, r.Body)
+			conn.Close()
+		}()
+		b := make([]byte, buf.Size)
+		for {
+			n, err := conn.Read(b)
+			if n > 0 {
+				w.Write(b[:n])
+				w.(http.Flusher).Flush()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}))
+	upstream.EnableHTTP2 = true
+	upstream.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&newConns, 1)
+		}
+	}
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	upstreamAddr := upstream.Listener.Addr().(*net.TCPAddr)
+	clientPort := tcp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(clientPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(dest.Address),
+					Port:    uint32(dest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_TCP},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&v2http.ClientConfig{
+					ServerEntry: []*v2http.ServerEntry{
+						{
+							Endpoint: &protocol.ServerEndpoint{
+								Address: net.NewIPOrDomain(net.LocalHostIP),
+								Port:    uint32(upstreamAddr.Port),
+							},
+							Headers: map[string]string{
+								"X-Proxy-Token": "secret",
+							},
+							TlsSettings: &tls.Config{
+								AllowInsecure: true,
+							},
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(clientConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	// The first tunnel sets up the HTTP/2 connection the others share.
+	common.Must(testTCPConn(clientPort, 10240, time.Second*20)())
+	var errg errgroup.Group
+	for i := 0; i < 4; i++ {
+		errg.Go(testTCPConn(clientPort, 10240, time.Second*20))
+	}
+	if err := errg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := atomic.LoadInt32(&tunnels); n != 5 {
+		t.Error("expected 5 tunnels, got ", n)
+	}
+	if n := atomic.LoadInt32(&newConns); n != 1 {
+		t.Error("expected 1 connection to upstream, got ", n)
+	}
+}
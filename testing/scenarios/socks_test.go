@@ -1,13 +1,21 @@
 package scenarios
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	core "github.com/v2fly/v2ray-core/v5"
+	"github.com/v2fly/v2ray-core/v5/app/commander"
+	"github.com/v2fly/v2ray-core/v5/app/policy"
 	"github.com/v2fly/v2ray-core/v5/app/proxyman"
 	"github.com/v2fly/v2ray-core/v5/app/router"
+	"github.com/v2fly/v2ray-core/v5/app/stats"
+	statscmd "github.com/v2fly/v2ray-core/v5/app/stats/command"
 	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 	"github.com/v2fly/v2ray-core/v5/common/serial"
@@ -18,6 +26,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
 	"github.com/v2fly/v2ray-core/v5/testing/servers/udp"
 	xproxy "golang.org/x/net/proxy"
+	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/anypb"
 	socks4 "h12.io/socks"
 )
@@ -369,3 +378,263 @@ func TestSocksConformanceMod(t *testing.T) {
 		}
 	}
 }
+
+func TestSocksUDPAssociation(t *testing.T) {
+	udpServer := udp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := udpServer.Start()
+	common.Must(err)
+	defer udpServer.Close()
+
+	serverPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(serverPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&socks.ServerConfig{
+					AuthType: socks.AuthType_PASSWORD,
+					Users: []*protocol.User{
+						{
+							Email: "test@v2fly.org",
+							Level: 1,
+							Account: serial.ToTypedMessage(&socks.Account{
+								Username: "Test Account",
+								Password: "Test Password",
+							}),
+						},
+					},
+					Address:    net.NewIPOrDomain(net.LocalHostIP),
+					UdpEnabled: true,
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	udpConn, err := net.DialUDP("udp", nil, &net.UDPAddr{
+		IP:   []byte{127, 0, 0, 1},
+		Port: int(serverPort),
+	})
+	common.Must(err)
+	defer udpConn.Close()
+
+	request := &protocol.RequestHeader{
+		Version: 5,
+		Command: protocol.RequestCommandUDP,
+		Address: dest.Address,
+		Port:    dest.Port,
+	}
+	roundTrip := func() error {
+		payload := []byte("udp association")
+		packet, err := socks.EncodeUDPPacket(request, payload)
+		common.Must(err)
+		defer packet.Release()
+		if _, err := udpConn.Write(packet.Bytes()); err != nil {
+			return err
+		}
+		response := make([]byte, 1024)
+		udpConn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := socks.NewUDPReader(udpConn).ReadFrom(response)
+		if err != nil {
+			return err
+		}
+		if r := cmp.Diff(response[:n], xor(payload)); r != "" {
+			return errors.New(r)
+		}
+		return nil
+	}
+
+	if err := roundTrip(); err == nil {
+		t.Error("UDP packets without association must be rejected")
+	}
+
+	tcpConn, err := net.DialTCP("tcp", nil, &net.TCPAddr{
+		IP:   []byte{127, 0, 0, 1},
+		Port: int(serverPort),
+	})
+	common.Must(err)
+	_, err = socks.ClientHandshake(&protocol.RequestHeader{
+		Version: 5,
+		Command: protocol.RequestCommandUDP,
+		Address: net.AnyIP,
+		Port:    0,
+		User: &protocol.MemoryUser{
+			Account: &socks.Account{
+				Username: "Test Account",
+				Password: "Test Password",
+			},
+		},
+	}, tcpConn, tcpConn)
+	common.Must(err)
+
+	if err := roundTrip(); err != nil {
+		t.Error(err)
+	}
+
+	tcpConn.Close()
+	time.Sleep(500 * time.Millisecond)
+	if err := roundTrip(); err == nil {
+		t.Error("UDP packets after the control connection closed must be rejected")
+	}
+}
+
+func TestSocksUserStats(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	serverPort := tcp.PickPort()
+	cmdPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		App: []*anypb.Any{
+			serial.ToTypedMessage(&stats.Config{}),
+			serial.ToTypedMessage(&commander.Config{
+				Tag: "api",
+				Service: []*anypb.Any{
+					serial.ToTypedMessage(&statscmd.Config{}),
+				},
+			}),
+			serial.ToTypedMessage(&router.Config{
+				Rule: []*router.RoutingRule{
+					{
+						InboundTag: []string{"api"},
+						TargetTag: &router.RoutingRule_Tag{
+							Tag: "api",
+						},
+					},
+				},
+			}),
+			serial.ToTypedMessage(&policy.Config{
+				Level: map[uint32]*policy.Policy{
+					1: {
+						Stats: &policy.Policy_Stats{
+							UserUplink:   true,
+							UserDownlink: true,
+						},
+					},
+				},
+			}),
+		},
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(serverPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&socks.ServerConfig{
+					AuthType: socks.AuthType_PASSWORD,
+					Users: []*protocol.User{
+						{
+							Email: "test@v2fly.org",
+							Level: 1,
+							Account: serial.ToTypedMessage(&socks.Account{
+								Username: "Test Account",
+								Password: "Test Password",
+							}),
+						},
+					},
+					Address: net.NewIPOrDomain(net.LocalHostIP),
+				}),
+			},
+			{
+				Tag: "api",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(cmdPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(dest.Address),
+					Port:    uint32(dest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_TCP},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	clientPort := tcp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(clientPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(dest.Address),
+					Port:    uint32(dest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_TCP},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&socks.ClientConfig{
+					Server: []*protocol.ServerEndpoint{
+						{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(serverPort),
+							User: []*protocol.User{
+								{
+									Account: serial.ToTypedMessage(&socks.Account{
+										Username: "Test Account",
+										Password: "Test Password",
+									}),
+								},
+							},
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	if err := testTCPConn(clientPort, 10240, time.Second*5)(); err != nil {
+		t.Fatal(err)
+	}
+
+	cmdConn, err := grpc.Dial(fmt.Sprintf("127.0.0.1:%d", cmdPort), grpc.WithInsecure(), grpc.WithBlock())
+	common.Must(err)
+	defer cmdConn.Close()
+
+	sClient := statscmd.NewStatsServiceClient(cmdConn)
+	for _, name := range []string{
+		"user>>>test@v2fly.org>>>traffic>>>uplink",
+		"user>>>test@v2fly.org>>>traffic>>>downlink",
+	} {
+		sresp, err := sClient.GetStats(context.Background(), &statscmd.GetStatsRequest{Name: name})
+		if err != nil {
+			t.Fatal(name, ": ", err)
+		}
+		if sresp.Stat.Value != 10240 {
+			t.Error("unexpected ", name, ": ", sresp.Stat.Value)
+		}
+	}
+}
@@ -1,14 +1,24 @@
 package scenarios
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	gotls "crypto/tls"
 	"crypto/x509"
+	"io"
+	gonet "net"
 	"runtime"
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/pires/go-proxyproto"
 	core "github.com/v2fly/v2ray-core/v5"
 	"github.com/v2fly/v2ray-core/v5/app/proxyman"
+	"github.com/v2fly/v2ray-core/v5/app/router/routercommon"
 	"github.com/v2fly/v2ray-core/v5/common"
+	"github.com/v2fly/v2ray-core/v5/common/errors"
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/common/protocol"
 	"github.com/v2fly/v2ray-core/v5/common/protocol/tls/cert"
@@ -16,6 +26,9 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/uuid"
 	"github.com/v2fly/v2ray-core/v5/proxy/dokodemo"
 	"github.com/v2fly/v2ray-core/v5/proxy/freedom"
+	"github.com/v2fly/v2ray-core/v5/proxy/vless"
+	vlessinbound "github.com/v2fly/v2ray-core/v5/proxy/vless/inbound"
+	vlessoutbound "github.com/v2fly/v2ray-core/v5/proxy/vless/outbound"
 	"github.com/v2fly/v2ray-core/v5/proxy/vmess"
 	"github.com/v2fly/v2ray-core/v5/proxy/vmess/inbound"
 	"github.com/v2fly/v2ray-core/v5/proxy/vmess/outbound"
@@ -692,3 +705,307 @@ func TestSimpleTLSConnectionPinned(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestVLessVision(t *testing.T) {
+	// The inner TLS 1.3 server, whose traffic Vision copies directly.
+	serverCert, serverKey := cert.MustGenerate(nil).ToPEM()
+	keyPair, err := gotls.X509KeyPair(serverCert, serverKey)
+	common.Must(err)
+	listener, err := gotls.Listen("tcp", "127.0.0.1:0", &gotls.Config{
+		Certificates: []gotls.Certificate{keyPair},
+		MinVersion:   gotls.VersionTLS13,
+	})
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	dest := net.DestinationFromAddr(listener.Addr())
+
+	userID := protocol.NewID(uuid.New())
+	serverPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(serverPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+					StreamSettings: &internet.StreamConfig{
+						SecurityType: serial.GetMessageType(&tls.Config{}),
+						SecuritySettings: []*anypb.Any{
+							serial.ToTypedMessage(&tls.Config{
+								Certificate: []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil))},
+							}),
+						},
+					},
+				}),
+				ProxySettings: serial.ToTypedMessage(&vlessinbound.Config{
+					Clients: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vless.Account{
+								Id:   userID.String(),
+								Flow: vless.XRV,
+							}),
+						},
+					},
+					Decryption: "none",
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	clientPort := tcp.PickPort()
+	clientConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(clientPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(dest.Address),
+					Port:    uint32(dest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_TCP},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&vlessoutbound.Config{
+					Vnext: []*protocol.ServerEndpoint{
+						{
+							Address: net.NewIPOrDomain(net.LocalHostIP),
+							Port:    uint32(serverPort),
+							User: []*protocol.User{
+								{
+									Account: serial.ToTypedMessage(&vless.Account{
+										Id:         userID.String(),
+										Flow:       vless.XRV,
+										Encryption: "none",
+									}),
+								},
+							},
+						},
+					},
+				}),
+				SenderSettings: serial.ToTypedMessage(&proxyman.SenderConfig{
+					StreamSettings: &internet.StreamConfig{
+						SecurityType: serial.GetMessageType(&tls.Config{}),
+						SecuritySettings: []*anypb.Any{
+							serial.ToTypedMessage(&tls.Config{
+								AllowInsecure: true,
+							}),
+						},
+					},
+				}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig, clientConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	var errg errgroup.Group
+	for i := 0; i < 4; i++ {
+		errg.Go(func() error {
+			rawConn, err := net.DialTCP("tcp", nil, &net.TCPAddr{
+				IP:   []byte{127, 0, 0, 1},
+				Port: int(clientPort),
+			})
+			if err != nil {
+				return err
+			}
+			conn := gotls.Client(rawConn, &gotls.Config{
+				InsecureSkipVerify: true,
+				MinVersion:         gotls.VersionTLS13,
+			})
+			defer conn.Close()
+
+			// Rounds after the handshake are copied directly.
+			for round := 0; round < 3; round++ {
+				if err := conn.SetDeadline(time.Now().Add(time.Second * 20)); err != nil {
+					return err
+				}
+				payload := make([]byte, 64*1024)
+				common.Must2(rand.Read(payload))
+				if _, err := conn.Write(payload); err != nil {
+					return err
+				}
+				response := make([]byte, len(payload))
+				if _, err := io.ReadFull(conn, response); err != nil {
+					return err
+				}
+				if !bytes.Equal(response, payload) {
+					return errors.New("unexpected response in round ", round)
+				}
+			}
+			return nil
+		})
+	}
+	if err := errg.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVLessFallback(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: xor,
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	request := []byte("GET / HTTP/1.1\r\nHost: www.example.com\r\n\r\n")
+
+	// The web server behind PROXY protocol v2, which replies with the TLVs.
+	listener, err := gonet.Listen("tcp", "127.0.0.1:0")
+	common.Must(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				header, err := proxyproto.Read(reader)
+				if err != nil {
+					return
+				}
+				tlvs, err := header.TLVs()
+				if err != nil {
+					return
+				}
+				if _, err := io.ReadFull(reader, make([]byte, len(request))); err != nil {
+					return
+				}
+				for _, tlv := range tlvs {
+					conn.Write(tlv.Value)
+					conn.Write([]byte{'\n'})
+				}
+			}()
+		}
+	}()
+
+	userID := protocol.NewID(uuid.New())
+	serverPort := tcp.PickPort()
+	serverConfig := &core.Config{
+		Inbound: []*core.InboundHandlerConfig{
+			{
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(serverPort),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+					StreamSettings: &internet.StreamConfig{
+						SecurityType: serial.GetMessageType(&tls.Config{}),
+						SecuritySettings: []*anypb.Any{
+							serial.ToTypedMessage(&tls.Config{
+								Certificate:  []*tls.Certificate{tls.ParseCertificate(cert.MustGenerate(nil))},
+								NextProtocol: []string{"http/1.1"},
+							}),
+						},
+					},
+				}),
+				ProxySettings: serial.ToTypedMessage(&vlessinbound.Config{
+					Clients: []*protocol.User{
+						{
+							Account: serial.ToTypedMessage(&vless.Account{
+								Id: userID.String(),
+							}),
+						},
+					},
+					Decryption: "none",
+					Fallbacks: []*vlessinbound.Fallback{
+						{
+							Type: "inbound",
+							Dest: "fallback",
+						},
+						{
+							Name: "proxy.example.com",
+							Type: "tcp",
+							Dest: listener.Addr().String(),
+							Xver: 2,
+						},
+					},
+				}),
+			},
+			{
+				Tag: "fallback",
+				ReceiverSettings: serial.ToTypedMessage(&proxyman.ReceiverConfig{
+					PortRange: net.SinglePortRange(tcp.PickPort()),
+					Listen:    net.NewIPOrDomain(net.LocalHostIP),
+					// Fallback connections are checked by the inbound that
+					// accepted them, not again by this one.
+					AccessControl: &proxyman.AccessControlConfig{
+						Deny: []*routercommon.CIDR{{Ip: net.LocalHostIP.IP(), Prefix: 8}},
+					},
+				}),
+				ProxySettings: serial.ToTypedMessage(&dokodemo.Config{
+					Address: net.NewIPOrDomain(dest.Address),
+					Port:    uint32(dest.Port),
+					NetworkList: &net.NetworkList{
+						Network: []net.Network{net.Network_TCP},
+					},
+				}),
+			},
+		},
+		Outbound: []*core.OutboundHandlerConfig{
+			{
+				ProxySettings: serial.ToTypedMessage(&freedom.Config{}),
+			},
+		},
+	}
+
+	servers, err := InitializeServerConfigs(serverConfig)
+	common.Must(err)
+	defer CloseAllServers(servers)
+
+	dial := func(serverName string) *gotls.Conn {
+		conn, err := gotls.Dial("tcp", net.LocalHostIP.String()+":"+serverPort.String(), &gotls.Config{
+			ServerName:         serverName,
+			NextProtos:         []string{"http/1.1"},
+			InsecureSkipVerify: true,
+		})
+		common.Must(err)
+		common.Must(conn.SetDeadline(time.Now().Add(time.Second * 20)))
+		return conn
+	}
+
+	// Falls back to another inbound in process.
+	conn := dial("www.example.com")
+	common.Must2(conn.Write(request))
+	response := make([]byte, len(request))
+	common.Must2(io.ReadFull(conn, response))
+	if r := cmp.Diff(response, xor(request)); r != "" {
+		t.Error(r)
+	}
+	conn.Close()
+
+	// Falls back by SNI, with the SNI and ALPN in the PROXY protocol header.
+	conn = dial("proxy.example.com")
+	common.Must2(conn.Write(request))
+	response, err = io.ReadAll(conn)
+	common.Must(err)
+	if r := cmp.Diff(string(response), "http/1.1\nproxy.example.com\n"); r != "" {
+		t.Error(r)
+	}
+	conn.Close()
+}
@@ -0,0 +1,22 @@
+//go:build go1.21
+// +build go1.21
+
+package internet
+
+import (
+	"net"
+)
+
+// enableMultipathTCP makes dialer and lc use Multipath TCP if sockopt asks
+// for it. The system falls back to TCP if Multipath TCP is not available.
+func enableMultipathTCP(dialer *net.Dialer, lc *net.ListenConfig, sockopt *SocketConfig) {
+	if sockopt == nil || !sockopt.Mptcp {
+		return
+	}
+	if dialer != nil {
+		dialer.SetMultipathTCP(true)
+	}
+	if lc != nil {
+		lc.SetMultipathTCP(true)
+	}
+}
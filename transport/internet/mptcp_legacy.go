@@ -0,0 +1,14 @@
+//go:build !go1.21
+// +build !go1.21
+
+package internet
+
+import (
+	"net"
+)
+
+func enableMultipathTCP(_ *net.Dialer, _ *net.ListenConfig, sockopt *SocketConfig) {
+	if sockopt != nil && sockopt.Mptcp {
+		newError("Multipath TCP requires V2Ray built with Go 1.21 or later, using TCP instead").AtWarning().WriteToLog()
+	}
+}
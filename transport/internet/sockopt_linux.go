@@ -2,6 +2,7 @@ package internet
 
 import (
 	"net"
+	"strings"
 	"syscall"
 
 	"golang.org/x/sys/unix"
@@ -78,7 +79,7 @@ func applyOutboundSocketOptions(network string, address string, fd uintptr, conf
 		}
 	}
 
-	return nil
+	return applyLinuxSocketOptions(network, fd, config)
 }
 
 func applyInboundSocketOptions(network string, fd uintptr, config *SocketConfig) error {
@@ -126,6 +127,37 @@ func applyInboundSocketOptions(network string, fd uintptr, config *SocketConfig)
 		}
 	}
 
+	return applyLinuxSocketOptions(network, fd, config)
+}
+
+// applyLinuxSocketOptions applies the options for both incoming and outgoing
+// sockets.
+func applyLinuxSocketOptions(network string, fd uintptr, config *SocketConfig) error {
+	if config.BindToDevice != "" {
+		if err := unix.BindToDevice(int(fd), config.BindToDevice); err != nil {
+			return newError("failed to set SO_BINDTODEVICE=", config.BindToDevice).Base(err)
+		}
+	}
+
+	if isTCPSocket(network) {
+		if config.TcpCongestion != "" {
+			if err := unix.SetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_CONGESTION, config.TcpCongestion); err != nil {
+				return newError("failed to set TCP_CONGESTION=", config.TcpCongestion).Base(err)
+			}
+		}
+		if config.TcpUserTimeout > 0 {
+			if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT, int(config.TcpUserTimeout)); err != nil {
+				return newError("failed to set TCP_USER_TIMEOUT=", config.TcpUserTimeout).Base(err)
+			}
+		}
+	}
+
+	if config.V6Only && strings.HasSuffix(network, "6") {
+		if err := unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_V6ONLY, 1); err != nil {
+			return newError("failed to set IPV6_V6ONLY").Base(err)
+		}
+	}
+
 	return nil
 }
 
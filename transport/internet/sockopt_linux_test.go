@@ -2,6 +2,7 @@ package internet_test
 
 import (
 	"context"
+	"strings"
 	"syscall"
 	"testing"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/v2fly/v2ray-core/v5/common/net"
 	"github.com/v2fly/v2ray-core/v5/testing/servers/tcp"
 	. "github.com/v2fly/v2ray-core/v5/transport/internet"
+	"golang.org/x/sys/unix"
 )
 
 func TestSockOptMark(t *testing.T) {
@@ -40,3 +42,57 @@ func TestSockOptMark(t *testing.T) {
 	})
 	common.Must(err)
 }
+
+func TestSockOptTCP(t *testing.T) {
+	tcpServer := tcp.Server{
+		MsgProcessor: func(b []byte) []byte {
+			return b
+		},
+	}
+	dest, err := tcpServer.Start()
+	common.Must(err)
+	defer tcpServer.Close()
+
+	dialer := DefaultSystemDialer{}
+	conn, err := dialer.Dial(context.Background(), nil, dest, &SocketConfig{
+		TcpCongestion:  "reno",
+		TcpUserTimeout: 5000,
+	})
+	common.Must(err)
+	defer conn.Close()
+
+	rawConn, err := conn.(*net.TCPConn).SyscallConn()
+	common.Must(err)
+	err = rawConn.Control(func(fd uintptr) {
+		congestion, err := unix.GetsockoptString(int(fd), unix.IPPROTO_TCP, unix.TCP_CONGESTION)
+		common.Must(err)
+		if strings.TrimRight(congestion, "\x00") != "reno" {
+			t.Error("unexpected congestion control ", congestion)
+		}
+		timeout, err := unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_USER_TIMEOUT)
+		common.Must(err)
+		if timeout != 5000 {
+			t.Error("unexpected user timeout ", timeout)
+		}
+	})
+	common.Must(err)
+}
+
+func TestSockOptV6Only(t *testing.T) {
+	listener, err := ListenSystem(context.Background(), &net.TCPAddr{IP: net.ParseIP("::")}, &SocketConfig{V6Only: true})
+	if err != nil {
+		t.Skip("IPv6 not available: ", err)
+	}
+	defer listener.Close()
+
+	rawConn, err := listener.(*net.TCPListener).SyscallConn()
+	common.Must(err)
+	err = rawConn.Control(func(fd uintptr) {
+		v6only, err := unix.GetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_V6ONLY)
+		common.Must(err)
+		if v6only != 1 {
+			t.Error("expected IPV6_V6ONLY")
+		}
+	})
+	common.Must(err)
+}
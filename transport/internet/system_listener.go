@@ -52,6 +52,7 @@ func (dl *DefaultListener) Listen(ctx context.Context, addr net.Addr, sockopt *S
 		if sockopt != nil && sockopt.TcpKeepAliveIdle != 0 {
 			lc.KeepAlive = time.Duration(-1)
 		}
+		enableMultipathTCP(nil, &lc, sockopt)
 	case *net.UnixAddr:
 		lc.Control = nil
 		network = addr.Network()
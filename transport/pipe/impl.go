@@ -67,6 +67,13 @@ func (p *pipe) getState(forRead bool) error {
 	}
 }
 
+func (p *pipe) Len() int32 {
+	p.Lock()
+	defer p.Unlock()
+
+	return p.data.Len()
+}
+
 func (p *pipe) readMultiBufferInternal() (buf.MultiBuffer, error) {
 	p.Lock()
 	defer p.Unlock()
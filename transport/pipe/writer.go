@@ -27,3 +27,8 @@ func (w *Writer) Interrupt() {
 func (w *Writer) IsPipe() bool {
 	return true
 }
+
+// Len returns the size of the data that has been written but not yet read.
+func (w *Writer) Len() int32 {
+	return w.pipe.Len()
+}